@@ -11,11 +11,14 @@ import (
 	"syscall"
 	"time"
 
+	"cursorIM/internal/chat"
 	"cursorIM/internal/config"
 	"cursorIM/internal/connection"
 	"cursorIM/internal/database"
+	"cursorIM/internal/model"
 	"cursorIM/internal/redisclient"
 	"cursorIM/internal/router"
+	"cursorIM/internal/search"
 	"cursorIM/internal/server"
 	"cursorIM/internal/service"
 
@@ -54,8 +57,22 @@ func main() {
 		log.Println("Redis 初始化成功")
 	}
 
+	// 合并旧版本中分散在多个会话里的单聊记录（只在升级后首次启动时执行）
+	if err := model.RunMigrationOnce(db, "merge_private_conversations", func() error {
+		return chat.NewChatService().MergePrivateConversations(context.Background())
+	}); err != nil {
+		log.Printf("合并单聊记录失败: %v", err)
+	}
+
+	// 初始化消息搜索索引（进程内索引需要从数据库重建）
+	search.Init(db)
+	if err := chat.NewMessageService().RebuildSearchIndex(context.Background()); err != nil {
+		log.Printf("重建消息搜索索引失败: %v", err)
+	}
+
 	// 创建优化的连接管理器（支持协议适配）
-	connMgr := connection.NewOptimizedConnectionManager("server-1", "localhost:8082")
+	serverID := "server-1"
+	connMgr := connection.NewOptimizedConnectionManager(serverID, "localhost:8082")
 
 	// 启动连接管理器
 	ctx, cancel := context.WithCancel(context.Background())
@@ -65,6 +82,18 @@ func main() {
 	// 创建统一服务管理器
 	serviceMgr := service.NewManager(context.Background(), connMgr)
 
+	// 启动定时消息调度器
+	go server.NewMessageScheduler(serverID, connMgr, serviceMgr.GetChatService()).Run(ctx)
+
+	// 启动自毁消息清理
+	go serviceMgr.GetChatService().RunExpiryReaper(ctx)
+
+	// 启动投票到期检查
+	go serviceMgr.GetChatService().RunPollCloser(ctx)
+
+	// 启动禁言到期检查
+	go serviceMgr.GetGroupService().RunMuteExpiry(ctx)
+
 	// 启动增强的 TCP 服务器（支持 Protobuf 协议）
 	enhancedTCPServer := server.NewEnhancedTCPServer(":8083", connMgr, serviceMgr.GetChatService())
 	if err := enhancedTCPServer.Start(); err != nil {
@@ -73,7 +102,7 @@ func main() {
 	defer enhancedTCPServer.Stop()
 
 	// 设置 Gin 路由
-	r := router.SetupRouter(connMgr, serviceMgr.GetChatService())
+	r := router.SetupRouter(connMgr, serviceMgr.GetChatService(), serviceMgr.GetGroupService())
 
 	// 添加增强的 WebSocket 路由（支持协议适配）
 	r.GET("/api/ws", server.EnhancedWebSocketHandler(connMgr, serviceMgr.GetChatService(), false))
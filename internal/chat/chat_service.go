@@ -3,6 +3,8 @@ package chat
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync"
 	"time"
 
 	"cursorIM/internal/database"
@@ -10,6 +12,7 @@ import (
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // ChatService 处理会话和消息相关逻辑
@@ -28,14 +31,7 @@ func NewChatService() *ChatService {
 func (s *ChatService) CreateConversation(ctx context.Context, userID, recipientID string, isGroup bool, name string) (*ConversationResponse, error) {
 	// 检查单聊是否已存在
 	if !isGroup {
-		var existingConvID string
-
-		err := s.db.Raw(`
-			SELECT c.id FROM conversations c
-			JOIN participants p1 ON c.id = p1.conversation_id
-			JOIN participants p2 ON c.id = p2.conversation_id
-			WHERE c.is_group = false AND p1.user_id = ? AND p2.user_id = ?
-		`, userID, recipientID).Scan(&existingConvID).Error
+		existingConvID, err := s.findPrivateConversationID(userID, recipientID)
 
 		if err == nil && existingConvID != "" {
 			// 会话已存在，获取会话信息
@@ -46,15 +42,13 @@ func (s *ChatService) CreateConversation(ctx context.Context, userID, recipientI
 				       COALESCE(m.content, '') as lastMessage,
 				       0 as unread
 				FROM conversations c
-				LEFT JOIN messages m ON m.conversation_id = c.id
-				WHERE c.id = ? AND (
-					m.id = (
-						SELECT msg.id FROM messages msg
-						WHERE msg.conversation_id = c.id
-						ORDER BY msg.created_at DESC
-						LIMIT 1
-					) OR m.id IS NULL
+				LEFT JOIN messages m ON m.id = (
+					SELECT msg.id FROM messages msg
+					WHERE msg.conversation_id = c.id
+					ORDER BY msg.created_at DESC
+					LIMIT 1
 				)
+				WHERE c.id = ?
 			`, existingConvID).Scan(&conversation).Error
 
 			if err == nil {
@@ -79,13 +73,18 @@ func (s *ChatService) CreateConversation(ctx context.Context, userID, recipientI
 		}
 	}
 
-	// 创建新会话
+	// 创建新会话，单聊使用由双方用户ID确定的会话ID
+	conversationID := uuid.New().String()
+	if !isGroup {
+		conversationID = model.PrivateConversationID(userID, recipientID)
+	}
+
 	tx := s.db.Begin()
 
 	// 1. 创建会话
 	now := time.Now()
 	conversation := model.Conversation{
-		ID:   uuid.New().String(),
+		ID:   conversationID,
 		Name: name,
 		Type: func() int {
 			if isGroup {
@@ -102,38 +101,16 @@ func (s *ChatService) CreateConversation(ctx context.Context, userID, recipientI
 		UpdatedAt: now,
 	}
 
-	if err := tx.Create(&conversation).Error; err != nil {
+	// 会话和参与者的ID都是确定的，并发创建同一单聊时忽略冲突
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&conversation).Error; err != nil {
 		tx.Rollback()
 		return nil, err
 	}
 
 	// 2. 添加参与者
-	participants := []model.Participant{
-		{
-			ID:             uuid.New().String(),
-			ConversationID: conversation.ID,
-			UserID:         userID,
-			JoinedAt:       time.Now(),
-			LastReadAt:     time.Now(),
-			CreatedAt:      time.Now(),
-			UpdatedAt:      time.Now(),
-		},
-		{
-			ID:             uuid.New().String(),
-			ConversationID: conversation.ID,
-			UserID:         recipientID,
-			JoinedAt:       time.Now(),
-			LastReadAt:     time.Now(),
-			CreatedAt:      time.Now(),
-			UpdatedAt:      time.Now(),
-		},
-	}
-
-	for _, p := range participants {
-		if err := tx.Create(&p).Error; err != nil {
-			tx.Rollback()
-			return nil, err
-		}
+	if err := createParticipants(tx, conversation.ID, userID, recipientID); err != nil {
+		tx.Rollback()
+		return nil, err
 	}
 
 	if err := tx.Commit().Error; err != nil {
@@ -168,6 +145,74 @@ func (s *ChatService) CreateConversation(ctx context.Context, userID, recipientI
 	return convResponse, nil
 }
 
+// privateConversationCache 单聊用户对 -> 会话ID 的进程内缓存
+var privateConversationCache sync.Map
+
+// ResolvePrivateConversation 获取两个用户之间唯一的单聊会话ID，不存在时创建会话及参与者
+func (s *ChatService) ResolvePrivateConversation(ctx context.Context, userID, recipientID string) (string, error) {
+	cacheKey := model.PrivateConversationID(userID, recipientID)
+	if conversationID, ok := privateConversationCache.Load(cacheKey); ok {
+		return conversationID.(string), nil
+	}
+
+	conversationID, err := s.findPrivateConversationID(userID, recipientID)
+	if err != nil {
+		return "", fmt.Errorf("查询单聊会话失败: %w", err)
+	}
+
+	if conversationID == "" {
+		if _, err := s.CreateConversation(ctx, userID, recipientID, false, ""); err != nil {
+			return "", fmt.Errorf("创建单聊会话失败: %w", err)
+		}
+		conversationID = model.PrivateConversationID(userID, recipientID)
+	}
+
+	privateConversationCache.Store(cacheKey, conversationID)
+	return conversationID, nil
+}
+
+// findPrivateConversationID 查找两个用户之间已有的单聊会话
+// 只匹配参与者恰好是这两个用户的会话；存在多个时取最早创建的
+func (s *ChatService) findPrivateConversationID(userID, recipientID string) (string, error) {
+	var conversationIDs []string
+	err := s.db.Raw(`
+		SELECT c.id FROM conversations c
+		JOIN participants p1 ON c.id = p1.conversation_id
+		JOIN participants p2 ON c.id = p2.conversation_id
+		WHERE c.is_group = false AND p1.user_id = ? AND p2.user_id = ?
+		  AND NOT EXISTS (
+			SELECT 1 FROM participants p3
+			WHERE p3.conversation_id = c.id AND p3.user_id NOT IN (?, ?)
+		  )
+		ORDER BY c.created_at ASC
+		LIMIT 1
+	`, userID, recipientID, userID, recipientID).Scan(&conversationIDs).Error
+	if err != nil || len(conversationIDs) == 0 {
+		return "", err
+	}
+	return conversationIDs[0], nil
+}
+
+// createParticipants 为会话添加参与者，已存在的参与者忽略
+func createParticipants(tx *gorm.DB, conversationID string, userIDs ...string) error {
+	now := time.Now()
+	for _, userID := range userIDs {
+		participant := model.Participant{
+			ID:             model.ParticipantID(conversationID, userID),
+			ConversationID: conversationID,
+			UserID:         userID,
+			JoinedAt:       now,
+			LastReadAt:     now,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&participant).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // GetConversations 获取用户的所有会话
 func (s *ChatService) GetConversations(ctx context.Context, userID string) ([]ConversationResponse, error) {
 	var conversations []ConversationResponse
@@ -179,18 +224,24 @@ func (s *ChatService) GetConversations(ctx context.Context, userID string) ([]Co
 		       (SELECT COUNT(*) FROM messages msg 
 		        WHERE msg.conversation_id = c.id 
 		          AND msg.created_at > COALESCE(p.last_read_at, '1970-01-01')
-		          AND msg.sender_id != ?) as unread
+		          AND msg.sender_id != ?) as unread,
+		       (SELECT COUNT(*) FROM message_mentions mm
+		        JOIN messages msg ON msg.id = mm.message_id
+		        WHERE mm.conversation_id = c.id
+		          AND mm.user_id = ?
+		          AND msg.recalled = false
+		          AND mm.created_at > COALESCE(p.last_read_at, '1970-01-01')) as mention_unread,
+		       p.muted as muted
 		FROM conversations c
 		JOIN participants p ON c.id = p.conversation_id AND p.user_id = ?
-		LEFT JOIN messages m ON m.conversation_id = c.id
-		WHERE m.id = (
+		LEFT JOIN messages m ON m.id = (
 			SELECT msg.id FROM messages msg
 			WHERE msg.conversation_id = c.id
 			ORDER BY msg.created_at DESC
 			LIMIT 1
-		) OR m.id IS NULL
+		)
 		ORDER BY COALESCE(m.created_at, c.created_at) DESC
-	`, userID, userID).Scan(&conversations).Error
+	`, userID, userID, userID).Scan(&conversations).Error
 
 	if err != nil {
 		return nil, err
@@ -228,6 +279,30 @@ func (s *ChatService) GetConversations(ctx context.Context, userID string) ([]Co
 	return conversations, nil
 }
 
+// SetConversationMuted 设置会话免打扰
+// 免打扰只影响普通消息的提醒，被@的提醒和提及未读数不受影响
+func (s *ChatService) SetConversationMuted(ctx context.Context, conversationID, userID string, muted bool) error {
+	result := s.db.Model(&model.Participant{}).
+		Where("conversation_id = ? AND user_id = ?", conversationID, userID).
+		Updates(map[string]interface{}{
+			"muted":      muted,
+			"updated_at": time.Now(),
+		})
+	if result.Error != nil {
+		return fmt.Errorf("设置会话免打扰失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		var count int64
+		s.db.Model(&model.Participant{}).
+			Where("conversation_id = ? AND user_id = ?", conversationID, userID).
+			Count(&count)
+		if count == 0 {
+			return errors.New("您不是该会话的参与者")
+		}
+	}
+	return nil
+}
+
 // GetConversationByID 根据ID获取会话详情
 func (s *ChatService) GetConversationByID(ctx context.Context, conversationID, userID string) (*ConversationResponse, error) {
 	var conversation ConversationResponse
@@ -238,19 +313,24 @@ func (s *ChatService) GetConversationByID(ctx context.Context, conversationID, u
 		       (SELECT COUNT(*) FROM messages msg 
 		        WHERE msg.conversation_id = c.id 
 		          AND msg.created_at > COALESCE(p.last_read_at, '1970-01-01')
-		          AND msg.sender_id != ?) as unread
+		          AND msg.sender_id != ?) as unread,
+		       (SELECT COUNT(*) FROM message_mentions mm
+		        JOIN messages msg ON msg.id = mm.message_id
+		        WHERE mm.conversation_id = c.id
+		          AND mm.user_id = ?
+		          AND msg.recalled = false
+		          AND mm.created_at > COALESCE(p.last_read_at, '1970-01-01')) as mention_unread,
+		       p.muted as muted
 		FROM conversations c
 		JOIN participants p ON c.id = p.conversation_id AND p.user_id = ?
-		LEFT JOIN messages m ON m.conversation_id = c.id
-		WHERE c.id = ? AND (
-			m.id = (
-				SELECT msg.id FROM messages msg
-				WHERE msg.conversation_id = c.id
-				ORDER BY msg.created_at DESC
-				LIMIT 1
-			) OR m.id IS NULL
+		LEFT JOIN messages m ON m.id = (
+			SELECT msg.id FROM messages msg
+			WHERE msg.conversation_id = c.id
+			ORDER BY msg.created_at DESC
+			LIMIT 1
 		)
-	`, userID, userID, conversationID).Scan(&conversation).Error
+		WHERE c.id = ?
+	`, userID, userID, userID, conversationID).Scan(&conversation).Error
 
 	if err != nil {
 		return nil, err
@@ -4,8 +4,14 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
+
+	"cursorIM/internal/model"
+	"cursorIM/internal/protocol"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 )
 
 // GetConversations 获取用户的所有会话
@@ -101,12 +107,359 @@ func GetMessages(c *gin.Context) {
 	}
 	log.Printf("userID:%s", userID)
 
-	conversationID := c.Param("conversationId")
+	conversationID := c.Param("id")
 	if conversationID == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "会话ID不能为空"})
 		return
 	}
 
+	if _, err := NewMessageService().conversationMembership(conversationID, userID.(string)); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	respondHistory(c, conversationID)
+}
+
+// GetUserMessages 获取与特定用户的单聊历史消息
+func GetUserMessages(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	otherUserID := c.Param("user_id")
+	if otherUserID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "用户ID不能为空"})
+		return
+	}
+	log.Printf("获取用户 %s 和 %s 之间的消息", userID, otherUserID)
+
+	// 两个用户之间的单聊会话ID是确定的
+	respondHistory(c, model.PrivateConversationID(userID.(string), otherUserID))
+}
+
+// GetGroupMessages 获取群组历史消息（群消息以群组ID作为会话ID），只有群成员可以查看
+func GetGroupMessages(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	groupID := c.Param("group_id")
+	if groupID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "群组ID不能为空"})
+		return
+	}
+	log.Printf("获取群组 %s 的消息", groupID)
+
+	if _, err := NewMessageService().conversationMembership(groupID, userID.(string)); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	respondHistory(c, groupID)
+}
+
+// respondHistory 按查询参数 before/after/limit 分页返回历史消息
+// 带分页参数时响应为 MessageBatch 结构，不带分页参数时保持原有格式，只返回最新消息数组；
+// 请求头 Accept 为 application/x-protobuf 时返回 pb.MessageBatch
+func respondHistory(c *gin.Context, conversationID string) {
+	query := HistoryQuery{
+		Before: c.Query("before"),
+		After:  c.Query("after"),
+	}
+	if limit, err := strconv.Atoi(c.Query("limit")); err == nil {
+		query.Limit = limit
+	}
+
+	messageService := NewMessageService()
+	batch, err := messageService.GetMessageHistory(c.Request.Context(), conversationID, query)
+	if err != nil {
+		log.Printf("获取消息失败: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if c.NegotiateFormat(binding.MIMEJSON, binding.MIMEPROTOBUF) == binding.MIMEPROTOBUF {
+		pbBatch, err := protocol.NewMessageAdapter().BatchToProtobuf(batch)
+		if err != nil {
+			log.Printf("转换历史消息失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取消息失败"})
+			return
+		}
+		c.ProtoBuf(http.StatusOK, pbBatch)
+		return
+	}
+
+	if c.Query("before") == "" && c.Query("after") == "" && c.Query("limit") == "" {
+		c.JSON(http.StatusOK, batch.Messages)
+		return
+	}
+
+	c.JSON(http.StatusOK, batch)
+}
+
+// SyncMessagesRequest 增量同步请求
+type SyncMessagesRequest struct {
+	Cursors map[string]int64 `json:"cursors"` // 会话ID -> 客户端已收到的最大序列号
+	Limit   int              `json:"limit"`   // 每个会话最多返回的消息数
+}
+
+// SyncMessages 增量同步用户所有会话中的新消息
+func SyncMessages(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var req SyncMessagesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数"})
+		return
+	}
+
+	messageService := NewMessageService()
+	results, err := messageService.SyncMessages(c.Request.Context(), userID.(string), req.Cursors, req.Limit)
+	if err != nil {
+		log.Printf("同步消息失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "同步消息失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"conversations": results})
+}
+
+// RecallMessage 撤回消息
+// 需要使用已设置连接管理器的消息服务，以便推送撤回通知
+func RecallMessage(messageService *MessageService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+			return
+		}
+
+		messageID := c.Param("id")
+		if messageID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "消息ID不能为空"})
+			return
+		}
+
+		notice, err := messageService.RecallMessage(c.Request.Context(), messageID, userID.(string))
+		if err != nil {
+			log.Printf("撤回消息失败: %v", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "消息已撤回", "recall": notice})
+	}
+}
+
+// EditMessageRequest 编辑消息请求
+type EditMessageRequest struct {
+	Content string `json:"content" binding:"required"`
+}
+
+// EditMessage 编辑消息
+// 需要使用已设置连接管理器的消息服务，以便推送更新事件
+func EditMessage(messageService *MessageService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+			return
+		}
+
+		messageID := c.Param("id")
+		if messageID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "消息ID不能为空"})
+			return
+		}
+
+		var req EditMessageRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		notice, err := messageService.EditMessage(c.Request.Context(), messageID, userID.(string), req.Content)
+		if err != nil {
+			log.Printf("编辑消息失败: %v", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "消息已编辑", "edit": notice})
+	}
+}
+
+// ForwardMessages 转发消息（逐条或合并为聊天记录）
+// 需要使用已设置连接管理器的消息服务，以便投递转发的消息
+func ForwardMessages(messageService *MessageService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+			return
+		}
+
+		var req ForwardRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		messages, err := messageService.ForwardMessages(c.Request.Context(), userID.(string), req)
+		if err != nil {
+			log.Printf("转发消息失败: %v", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "转发成功", "messages": messages})
+	}
+}
+
+// ReactionRequest 表情回应请求
+type ReactionRequest struct {
+	Emoji string `json:"emoji" binding:"required"`
+}
+
+// AddReaction 添加表情回应
+// 需要使用已设置连接管理器的消息服务，以便推送回应事件
+func AddReaction(messageService *MessageService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+			return
+		}
+
+		messageID := c.Param("id")
+		if messageID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "消息ID不能为空"})
+			return
+		}
+
+		var req ReactionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		notice, err := messageService.ReactToMessage(c.Request.Context(), messageID, userID.(string), req.Emoji, false)
+		if err != nil {
+			log.Printf("添加表情回应失败: %v", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "已添加表情回应", "reactions": notice.Reactions})
+	}
+}
+
+// RemoveReaction 取消表情回应，表情通过查询参数 emoji 传递
+// 需要使用已设置连接管理器的消息服务，以便推送回应事件
+func RemoveReaction(messageService *MessageService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+			return
+		}
+
+		messageID := c.Param("id")
+		if messageID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "消息ID不能为空"})
+			return
+		}
+
+		emoji := c.Query("emoji")
+		if emoji == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "表情不能为空"})
+			return
+		}
+
+		notice, err := messageService.ReactToMessage(c.Request.Context(), messageID, userID.(string), emoji, true)
+		if err != nil {
+			log.Printf("取消表情回应失败: %v", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "已取消表情回应", "reactions": notice.Reactions})
+	}
+}
+
+// GetMessageRevisions 获取消息的编辑历史
+func GetMessageRevisions(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	messageID := c.Param("id")
+	if messageID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "消息ID不能为空"})
+		return
+	}
+
+	messageService := NewMessageService()
+	revisions, err := messageService.GetMessageRevisions(c.Request.Context(), messageID, userID.(string))
+	if err != nil {
+		log.Printf("获取消息编辑历史失败: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, revisions)
+}
+
+// GetThread 获取话题概要（根消息、回复数、未读数）
+func GetThread(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	threadID := c.Param("id")
+	if threadID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "话题ID不能为空"})
+		return
+	}
+
+	messageService := NewMessageService()
+	info, err := messageService.GetThreadInfo(c.Request.Context(), threadID, userID.(string))
+	if err != nil {
+		log.Printf("获取话题失败: %v", err)
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, info)
+}
+
+// GetThreadMessages 获取话题内的回复
+func GetThreadMessages(c *gin.Context) {
+	_, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	threadID := c.Param("id")
+	if threadID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "话题ID不能为空"})
+		return
+	}
+
 	limitStr := c.DefaultQuery("limit", "50")
 	limit, err := strconv.ParseInt(limitStr, 10, 64)
 	if err != nil {
@@ -114,16 +467,40 @@ func GetMessages(c *gin.Context) {
 	}
 
 	messageService := NewMessageService()
-	messages, err := messageService.GetMessagesByConversation(c.Request.Context(), conversationID, limit)
+	messages, err := messageService.GetThreadMessages(c.Request.Context(), threadID, limit)
 	if err != nil {
-		log.Printf("获取消息失败: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取消息失败"})
+		log.Printf("获取话题消息失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取话题消息失败"})
 		return
 	}
 
 	c.JSON(http.StatusOK, messages)
 }
 
+// MarkThreadRead 标记话题为已读
+func MarkThreadRead(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	threadID := c.Param("id")
+	if threadID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "话题ID不能为空"})
+		return
+	}
+
+	messageService := NewMessageService()
+	if err := messageService.MarkThreadRead(c.Request.Context(), threadID, userID.(string)); err != nil {
+		log.Printf("标记话题为已读失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "标记话题为已读失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "话题已标记为已读"})
+}
+
 // GetParticipants 获取会话参与者
 func GetParticipants(c *gin.Context) {
 	userID, exists := c.Get("userID")
@@ -158,8 +535,359 @@ func GetParticipants(c *gin.Context) {
 	c.JSON(http.StatusOK, participants)
 }
 
+// MuteConversationRequest 会话免打扰请求
+type MuteConversationRequest struct {
+	Muted bool `json:"muted"`
+}
+
+// MuteConversation 设置会话免打扰
+func MuteConversation(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	conversationID := c.Param("id")
+	if conversationID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "会话ID不能为空"})
+		return
+	}
+
+	var req MuteConversationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	chatService := NewChatService()
+	if err := chatService.SetConversationMuted(c.Request.Context(), conversationID, userID.(string), req.Muted); err != nil {
+		log.Printf("设置会话免打扰失败: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "设置成功", "muted": req.Muted})
+}
+
+// GetConversationRetention 获取会话的自毁消息设置
+func GetConversationRetention(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	conversationID := c.Param("id")
+	if conversationID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "会话ID不能为空"})
+		return
+	}
+
+	messageService := NewMessageService()
+	retention, err := messageService.GetConversationRetention(c.Request.Context(), conversationID, userID.(string))
+	if err != nil {
+		log.Printf("获取自毁消息设置失败: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, retention)
+}
+
+// SetConversationRetention 修改会话的自毁消息设置（群聊使用群组ID）
+func SetConversationRetention(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	conversationID := c.Param("id")
+	if conversationID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "会话ID不能为空"})
+		return
+	}
+
+	var req RetentionUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	messageService := NewMessageService()
+	retention, err := messageService.SetConversationRetention(c.Request.Context(), conversationID, userID.(string), req)
+	if err != nil {
+		log.Printf("修改自毁消息设置失败: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, retention)
+}
+
+// MarkReadRequest 标记已读请求，Seq 为空时标记到最新消息
+type MarkReadRequest struct {
+	Seq int64 `json:"seq"`
+}
+
 // MarkMessagesAsRead 标记消息为已读
-func MarkMessagesAsRead(c *gin.Context) {
+// 需要使用已设置连接管理器的消息服务，以便推送已读回执
+func MarkMessagesAsRead(messageService *MessageService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+			return
+		}
+
+		conversationID := c.Param("id")
+		if conversationID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "会话ID不能为空"})
+			return
+		}
+
+		// 请求体可选
+		var req MarkReadRequest
+		if c.Request.ContentLength > 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		lastReadSeq, err := messageService.MarkConversationRead(c.Request.Context(), conversationID, userID.(string), req.Seq)
+		if err != nil {
+			log.Printf("标记消息为已读失败: %v", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "消息已标记为已读", "last_read_seq": lastReadSeq})
+	}
+}
+
+// GetMessageReaders 获取消息的已读成员列表
+func GetMessageReaders(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	messageID := c.Param("id")
+	if messageID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "消息ID不能为空"})
+		return
+	}
+
+	messageService := NewMessageService()
+	info, err := messageService.GetMessageReadInfo(c.Request.Context(), messageID, userID.(string))
+	if err != nil {
+		log.Printf("获取消息已读列表失败: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, info)
+}
+
+// SearchMessages 在当前用户可见的会话中全文搜索消息
+// 查询参数：q 关键词，conversation_id、sender_id、type 过滤，from/to 为 Unix 秒，limit/offset 分页
+func SearchMessages(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	query := MessageSearchQuery{
+		Text:           strings.TrimSpace(c.Query("q")),
+		ConversationID: c.Query("conversation_id"),
+		SenderID:       c.Query("sender_id"),
+		ContentType:    c.Query("type"),
+	}
+
+	var err error
+	if from := c.Query("from"); from != "" {
+		if query.From, err = strconv.ParseInt(from, 10, 64); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的起始时间"})
+			return
+		}
+	}
+	if to := c.Query("to"); to != "" {
+		if query.To, err = strconv.ParseInt(to, 10, 64); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的结束时间"})
+			return
+		}
+	}
+	if limit, err := strconv.Atoi(c.Query("limit")); err == nil {
+		query.Limit = limit
+	}
+	if offset, err := strconv.Atoi(c.Query("offset")); err == nil {
+		query.Offset = offset
+	}
+
+	messageService := NewMessageService()
+	result, err := messageService.SearchMessages(c.Request.Context(), userID.(string), query)
+	if err != nil {
+		log.Printf("搜索消息失败: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ScheduleMessageRequest 创建定时消息请求，消息字段与实时发送时相同
+type ScheduleMessageRequest struct {
+	protocol.Message
+	SendAt int64 `json:"send_at" binding:"required"` // 计划发送时间（Unix 秒）
+}
+
+// UpdateScheduledMessageRequest 修改定时消息请求，未提供的字段保持不变
+type UpdateScheduledMessageRequest struct {
+	Content *string `json:"content"`
+	SendAt  *int64  `json:"send_at"` // 计划发送时间（Unix 秒）
+}
+
+// ScheduleMessage 创建定时消息
+func ScheduleMessage(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var req ScheduleMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	messageService := NewMessageService()
+	scheduled, err := messageService.ScheduleMessage(c.Request.Context(), userID.(string), &req.Message, time.Unix(req.SendAt, 0))
+	if err != nil {
+		log.Printf("创建定时消息失败: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, scheduled)
+}
+
+// ListScheduledMessages 列出当前用户的定时消息，可用 status 过滤（默认只返回等待发送的）
+func ListScheduledMessages(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	messageService := NewMessageService()
+	scheduled, err := messageService.ListScheduledMessages(c.Request.Context(), userID.(string), c.Query("status"))
+	if err != nil {
+		log.Printf("获取定时消息失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, scheduled)
+}
+
+// UpdateScheduledMessage 修改等待发送的定时消息
+func UpdateScheduledMessage(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var req UpdateScheduledMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var sendAt *time.Time
+	if req.SendAt != nil {
+		at := time.Unix(*req.SendAt, 0)
+		sendAt = &at
+	}
+
+	messageService := NewMessageService()
+	scheduled, err := messageService.UpdateScheduledMessage(c.Request.Context(), c.Param("id"), userID.(string), req.Content, sendAt)
+	if err != nil {
+		log.Printf("修改定时消息失败: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, scheduled)
+}
+
+// CancelScheduledMessage 取消等待发送的定时消息
+func CancelScheduledMessage(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	messageService := NewMessageService()
+	if err := messageService.CancelScheduledMessage(c.Request.Context(), c.Param("id"), userID.(string)); err != nil {
+		log.Printf("取消定时消息失败: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "定时消息已取消"})
+}
+
+// PinMessage 置顶消息
+// 需要使用已设置连接管理器的消息服务，以便推送置顶事件
+func PinMessage(messageService *MessageService) gin.HandlerFunc {
+	return pinHandler(messageService, false)
+}
+
+// UnpinMessage 取消置顶消息
+func UnpinMessage(messageService *MessageService) gin.HandlerFunc {
+	return pinHandler(messageService, true)
+}
+
+// pinHandler 置顶/取消置顶的公共处理逻辑
+func pinHandler(messageService *MessageService, unpin bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+			return
+		}
+
+		messageID := c.Param("id")
+		if messageID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "消息ID不能为空"})
+			return
+		}
+
+		notice, err := messageService.PinMessage(c.Request.Context(), messageID, userID.(string), unpin)
+		if err != nil {
+			log.Printf("置顶消息失败: %v", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		message := "消息已置顶"
+		if unpin {
+			message = "已取消置顶"
+		}
+		c.JSON(http.StatusOK, gin.H{"message": message, "pin": notice})
+	}
+}
+
+// GetPinnedMessages 获取会话的置顶消息列表
+// 同时挂在 /conversations/:id/pins 和 /group/:groupId/pins 下，群聊以群组ID作为会话ID
+func GetPinnedMessages(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
@@ -167,17 +895,140 @@ func MarkMessagesAsRead(c *gin.Context) {
 	}
 
 	conversationID := c.Param("id")
+	if conversationID == "" {
+		conversationID = c.Param("groupId")
+	}
 	if conversationID == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "会话ID不能为空"})
 		return
 	}
 
 	messageService := NewMessageService()
-	if err := messageService.MarkMessagesAsRead(c.Request.Context(), conversationID, userID.(string)); err != nil {
-		log.Printf("标记消息为已读失败: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "标记消息为已读失败"})
+	pins, err := messageService.GetPinnedMessages(c.Request.Context(), conversationID, userID.(string))
+	if err != nil {
+		log.Printf("获取置顶消息失败: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"pins": pins})
+}
+
+// VotePollRequest 投票请求，option_ids 为选择的选项ID
+type VotePollRequest struct {
+	OptionIDs []string `json:"option_ids" binding:"required"`
+}
+
+// VotePoll 投票或修改投票
+// 需要使用已设置连接管理器的消息服务，以便推送计票事件
+func VotePoll(messageService *MessageService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+			return
+		}
+
+		pollID := c.Param("id")
+		if pollID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "投票ID不能为空"})
+			return
+		}
+
+		var req VotePollRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if len(req.OptionIDs) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "请选择投票选项"})
+			return
+		}
+
+		result, err := messageService.VotePoll(c.Request.Context(), pollID, userID.(string), req.OptionIDs)
+		if err != nil {
+			log.Printf("投票失败: %v", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "投票成功", "option_ids": result.OptionIDs, "poll": result.Poll})
+	}
+}
+
+// RetractVote 撤回自己的投票
+func RetractVote(messageService *MessageService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+			return
+		}
+
+		pollID := c.Param("id")
+		if pollID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "投票ID不能为空"})
+			return
+		}
+
+		result, err := messageService.VotePoll(c.Request.Context(), pollID, userID.(string), nil)
+		if err != nil {
+			log.Printf("撤回投票失败: %v", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "已撤回投票", "poll": result.Poll})
+	}
+}
+
+// ClosePoll 结束投票
+func ClosePoll(messageService *MessageService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+			return
+		}
+
+		pollID := c.Param("id")
+		if pollID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "投票ID不能为空"})
+			return
+		}
+
+		notice, err := messageService.ClosePoll(c.Request.Context(), pollID, userID.(string))
+		if err != nil {
+			log.Printf("结束投票失败: %v", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "投票已结束", "poll": notice.Poll})
+	}
+}
+
+// GetPoll 获取投票的最新结果
+func GetPoll(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	pollID := c.Param("id")
+	if pollID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "投票ID不能为空"})
+		return
+	}
+
+	messageService := NewMessageService()
+	poll, err := messageService.GetPoll(c.Request.Context(), pollID, userID.(string))
+	if err != nil {
+		log.Printf("获取投票失败: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "消息已标记为已读"})
+	c.JSON(http.StatusOK, gin.H{"poll": poll})
 }
@@ -0,0 +1,244 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"cursorIM/internal/constants"
+	"cursorIM/internal/model"
+	"cursorIM/internal/redisclient"
+
+	"gorm.io/gorm"
+)
+
+// privatePair 单聊的两个用户（按ID排序）
+type privatePair struct {
+	UserA string
+	UserB string
+}
+
+// MergePrivateConversations 将历史上分散在多个会话ID下的单聊记录合并到唯一确定的会话中
+// 旧版本为每条缺少会话ID的消息生成随机ID，导致同一对用户的聊天被拆成大量会话。
+// 合并后重新按时间顺序分配序列号，客户端需要丢弃这些会话已保存的 seq 重新同步，
+// 因此只作为升级时的一次性迁移执行（见 model.RunMigrationOnce），不在每次启动时运行
+func (s *ChatService) MergePrivateConversations(ctx context.Context) error {
+	pairs, err := s.fragmentedPrivatePairs()
+	if err != nil {
+		return err
+	}
+
+	if len(pairs) == 0 {
+		return nil
+	}
+
+	log.Printf("发现 %d 对用户的单聊记录分散在多个会话中，开始合并", len(pairs))
+
+	for _, pair := range pairs {
+		if err := s.mergePrivatePair(pair); err != nil {
+			return fmt.Errorf("合并用户 %s 和 %s 的单聊记录失败: %w", pair.UserA, pair.UserB, err)
+		}
+	}
+
+	log.Printf("单聊记录合并完成，共处理 %d 对用户", len(pairs))
+	return nil
+}
+
+// fragmentedPrivatePairs 找出会话ID不是确定性ID的单聊用户对
+func (s *ChatService) fragmentedPrivatePairs() ([]privatePair, error) {
+	seen := make(map[privatePair]bool)
+	var pairs []privatePair
+
+	addPair := func(userA, userB, conversationID string) {
+		if userA > userB {
+			userA, userB = userB, userA
+		}
+		if conversationID == model.PrivateConversationID(userA, userB) {
+			return
+		}
+		pair := privatePair{UserA: userA, UserB: userB}
+		if !seen[pair] {
+			seen[pair] = true
+			pairs = append(pairs, pair)
+		}
+	}
+
+	// 消息表中的单聊记录
+	var messageRows []struct {
+		SenderID       string
+		RecipientID    string
+		ConversationID string
+	}
+	err := s.db.Model(&model.Message{}).
+		Distinct("sender_id", "recipient_id", "conversation_id").
+		Where("is_group = ? AND sender_id <> '' AND recipient_id <> ''", false).
+		Scan(&messageRows).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询单聊消息失败: %w", err)
+	}
+	for _, row := range messageRows {
+		addPair(row.SenderID, row.RecipientID, row.ConversationID)
+	}
+
+	// 会话表中没有消息的单聊会话
+	var conversationRows []struct {
+		ConversationID string
+		UserA          string
+		UserB          string
+	}
+	err = s.db.Raw(`
+		SELECT c.id AS conversation_id, p1.user_id AS user_a, p2.user_id AS user_b
+		FROM conversations c
+		JOIN participants p1 ON c.id = p1.conversation_id
+		JOIN participants p2 ON c.id = p2.conversation_id AND p1.id < p2.id
+		WHERE c.is_group = false
+		  AND NOT EXISTS (
+			SELECT 1 FROM participants p3
+			WHERE p3.conversation_id = c.id AND p3.user_id NOT IN (p1.user_id, p2.user_id)
+		  )
+	`).Scan(&conversationRows).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询单聊会话失败: %w", err)
+	}
+	for _, row := range conversationRows {
+		addPair(row.UserA, row.UserB, row.ConversationID)
+	}
+
+	return pairs, nil
+}
+
+// mergePrivatePair 合并一对用户的单聊记录
+func (s *ChatService) mergePrivatePair(pair privatePair) error {
+	canonicalID := model.PrivateConversationID(pair.UserA, pair.UserB)
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// 旧的单聊会话（参与者恰好是这两个用户）
+		var oldConversationIDs []string
+		err := tx.Raw(`
+			SELECT c.id FROM conversations c
+			JOIN participants p1 ON c.id = p1.conversation_id
+			JOIN participants p2 ON c.id = p2.conversation_id
+			WHERE c.is_group = false AND c.id <> ? AND p1.user_id = ? AND p2.user_id = ?
+			  AND NOT EXISTS (
+				SELECT 1 FROM participants p3
+				WHERE p3.conversation_id = c.id AND p3.user_id NOT IN (?, ?)
+			  )
+		`, canonicalID, pair.UserA, pair.UserB, pair.UserA, pair.UserB).Scan(&oldConversationIDs).Error
+		if err != nil {
+			return err
+		}
+
+		// 保留旧会话中最晚的已读时间
+		var lastReadAt []struct {
+			UserID     string
+			LastReadAt time.Time
+		}
+		if len(oldConversationIDs) > 0 {
+			err = tx.Model(&model.Participant{}).
+				Select("user_id, MAX(last_read_at) AS last_read_at").
+				Where("conversation_id IN ?", oldConversationIDs).
+				Group("user_id").
+				Scan(&lastReadAt).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		// 确保确定性会话及参与者存在
+		now := time.Now()
+		conversation := model.Conversation{
+			ID:        canonicalID,
+			Type:      constants.ConversationTypePrivate,
+			IsGroup:   false,
+			LastTime:  now,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		if err := tx.Where("id = ?", canonicalID).FirstOrCreate(&conversation).Error; err != nil {
+			return err
+		}
+		if err := createParticipants(tx, canonicalID, pair.UserA, pair.UserB); err != nil {
+			return err
+		}
+		for _, row := range lastReadAt {
+			err := tx.Model(&model.Participant{}).
+				Where("conversation_id = ? AND user_id = ? AND last_read_at < ?", canonicalID, row.UserID, row.LastReadAt).
+				Update("last_read_at", row.LastReadAt).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		// 旧的会话ID（包括只存在于消息表中的临时ID）
+		var fragmentIDs []string
+		err = tx.Model(&model.Message{}).
+			Distinct("conversation_id").
+			Where("is_group = ? AND conversation_id <> ? AND ((sender_id = ? AND recipient_id = ?) OR (sender_id = ? AND recipient_id = ?))",
+				false, canonicalID, pair.UserA, pair.UserB, pair.UserB, pair.UserA).
+			Pluck("conversation_id", &fragmentIDs).Error
+		if err != nil {
+			return err
+		}
+		fragmentIDs = append(fragmentIDs, oldConversationIDs...)
+
+		// 把两人之间的所有单聊消息移到确定性会话
+		err = tx.Model(&model.Message{}).
+			Where("is_group = ? AND ((sender_id = ? AND recipient_id = ?) OR (sender_id = ? AND recipient_id = ?))",
+				false, pair.UserA, pair.UserB, pair.UserB, pair.UserA).
+			Update("conversation_id", canonicalID).Error
+		if err != nil {
+			return err
+		}
+
+		// 删除旧会话
+		if len(oldConversationIDs) > 0 {
+			if err := tx.Where("conversation_id IN ?", oldConversationIDs).Delete(&model.Participant{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("id IN ?", oldConversationIDs).Delete(&model.Conversation{}).Error; err != nil {
+				return err
+			}
+		}
+		if len(fragmentIDs) > 0 {
+			if err := tx.Where("conversation_id IN ?", fragmentIDs).Delete(&model.ConversationSequence{}).Error; err != nil {
+				return err
+			}
+		}
+
+		// 按时间顺序重新分配序列号
+		var messageIDs []string
+		err = tx.Model(&model.Message{}).
+			Where("conversation_id = ?", canonicalID).
+			Order("timestamp asc, created_at asc, id asc").
+			Pluck("id", &messageIDs).Error
+		if err != nil {
+			return err
+		}
+		for i, id := range messageIDs {
+			if err := tx.Model(&model.Message{}).Where("id = ?", id).Update("seq", i+1).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Save(&model.ConversationSequence{
+			ConversationID: canonicalID,
+			LastSeq:        int64(len(messageIDs)),
+			UpdatedAt:      now,
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	// 删除 Redis 中的计数器，所有节点下次分配时都从数据库重新取得起点
+	if redisclient.IsRedisEnabled() {
+		key := fmt.Sprintf(constants.RedisKeyConversationSeq, canonicalID)
+		if err := redisclient.GetRedisClient().Del(context.Background(), key).Err(); err != nil {
+			log.Printf("清除会话 %s 的序列号计数器失败: %v", canonicalID, err)
+		}
+	}
+	reseedConversations.Store(canonicalID, true)
+	privateConversationCache.Delete(canonicalID)
+	return nil
+}
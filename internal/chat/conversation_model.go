@@ -7,4 +7,7 @@ type ConversationResponse struct {
 	LastMessage string `json:"lastMessage"`
 	Unread      int    `json:"unread"`
 	IsGroup     bool   `json:"isGroup"`
+	// 未读消息中提及当前用户的条数，不受免打扰影响
+	MentionUnread int  `json:"mentionUnread"`
+	Muted         bool `json:"muted"`
 }
@@ -0,0 +1,50 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+
+	"cursorIM/internal/model"
+	"cursorIM/internal/protocol"
+
+	"gorm.io/gorm"
+)
+
+// prepareBody 校验结构化消息体并补全由服务器决定的字段
+// 名片的昵称和头像以用户资料为准；客户端没有填写 content 时使用消息体摘要
+func (s *MessageService) prepareBody(message *protocol.Message) error {
+	if err := protocol.ValidateBody(message); err != nil {
+		return err
+	}
+
+	if message.Contact != nil {
+		var user model.User
+		if err := s.db.Select("id, username, nickname, avatar_url").
+			Where("id = ?", message.Contact.UserID).Take(&user).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("名片中的用户不存在")
+			}
+			return fmt.Errorf("查询名片用户失败: %w", err)
+		}
+		message.Contact.Nickname = user.Nickname
+		if message.Contact.Nickname == "" {
+			message.Contact.Nickname = user.Username
+		}
+		message.Contact.AvatarURL = user.AvatarURL
+	}
+
+	if message.Content == "" {
+		message.Content = protocol.BodySummary(message)
+	}
+	return nil
+}
+
+// applyBody 将结构化消息体写入数据库消息
+func applyBody(dbMessage *model.Message, message *protocol.Message) error {
+	body, err := protocol.EncodeBody(message)
+	if err != nil {
+		return fmt.Errorf("序列化消息体失败: %w", err)
+	}
+	dbMessage.Body = body
+	return nil
+}
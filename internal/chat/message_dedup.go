@@ -0,0 +1,95 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"cursorIM/internal/config"
+	"cursorIM/internal/constants"
+	"cursorIM/internal/model"
+	"cursorIM/internal/protocol"
+	"cursorIM/internal/redisclient"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+// ErrDuplicateMessage 客户端重发了已保存的消息
+// SaveMessage 返回该错误时，消息的 ID、Seq 等已替换为首次保存的值，调用方只需回执、不应再次投递
+var ErrDuplicateMessage = errors.New("重复消息")
+
+// mysqlDuplicateEntry MySQL 唯一键冲突错误码
+const mysqlDuplicateEntry = 1062
+
+// findClientMessage 按 (发送者, 客户端消息ID) 查找已保存的消息
+// 去重时限内优先查 Redis，否则查数据库唯一索引
+func (s *MessageService) findClientMessage(ctx context.Context, senderID, clientMsgID string) (*model.Message, error) {
+	query := s.db.Where("sender_id = ? AND client_msg_id = ?", senderID, clientMsgID)
+
+	if redisclient.IsRedisEnabled() {
+		key := fmt.Sprintf(constants.RedisKeyClientMessage, senderID, clientMsgID)
+		messageID, err := redisclient.GetRedisClient().Get(ctx, key).Result()
+		if err == nil {
+			query = s.db.Where("id = ?", messageID)
+		} else if err != redis.Nil {
+			log.Printf("查询客户端消息ID %s 的去重记录失败: %v", clientMsgID, err)
+		}
+	}
+
+	var dbMessage model.Message
+	if err := query.Take(&dbMessage).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("查询重复消息失败: %w", err)
+	}
+	return &dbMessage, nil
+}
+
+// rememberClientMessage 在 Redis 中记录客户端消息ID对应的服务端消息ID
+func (s *MessageService) rememberClientMessage(ctx context.Context, message *protocol.Message) {
+	if message.ClientMsgID == "" || !redisclient.IsRedisEnabled() {
+		return
+	}
+
+	key := fmt.Sprintf(constants.RedisKeyClientMessage, message.SenderID, message.ClientMsgID)
+	window := time.Duration(config.GlobalConfig.Message.DedupWindow) * time.Second
+	if err := redisclient.GetRedisClient().Set(ctx, key, message.ID, window).Err(); err != nil {
+		log.Printf("记录客户端消息ID %s 失败: %v", message.ClientMsgID, err)
+	}
+}
+
+// applyOriginal 用首次保存的消息覆盖重发消息的服务端字段
+func applyOriginal(message *protocol.Message, original *model.Message) {
+	message.ID = original.ID
+	message.Seq = original.Seq
+	message.ConversationID = original.ConversationID
+	message.Timestamp = original.Timestamp
+	message.Status = original.Status
+}
+
+// clientMsgIDOrNil 空的客户端消息ID存为 NULL，避免唯一索引冲突
+func clientMsgIDOrNil(clientMsgID string) *string {
+	if clientMsgID == "" {
+		return nil
+	}
+	return &clientMsgID
+}
+
+// isDuplicateKey 判断是否为唯一键冲突
+func isDuplicateKey(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry
+}
+
+// derefString 读取可空字符串
+func derefString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
@@ -0,0 +1,173 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"cursorIM/internal/constants"
+	"cursorIM/internal/model"
+	"cursorIM/internal/protocol"
+	"cursorIM/internal/search"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// EditMessage 编辑一条已发送的文本消息
+// 只有发送者可以编辑，编辑前的内容写入修订表，随后向所有参与者推送更新事件
+func (s *MessageService) EditMessage(ctx context.Context, messageID string, userID string, content string) (*protocol.Message, error) {
+	if messageID == "" {
+		return nil, errors.New("消息ID不能为空")
+	}
+	if content == "" {
+		return nil, errors.New("消息内容不能为空")
+	}
+
+	var dbMessage model.Message
+	if err := s.db.Where("id = ?", messageID).Take(&dbMessage).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("消息不存在")
+		}
+		return nil, fmt.Errorf("查询消息失败: %w", err)
+	}
+
+	if dbMessage.SenderID != userID {
+		return nil, errors.New("只能编辑自己发送的消息")
+	}
+	if dbMessage.Recalled {
+		return nil, errors.New("消息已撤回，无法编辑")
+	}
+	if !isTextMessage(dbMessage.ContentType) {
+		return nil, errors.New("只能编辑文本消息")
+	}
+	if dbMessage.Content == content {
+		return editNotice(&dbMessage), nil
+	}
+
+	now := time.Now()
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// 锁定消息行，保证并发编辑时修订号连续且保存的是真正的上一版本
+		var current model.Message
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", messageID).Take(&current).Error; err != nil {
+			return err
+		}
+		if current.Recalled {
+			return errors.New("消息已撤回，无法编辑")
+		}
+
+		var revisions int64
+		if err := tx.Model(&model.MessageRevision{}).Where("message_id = ?", messageID).Count(&revisions).Error; err != nil {
+			return err
+		}
+
+		// 保存编辑前的内容
+		revision := model.MessageRevision{
+			ID:        uuid.New().String(),
+			MessageID: messageID,
+			Revision:  int(revisions) + 1,
+			Content:   current.Content,
+			EditedBy:  userID,
+			CreatedAt: now,
+		}
+		if err := tx.Create(&revision).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&model.Message{}).Where("id = ?", messageID).Updates(map[string]interface{}{
+			"content":    content,
+			"edited_at":  now,
+			"updated_at": now,
+		}).Error; err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{
+			"content":   content,
+			"edited_at": now,
+		}
+		if dbMessage.IsGroup {
+			return tx.Model(&model.GroupMessage{}).Where("id = ?", messageID).Updates(updates).Error
+		}
+		return tx.Model(&model.PrivateMessage{}).Where("id = ?", messageID).Updates(updates).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("编辑消息失败: %w", err)
+	}
+
+	log.Printf("用户 %s 编辑了消息 %s (会话: %s)", userID, messageID, dbMessage.ConversationID)
+
+	dbMessage.Content = content
+	dbMessage.EditedAt = &now
+	s.indexMessage(search.DocumentFromModel(&dbMessage))
+	notice := editNotice(&dbMessage)
+
+	participants, err := s.messageParticipants(&dbMessage)
+	if err != nil {
+		return notice, err
+	}
+	if err := s.notifyUsers(ctx, notice, participants); err != nil {
+		return notice, err
+	}
+
+	return notice, nil
+}
+
+// GetMessageRevisions 获取消息的编辑历史
+// 发送者本人和群主/群管理员可以查看
+func (s *MessageService) GetMessageRevisions(ctx context.Context, messageID string, userID string) ([]model.MessageRevision, error) {
+	var dbMessage model.Message
+	if err := s.db.Where("id = ?", messageID).Take(&dbMessage).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("消息不存在")
+		}
+		return nil, fmt.Errorf("查询消息失败: %w", err)
+	}
+
+	if dbMessage.SenderID != userID && !(dbMessage.IsGroup && s.isGroupAdmin(dbMessage.RecipientID, userID)) {
+		return nil, errors.New("权限不足")
+	}
+
+	var revisions []model.MessageRevision
+	if err := s.db.Where("message_id = ?", messageID).
+		Order("revision asc").
+		Find(&revisions).Error; err != nil {
+		return nil, fmt.Errorf("查询编辑历史失败: %w", err)
+	}
+	return revisions, nil
+}
+
+// editNotice 构造编辑更新事件
+func editNotice(dbMessage *model.Message) *protocol.Message {
+	notice := &protocol.Message{
+		Type:           constants.MessageTypeEdit,
+		SenderID:       dbMessage.SenderID,
+		TargetID:       dbMessage.ID,
+		ConversationID: dbMessage.ConversationID,
+		Content:        dbMessage.Content,
+		IsGroup:        dbMessage.IsGroup,
+		EditedAt:       unixOrZero(dbMessage.EditedAt),
+		Timestamp:      time.Now().Unix(),
+	}
+	if dbMessage.IsGroup {
+		notice.GroupID = dbMessage.RecipientID
+	}
+	return notice
+}
+
+// isTextMessage 判断消息类型是否为文本
+func isTextMessage(contentType string) bool {
+	return contentType == "" || contentType == "message" || contentType == constants.MessageTypeText
+}
+
+// unixOrZero 将可空时间转换为 Unix 时间戳
+func unixOrZero(t *time.Time) int64 {
+	if t == nil {
+		return 0
+	}
+	return t.Unix()
+}
@@ -0,0 +1,292 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"cursorIM/internal/constants"
+	"cursorIM/internal/model"
+	"cursorIM/internal/protocol"
+)
+
+// 转发的限制
+const (
+	maxForwardMessages = 100 // 一次最多转发的消息数
+	maxForwardTargets  = 20  // 一次最多转发到的会话数
+)
+
+// ForwardTarget 转发目标，群聊时 RecipientID 为群组ID
+type ForwardTarget struct {
+	RecipientID string `json:"recipient_id" binding:"required"`
+	IsGroup     bool   `json:"is_group"`
+}
+
+// ForwardRequest 转发请求
+// Merge 为 false 时逐条转发，为 true 时把同一会话中的多条消息合并为一条聊天记录
+type ForwardRequest struct {
+	MessageIDs []string        `json:"message_ids" binding:"required"`
+	Targets    []ForwardTarget `json:"targets" binding:"required"`
+	Merge      bool            `json:"merge"`
+	Title      string          `json:"title"` // 聊天记录标题，为空时按来源会话生成
+}
+
+// ForwardMessages 把消息转发到其他会话或群组
+// 转发者必须能看到所有原消息，并且是目标群组的成员。转发的消息保留原发送者和原会话，
+// 图片、文件等媒体消息直接引用原地址，不重新上传
+func (s *MessageService) ForwardMessages(ctx context.Context, userID string, req ForwardRequest) ([]*protocol.Message, error) {
+	messageIDs := uniqueStrings(req.MessageIDs)
+	if len(messageIDs) == 0 {
+		return nil, errors.New("请选择要转发的消息")
+	}
+	if len(messageIDs) > maxForwardMessages {
+		return nil, fmt.Errorf("一次最多转发 %d 条消息", maxForwardMessages)
+	}
+	if len(req.Targets) == 0 {
+		return nil, errors.New("请选择转发目标")
+	}
+	if len(req.Targets) > maxForwardTargets {
+		return nil, fmt.Errorf("一次最多转发到 %d 个会话", maxForwardTargets)
+	}
+
+	sources, err := s.loadForwardSources(userID, messageIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	targets, err := s.validateForwardTargets(userID, req.Targets)
+	if err != nil {
+		return nil, err
+	}
+
+	var templates []*protocol.Message
+	if req.Merge {
+		record, err := s.buildChatRecord(sources, req.Title)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, record)
+	} else {
+		for i := range sources {
+			if sources[i].ContentType == constants.MessageTypePoll {
+				return nil, errors.New("投票不能逐条转发，请使用合并转发")
+			}
+			if sources[i].ContentType == constants.MessageTypeSystem {
+				return nil, errors.New("系统消息不能逐条转发")
+			}
+			templates = append(templates, forwardedCopy(&sources[i]))
+		}
+	}
+
+	chatService := NewChatService()
+	sent := make([]*protocol.Message, 0, len(targets)*len(templates))
+	for _, target := range targets {
+		conversationID := target.RecipientID
+		if !target.IsGroup {
+			conversationID, err = chatService.ResolvePrivateConversation(ctx, userID, target.RecipientID)
+			if err != nil {
+				return sent, err
+			}
+		}
+
+		for _, template := range templates {
+			message := *template
+			message.SenderID = userID
+			message.RecipientID = target.RecipientID
+			message.IsGroup = target.IsGroup
+			message.ConversationID = conversationID
+			message.Timestamp = time.Now().Unix()
+			if target.IsGroup {
+				message.GroupID = target.RecipientID
+			}
+
+			if err := s.saveMessage(ctx, &message); err != nil {
+				return sent, fmt.Errorf("保存转发消息失败: %w", err)
+			}
+			if err := s.deliver(&message); err != nil {
+				log.Printf("投递转发消息 %s 失败: %v", message.ID, err)
+			}
+			sent = append(sent, &message)
+		}
+	}
+
+	log.Printf("用户 %s 转发了 %d 条消息到 %d 个会话 (合并: %v)", userID, len(sources), len(targets), req.Merge)
+	return sent, nil
+}
+
+// loadForwardSources 加载并校验要转发的原消息
+// 原消息必须存在、未撤回、未到期，并且转发者是原会话的参与者；结果保持请求中的顺序
+func (s *MessageService) loadForwardSources(userID string, messageIDs []string) ([]model.Message, error) {
+	var found []model.Message
+	if err := s.db.Where("id IN ?", messageIDs).Find(&found).Error; err != nil {
+		return nil, fmt.Errorf("查询原消息失败: %w", err)
+	}
+
+	byID := make(map[string]model.Message, len(found))
+	for _, message := range found {
+		byID[message.ID] = message
+	}
+
+	now := time.Now()
+	visible := make(map[string]bool)
+	sources := make([]model.Message, 0, len(messageIDs))
+	for _, id := range messageIDs {
+		message, ok := byID[id]
+		if !ok || (message.ExpiresAt != nil && !message.ExpiresAt.After(now)) {
+			return nil, errors.New("消息不存在")
+		}
+		if message.Recalled {
+			return nil, errors.New("消息已撤回，无法转发")
+		}
+
+		canSee, checked := visible[message.ConversationID]
+		if !checked {
+			participants, err := s.messageParticipants(&message)
+			if err != nil {
+				return nil, err
+			}
+			canSee = containsString(participants, userID)
+			visible[message.ConversationID] = canSee
+		}
+		if !canSee {
+			return nil, errors.New("无权转发该消息")
+		}
+
+		sources = append(sources, message)
+	}
+	return sources, nil
+}
+
+// validateForwardTargets 去重并校验转发目标：群聊需要是群成员，单聊对象必须存在
+func (s *MessageService) validateForwardTargets(userID string, targets []ForwardTarget) ([]ForwardTarget, error) {
+	seen := make(map[ForwardTarget]bool, len(targets))
+	result := make([]ForwardTarget, 0, len(targets))
+	for _, target := range targets {
+		if target.RecipientID == "" {
+			return nil, errors.New("转发目标不能为空")
+		}
+		if seen[target] {
+			continue
+		}
+		seen[target] = true
+
+		if target.IsGroup {
+			if !s.IsGroupMember(target.RecipientID, userID) {
+				return nil, errors.New("您不是目标群组的成员")
+			}
+			if err := s.checkMuted(target.RecipientID, userID); err != nil {
+				return nil, err
+			}
+		} else {
+			var count int64
+			if err := s.db.Model(&model.User{}).Where("id = ?", target.RecipientID).Count(&count).Error; err != nil {
+				return nil, fmt.Errorf("查询转发目标失败: %w", err)
+			}
+			if count == 0 {
+				return nil, errors.New("转发目标用户不存在")
+			}
+		}
+		result = append(result, target)
+	}
+	return result, nil
+}
+
+// forwardedCopy 构造逐条转发的消息，多次转发时来源始终指向最初的消息
+func forwardedCopy(source *model.Message) *protocol.Message {
+	forward := &protocol.ForwardInfo{
+		MessageID:      source.ID,
+		SenderID:       source.SenderID,
+		ConversationID: source.ConversationID,
+	}
+	if source.ForwardFromID != "" || source.ForwardConversationID != "" {
+		forward = &protocol.ForwardInfo{
+			MessageID:      source.ForwardFromID,
+			SenderID:       source.ForwardSenderID,
+			ConversationID: source.ForwardConversationID,
+		}
+	}
+
+	message := &protocol.Message{
+		Type:    source.ContentType,
+		Content: source.Content,
+		Forward: forward,
+		Record:  protocol.DecodeChatRecord(source.Record),
+	}
+	protocol.DecodeBody(message, source.Body)
+	return message
+}
+
+// buildChatRecord 把同一会话中的多条消息合并为一条聊天记录，按会话内顺序排列
+// 聊天记录中的聊天记录只保留标题，不再展开
+func (s *MessageService) buildChatRecord(sources []model.Message, title string) (*protocol.Message, error) {
+	conversationID := sources[0].ConversationID
+	for _, source := range sources[1:] {
+		if source.ConversationID != conversationID {
+			return nil, errors.New("只能合并转发同一会话中的消息")
+		}
+	}
+
+	sort.SliceStable(sources, func(i, j int) bool {
+		if sources[i].Seq != sources[j].Seq {
+			return sources[i].Seq < sources[j].Seq
+		}
+		return sources[i].Timestamp < sources[j].Timestamp
+	})
+
+	if title == "" {
+		title = "聊天记录"
+		if sources[0].IsGroup {
+			var group model.Group
+			s.db.Select("name").Where("id = ?", sources[0].RecipientID).Limit(1).Find(&group)
+			if group.Name != "" {
+				title = group.Name + "的聊天记录"
+			}
+		}
+	}
+
+	record := &protocol.ChatRecord{Title: title}
+	for _, source := range sources {
+		record.Items = append(record.Items, &protocol.ChatRecordItem{
+			ID:        source.ID,
+			SenderID:  source.SenderID,
+			Type:      source.ContentType,
+			Content:   source.Content,
+			Timestamp: source.Timestamp,
+		})
+	}
+
+	return &protocol.Message{
+		Type:    constants.MessageTypeChatRecord,
+		Content: title,
+		Forward: &protocol.ForwardInfo{ConversationID: conversationID},
+		Record:  record,
+	}, nil
+}
+
+// applyForward 将转发来源和聊天记录写入数据库消息
+func applyForward(dbMessage *model.Message, message *protocol.Message) error {
+	if message.Forward != nil {
+		dbMessage.ForwardFromID = message.Forward.MessageID
+		dbMessage.ForwardSenderID = message.Forward.SenderID
+		dbMessage.ForwardConversationID = message.Forward.ConversationID
+	}
+
+	record, err := protocol.EncodeChatRecord(message.Record)
+	if err != nil {
+		return fmt.Errorf("序列化聊天记录失败: %w", err)
+	}
+	dbMessage.Record = record
+	return nil
+}
+
+// deliver 通过连接管理器投递一条已保存的消息，不在线的接收者由连接管理器存入离线队列
+func (s *MessageService) deliver(message *protocol.Message) error {
+	cm, ok := s.connManager.(interface{ SendMessage(*protocol.Message) error })
+	if !ok {
+		return errors.New("连接管理器未设置或不支持SendMessage")
+	}
+	return cm.SendMessage(message)
+}
@@ -0,0 +1,113 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"cursorIM/internal/model"
+	"cursorIM/internal/protocol"
+
+	"gorm.io/gorm"
+)
+
+// 历史消息分页的默认/最大条数
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 200
+)
+
+// HistoryQuery 历史消息分页参数
+// Before/After 可以是消息ID或序列号，都为空时返回最新的消息
+type HistoryQuery struct {
+	Before string
+	After  string
+	Limit  int
+}
+
+// GetMessageHistory 按游标分页获取会话的历史消息，结果按序列号升序排列
+// 只指定 Before 时向前翻页（HasMore 表示更早的消息），指定 After 时向后翻页（HasMore 表示更新的消息）
+func (s *MessageService) GetMessageHistory(ctx context.Context, conversationID string, query HistoryQuery) (*protocol.MessageBatch, error) {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+	if limit > maxHistoryLimit {
+		limit = maxHistoryLimit
+	}
+
+	// 话题内的回复不出现在主消息流中，通过 GetThreadMessages 获取
+	db := s.db.Where("conversation_id = ?", conversationID).
+		Where("thread_id IS NULL OR thread_id = ''").
+		Where("expires_at IS NULL OR expires_at > ?", time.Now())
+
+	if query.Before != "" {
+		beforeSeq, err := s.resolveCursor(conversationID, query.Before)
+		if err != nil {
+			return nil, err
+		}
+		db = db.Where("seq < ?", beforeSeq)
+	}
+
+	ascending := query.After != ""
+	if ascending {
+		afterSeq, err := s.resolveCursor(conversationID, query.After)
+		if err != nil {
+			return nil, err
+		}
+		db = db.Where("seq > ?", afterSeq).Order("seq asc, timestamp asc")
+	} else {
+		db = db.Order("seq desc, timestamp desc")
+	}
+
+	var dbMessages []model.Message
+	if err := db.Limit(limit + 1).Find(&dbMessages).Error; err != nil {
+		return nil, fmt.Errorf("查询历史消息失败: %w", err)
+	}
+
+	hasMore := len(dbMessages) > limit
+	if hasMore {
+		dbMessages = dbMessages[:limit]
+	}
+
+	messages := make([]*protocol.Message, 0, len(dbMessages))
+	if ascending {
+		for i := range dbMessages {
+			messages = append(messages, messageFromModel(&dbMessages[i]))
+		}
+	} else {
+		for i := len(dbMessages) - 1; i >= 0; i-- { // 反转顺序，最早的消息在前
+			messages = append(messages, messageFromModel(&dbMessages[i]))
+		}
+	}
+	s.attachReactions(messages)
+	s.attachPolls(messages)
+	s.attachReadCounts(messages)
+
+	return &protocol.MessageBatch{
+		Messages:   messages,
+		TotalCount: len(messages),
+		HasMore:    hasMore,
+	}, nil
+}
+
+// resolveCursor 将游标解析为序列号：纯数字视为序列号，否则视为会话中的消息ID
+func (s *MessageService) resolveCursor(conversationID, cursor string) (int64, error) {
+	if seq, err := strconv.ParseInt(cursor, 10, 64); err == nil {
+		return seq, nil
+	}
+
+	var dbMessage model.Message
+	err := s.db.Select("seq").
+		Where("id = ? AND conversation_id = ?", cursor, conversationID).
+		Take(&dbMessage).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, errors.New("游标消息不存在")
+		}
+		return 0, fmt.Errorf("查询游标消息失败: %w", err)
+	}
+	return dbMessage.Seq, nil
+}
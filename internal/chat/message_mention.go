@@ -0,0 +1,174 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"cursorIM/internal/constants"
+	"cursorIM/internal/model"
+	"cursorIM/internal/protocol"
+
+	"github.com/google/uuid"
+)
+
+// 旧版客户端通过 metadata["mentions"] 传递逗号分隔的提及列表，all/admins 为关键字
+const (
+	mentionMetadataKey   = "mentions"
+	mentionKeywordAll    = "all"
+	mentionKeywordAdmins = "admins"
+)
+
+// prepareMentions 校验消息中的提及并返回需要提醒的用户（不含发送者）
+// @所有人 只允许群主/群管理员使用，@管理员 只在群聊中有效，显式提及的用户必须在会话中
+func (s *MessageService) prepareMentions(message *protocol.Message) ([]string, error) {
+	mentionsFromMetadata(message)
+	message.Mentions = uniqueStrings(message.Mentions)
+
+	if len(message.Mentions) == 0 && !message.MentionAll && !message.MentionAdmins {
+		return nil, nil
+	}
+
+	if !message.IsGroup {
+		if message.MentionAll || message.MentionAdmins {
+			return nil, errors.New("只有群聊支持@所有人和@管理员")
+		}
+		for _, userID := range message.Mentions {
+			if userID != message.SenderID && userID != message.RecipientID {
+				return nil, errors.New("被提及的用户不在会话中")
+			}
+		}
+		return excludeUser(message.Mentions, message.SenderID), nil
+	}
+
+	groupID := message.RecipientID
+	if message.MentionAll && !s.isGroupAdmin(groupID, message.SenderID) {
+		return nil, errors.New("只有群主和群管理员可以@所有人")
+	}
+
+	var members []model.GroupMember
+	if err := s.db.Where("group_id = ?", groupID).Find(&members).Error; err != nil {
+		return nil, fmt.Errorf("获取群组成员失败: %w", err)
+	}
+
+	isMember := make(map[string]bool, len(members))
+	for _, member := range members {
+		isMember[member.UserID] = true
+	}
+	for _, userID := range message.Mentions {
+		if !isMember[userID] {
+			return nil, errors.New("被提及的用户不在群组中")
+		}
+	}
+
+	mentioned := append([]string(nil), message.Mentions...)
+	if message.MentionAll {
+		for _, member := range members {
+			mentioned = append(mentioned, member.UserID)
+		}
+	} else if message.MentionAdmins {
+		var group model.Group
+		s.db.Select("owner_id").Where("id = ?", groupID).Limit(1).Find(&group)
+		for _, member := range members {
+			if member.Role != constants.GroupRoleMember || member.UserID == group.OwnerID {
+				mentioned = append(mentioned, member.UserID)
+			}
+		}
+	}
+
+	return excludeUser(uniqueStrings(mentioned), message.SenderID), nil
+}
+
+// mentionsFromMetadata 兼容旧版客户端放在 metadata 中的提及列表
+func mentionsFromMetadata(message *protocol.Message) {
+	raw, ok := message.Metadata[mentionMetadataKey]
+	if !ok || len(message.Mentions) > 0 || message.MentionAll || message.MentionAdmins {
+		return
+	}
+
+	for _, item := range strings.Split(raw, ",") {
+		item = strings.TrimSpace(item)
+		switch item {
+		case "":
+		case mentionKeywordAll:
+			message.MentionAll = true
+		case mentionKeywordAdmins:
+			message.MentionAdmins = true
+		default:
+			message.Mentions = append(message.Mentions, item)
+		}
+	}
+}
+
+// saveMentions 保存提及记录并向被提及的用户推送提醒
+// 提醒作为独立事件发送，客户端对免打扰的会话也应展示
+func (s *MessageService) saveMentions(ctx context.Context, message *protocol.Message, mentioned []string) error {
+	if len(mentioned) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	mentions := make([]model.MessageMention, 0, len(mentioned))
+	for _, userID := range mentioned {
+		mentions = append(mentions, model.MessageMention{
+			ID:             uuid.New().String(),
+			MessageID:      message.ID,
+			ConversationID: message.ConversationID,
+			UserID:         userID,
+			CreatedAt:      now,
+		})
+	}
+	if err := s.db.CreateInBatches(mentions, 500).Error; err != nil {
+		return fmt.Errorf("保存提及记录失败: %w", err)
+	}
+
+	log.Printf("消息 %s 提及了 %d 个用户", message.ID, len(mentioned))
+
+	notice := &protocol.Message{
+		Type:           constants.MessageTypeMention,
+		SenderID:       message.SenderID,
+		TargetID:       message.ID,
+		ConversationID: message.ConversationID,
+		IsGroup:        message.IsGroup,
+		Content:        message.Content,
+		MentionAll:     message.MentionAll,
+		MentionAdmins:  message.MentionAdmins,
+		Timestamp:      now.Unix(),
+	}
+	if message.IsGroup {
+		notice.GroupID = message.RecipientID
+	}
+	return s.notifyUsers(ctx, notice, mentioned)
+}
+
+// uniqueStrings 去掉重复和空的字符串（用户ID、消息ID等），保持原有顺序
+func uniqueStrings(values []string) []string {
+	if len(values) == 0 {
+		return values
+	}
+
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		result = append(result, value)
+	}
+	return result
+}
+
+// excludeUser 从列表中去掉指定用户
+func excludeUser(userIDs []string, userID string) []string {
+	result := make([]string, 0, len(userIDs))
+	for _, id := range userIDs {
+		if id != userID {
+			result = append(result, id)
+		}
+	}
+	return result
+}
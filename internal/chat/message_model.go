@@ -1,7 +1,7 @@
 package chat
 
 import (
-    "cursorIM/internal/protocol"
+	"cursorIM/internal/protocol"
 )
 
 // Message 类型别名，使用 protocol.Message
@@ -0,0 +1,61 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"cursorIM/internal/constants"
+	"cursorIM/internal/model"
+)
+
+// ErrMemberMuted 发送者在群内被禁言
+var ErrMemberMuted = errors.New("您已被禁言")
+
+// ErrGroupMuted 群组开启了全员禁言，只有群主和管理员可以发言
+var ErrGroupMuted = errors.New("群组已开启全员禁言")
+
+// ErrNotGroupMember 发送者不是群成员（未加入、已退出或被移出）
+var ErrNotGroupMember = errors.New("您不是该群组的成员")
+
+// ErrorCode 返回错误对应的业务错误码，没有对应的错误码时返回空字符串
+func ErrorCode(err error) string {
+	switch {
+	case errors.Is(err, ErrMemberMuted):
+		return constants.ErrorCodeMemberMuted
+	case errors.Is(err, ErrGroupMuted):
+		return constants.ErrorCodeGroupMuted
+	case errors.Is(err, ErrNotGroupMember):
+		return constants.ErrorCodeNotGroupMember
+	}
+	return ""
+}
+
+// checkMuted 校验用户可以在群内发言
+// 禁言按结束时间判断，到期后立即失效，不依赖后台清理
+func (s *MessageService) checkMuted(groupID, userID string) error {
+	var member model.GroupMember
+	if err := s.db.Where("group_id = ? AND user_id = ?", groupID, userID).Limit(1).Find(&member).Error; err != nil {
+		return fmt.Errorf("查询群成员失败: %w", err)
+	}
+	if member.ID == "" {
+		return ErrNotGroupMember
+	}
+
+	now := time.Now()
+	if member.MutedUntil != nil && member.MutedUntil.After(now) {
+		return fmt.Errorf("%w，将于 %s 解除", ErrMemberMuted, member.MutedUntil.Format("2006-01-02 15:04:05"))
+	}
+	if member.Role != constants.GroupRoleMember {
+		return nil
+	}
+
+	var group model.Group
+	if err := s.db.Select("id, mute_all, mute_all_until").Where("id = ?", groupID).Limit(1).Find(&group).Error; err != nil {
+		return fmt.Errorf("查询群组失败: %w", err)
+	}
+	if group.MuteAll && (group.MuteAllUntil == nil || group.MuteAllUntil.After(now)) {
+		return ErrGroupMuted
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"cursorIM/internal/constants"
+	"cursorIM/internal/model"
+	"cursorIM/internal/protocol"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxPinnedMessages 每个会话最多置顶的消息数
+const maxPinnedMessages = 50
+
+// PinnedMessageInfo 置顶消息及置顶操作信息
+type PinnedMessageInfo struct {
+	Message  *protocol.Message `json:"message"`
+	PinnedBy string            `json:"pinned_by"`
+	PinnedAt int64             `json:"pinned_at"`
+}
+
+// PinMessage 置顶或取消置顶消息
+// 单聊双方都可以置顶，群聊只有群主和群管理员可以置顶。变更后向所有参与者推送置顶事件
+func (s *MessageService) PinMessage(ctx context.Context, messageID string, userID string, unpin bool) (*protocol.Message, error) {
+	if messageID == "" {
+		return nil, errors.New("消息ID不能为空")
+	}
+
+	var dbMessage model.Message
+	if err := s.db.Where("id = ?", messageID).
+		Take(&dbMessage).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("消息不存在")
+		}
+		return nil, fmt.Errorf("查询消息失败: %w", err)
+	}
+
+	participants, err := s.messageParticipants(&dbMessage)
+	if err != nil {
+		return nil, err
+	}
+	if !containsString(participants, userID) {
+		return nil, errors.New("权限不足")
+	}
+	if dbMessage.IsGroup && !s.isGroupAdmin(dbMessage.RecipientID, userID) {
+		return nil, errors.New("只有群主和群管理员可以置顶消息")
+	}
+
+	if unpin {
+		err = s.db.Where("conversation_id = ? AND message_id = ?", dbMessage.ConversationID, messageID).
+			Delete(&model.PinnedMessage{}).Error
+	} else {
+		err = s.pin(&dbMessage, userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	action := "置顶"
+	if unpin {
+		action = "取消置顶"
+	}
+	log.Printf("用户 %s %s了消息 %s (会话: %s)", userID, action, messageID, dbMessage.ConversationID)
+
+	notice := pinNotice(&dbMessage, userID, unpin)
+	if err := s.notifyUsers(ctx, notice, participants); err != nil {
+		return notice, err
+	}
+	return notice, nil
+}
+
+// pin 保存置顶记录，重复置顶视为成功
+func (s *MessageService) pin(dbMessage *model.Message, userID string) error {
+	if dbMessage.Recalled {
+		return errors.New("消息已撤回，无法置顶")
+	}
+	if dbMessage.ExpiresAt != nil && !dbMessage.ExpiresAt.After(time.Now()) {
+		return errors.New("消息已过期，无法置顶")
+	}
+
+	var count int64
+	if err := s.db.Model(&model.PinnedMessage{}).
+		Where("conversation_id = ?", dbMessage.ConversationID).
+		Count(&count).Error; err != nil {
+		return fmt.Errorf("查询置顶消息失败: %w", err)
+	}
+	if count >= maxPinnedMessages {
+		return fmt.Errorf("每个会话最多置顶%d条消息", maxPinnedMessages)
+	}
+
+	err := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.PinnedMessage{
+		ID:             uuid.New().String(),
+		ConversationID: dbMessage.ConversationID,
+		MessageID:      dbMessage.ID,
+		PinnedBy:       userID,
+		CreatedAt:      time.Now(),
+	}).Error
+	if err != nil {
+		return fmt.Errorf("置顶消息失败: %w", err)
+	}
+	return nil
+}
+
+// pinNotice 构造置顶变更事件，置顶时 content 为被置顶消息的内容
+func pinNotice(dbMessage *model.Message, userID string, unpin bool) *protocol.Message {
+	notice := &protocol.Message{
+		Type:           constants.MessageTypePin,
+		SenderID:       userID,
+		TargetID:       dbMessage.ID,
+		ConversationID: dbMessage.ConversationID,
+		IsGroup:        dbMessage.IsGroup,
+		Remove:         unpin,
+		Timestamp:      time.Now().Unix(),
+	}
+	if !unpin {
+		notice.Content = dbMessage.Content
+	}
+	if dbMessage.IsGroup {
+		notice.GroupID = dbMessage.RecipientID
+	}
+	return notice
+}
+
+// GetPinnedMessages 获取会话的置顶消息，最近置顶的在前
+// 已撤回或已过期的消息不再返回
+func (s *MessageService) GetPinnedMessages(ctx context.Context, conversationID string, userID string) ([]*PinnedMessageInfo, error) {
+	if conversationID == "" {
+		return nil, errors.New("会话ID不能为空")
+	}
+	if _, err := s.conversationMembership(conversationID, userID); err != nil {
+		return nil, err
+	}
+
+	var pins []model.PinnedMessage
+	if err := s.db.Where("conversation_id = ?", conversationID).
+		Order("created_at desc").
+		Find(&pins).Error; err != nil {
+		return nil, fmt.Errorf("查询置顶消息失败: %w", err)
+	}
+	if len(pins) == 0 {
+		return []*PinnedMessageInfo{}, nil
+	}
+
+	messageIDs := make([]string, 0, len(pins))
+	for _, pin := range pins {
+		messageIDs = append(messageIDs, pin.MessageID)
+	}
+
+	var dbMessages []model.Message
+	if err := s.db.Where("id IN ? AND recalled = ?", messageIDs, false).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+		Find(&dbMessages).Error; err != nil {
+		return nil, fmt.Errorf("查询置顶消息失败: %w", err)
+	}
+
+	byID := make(map[string]*protocol.Message, len(dbMessages))
+	messages := make([]*protocol.Message, 0, len(dbMessages))
+	for i := range dbMessages {
+		message := messageFromModel(&dbMessages[i])
+		byID[message.ID] = message
+		messages = append(messages, message)
+	}
+	s.attachReactions(messages)
+	s.attachPolls(messages)
+
+	result := make([]*PinnedMessageInfo, 0, len(pins))
+	for _, pin := range pins {
+		message, ok := byID[pin.MessageID]
+		if !ok {
+			continue
+		}
+		result = append(result, &PinnedMessageInfo{
+			Message:  message,
+			PinnedBy: pin.PinnedBy,
+			PinnedAt: pin.CreatedAt.Unix(),
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,386 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"cursorIM/internal/constants"
+	"cursorIM/internal/model"
+	"cursorIM/internal/protocol"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 到期投票的检查间隔和每轮最多结束的投票数
+const (
+	pollCloseInterval = 5 * time.Second
+	pollCloseBatch    = 100
+)
+
+// pollRecord 由新发起的投票消息生成投票状态记录
+func pollRecord(message *protocol.Message) *model.Poll {
+	record := &model.Poll{
+		ID:             message.ID,
+		ConversationID: message.ConversationID,
+		CreatorID:      message.SenderID,
+		Multiple:       message.Poll.Multiple,
+		Anonymous:      message.Poll.Anonymous,
+		CreatedAt:      time.Now(),
+	}
+	if message.Poll.CloseAt != 0 {
+		closeAt := time.Unix(message.Poll.CloseAt, 0)
+		record.CloseAt = &closeAt
+	}
+	return record
+}
+
+// VotePoll 投票或修改投票，optionIDs 为空表示撤回自己的投票
+// 只有群成员可以投票，单选投票只能选择一个选项。投票后向所有群成员推送最新计票结果
+func (s *MessageService) VotePoll(ctx context.Context, pollID string, userID string, optionIDs []string) (*protocol.Message, error) {
+	if pollID == "" {
+		return nil, errors.New("投票ID不能为空")
+	}
+
+	dbMessage, err := s.loadPollMessage(pollID, userID)
+	if err != nil {
+		return nil, err
+	}
+	poll := messageFromModel(dbMessage).Poll
+	if poll == nil {
+		return nil, errors.New("投票内容已损坏")
+	}
+
+	optionIDs = uniqueStrings(optionIDs)
+	valid := make(map[string]bool, len(poll.Options))
+	for _, option := range poll.Options {
+		valid[option.ID] = true
+	}
+	for _, optionID := range optionIDs {
+		if !valid[optionID] {
+			return nil, errors.New("投票选项不存在")
+		}
+	}
+
+	now := time.Now()
+	var record model.Poll
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// 锁定投票状态，避免与结束投票并发
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", pollID).Take(&record).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("投票不存在")
+			}
+			return err
+		}
+		if pollClosed(&record, now) {
+			return errors.New("投票已结束")
+		}
+		if !record.Multiple && len(optionIDs) > 1 {
+			return errors.New("该投票只能选择一个选项")
+		}
+
+		if err := tx.Where("poll_id = ? AND user_id = ?", pollID, userID).
+			Delete(&model.PollVote{}).Error; err != nil {
+			return err
+		}
+		for _, optionID := range optionIDs {
+			if err := tx.Create(&model.PollVote{
+				ID:        uuid.New().String(),
+				PollID:    pollID,
+				UserID:    userID,
+				OptionID:  optionID,
+				CreatedAt: now,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("用户 %s 在投票 %s 中选择了 %v", userID, pollID, optionIDs)
+
+	notice, err := s.pollNotice(constants.MessageTypeVote, dbMessage, &record, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.notifyGroupPoll(ctx, notice, dbMessage); err != nil {
+		return notice, err
+	}
+
+	// 回执中带上投票者自己的选择（匿名投票的事件中不包含）
+	response := *notice
+	response.OptionIDs = optionIDs
+	return &response, nil
+}
+
+// ClosePoll 手动结束投票，只有发起者和群主/群管理员可以操作
+func (s *MessageService) ClosePoll(ctx context.Context, pollID string, userID string) (*protocol.Message, error) {
+	if pollID == "" {
+		return nil, errors.New("投票ID不能为空")
+	}
+
+	dbMessage, err := s.loadPollMessage(pollID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if dbMessage.SenderID != userID && !s.isGroupAdmin(dbMessage.RecipientID, userID) {
+		return nil, errors.New("只有发起者和群管理员可以结束投票")
+	}
+
+	notice, err := s.closePoll(ctx, dbMessage, userID)
+	if err != nil {
+		return nil, err
+	}
+	if notice == nil {
+		return nil, errors.New("投票已结束")
+	}
+	return notice, nil
+}
+
+// GetPoll 获取投票的最新结果，只有群成员可以查看
+func (s *MessageService) GetPoll(ctx context.Context, pollID string, userID string) (*protocol.Poll, error) {
+	dbMessage, err := s.loadPollMessage(pollID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	message := messageFromModel(dbMessage)
+	s.attachPolls([]*protocol.Message{message})
+	if message.Poll == nil {
+		return nil, errors.New("投票不存在")
+	}
+	return message.Poll, nil
+}
+
+// RunPollCloser 定期结束到期的投票并推送最终结果，直到 ctx 取消
+// 结束操作以数据库条件更新为准，多个节点同时运行时每个投票只会结束一次
+func (s *MessageService) RunPollCloser(ctx context.Context) {
+	ticker := time.NewTicker(pollCloseInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.closeDuePolls(ctx, time.Now()); err != nil {
+				log.Printf("结束到期投票失败: %v", err)
+			}
+		}
+	}
+}
+
+// closeDuePolls 结束一批到期的投票
+func (s *MessageService) closeDuePolls(ctx context.Context, now time.Time) error {
+	var pollIDs []string
+	if err := s.db.Model(&model.Poll{}).
+		Where("closed_at IS NULL AND close_at IS NOT NULL AND close_at <= ?", now).
+		Order("close_at asc").
+		Limit(pollCloseBatch).
+		Pluck("id", &pollIDs).Error; err != nil {
+		return err
+	}
+
+	for _, pollID := range pollIDs {
+		var dbMessage model.Message
+		if err := s.db.Where("id = ?", pollID).Take(&dbMessage).Error; err != nil {
+			log.Printf("查询投票消息 %s 失败: %v", pollID, err)
+			continue
+		}
+		if _, err := s.closePoll(ctx, &dbMessage, ""); err != nil {
+			log.Printf("结束投票 %s 失败: %v", pollID, err)
+		}
+	}
+	return nil
+}
+
+// closePoll 标记投票结束并向群成员推送最终结果；投票已被结束时返回 nil
+func (s *MessageService) closePoll(ctx context.Context, dbMessage *model.Message, operatorID string) (*protocol.Message, error) {
+	now := time.Now()
+	result := s.db.Model(&model.Poll{}).
+		Where("id = ? AND closed_at IS NULL", dbMessage.ID).
+		Updates(map[string]interface{}{
+			"closed_at": now,
+			"closed_by": operatorID,
+		})
+	if result.Error != nil {
+		return nil, fmt.Errorf("结束投票失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	var record model.Poll
+	if err := s.db.Where("id = ?", dbMessage.ID).Take(&record).Error; err != nil {
+		return nil, fmt.Errorf("查询投票失败: %w", err)
+	}
+
+	log.Printf("投票 %s 已结束 (操作人: %q)", dbMessage.ID, operatorID)
+
+	notice, err := s.pollNotice(constants.MessageTypePollClose, dbMessage, &record, operatorID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.notifyGroupPoll(ctx, notice, dbMessage); err != nil {
+		return notice, err
+	}
+	return notice, nil
+}
+
+// loadPollMessage 加载投票消息并校验用户是群成员
+func (s *MessageService) loadPollMessage(pollID, userID string) (*model.Message, error) {
+	var dbMessage model.Message
+	if err := s.db.Where("id = ? AND content_type = ?", pollID, constants.MessageTypePoll).
+		Take(&dbMessage).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("投票不存在")
+		}
+		return nil, fmt.Errorf("查询投票失败: %w", err)
+	}
+	if dbMessage.Recalled {
+		return nil, errors.New("投票已撤回")
+	}
+	if dbMessage.ExpiresAt != nil && !dbMessage.ExpiresAt.After(time.Now()) {
+		return nil, errors.New("投票不存在")
+	}
+	if !s.IsGroupMember(dbMessage.RecipientID, userID) {
+		return nil, errors.New("您不是该群组的成员")
+	}
+	return &dbMessage, nil
+}
+
+// pollNotice 构造计票或结束投票事件，poll 为最新结果
+// 匿名投票的事件不暴露投票者
+func (s *MessageService) pollNotice(noticeType string, dbMessage *model.Message, record *model.Poll, operatorID string) (*protocol.Message, error) {
+	message := messageFromModel(dbMessage)
+	if message.Poll == nil {
+		return nil, errors.New("投票内容已损坏")
+	}
+	votes, err := s.loadPollVotes([]string{record.ID})
+	if err != nil {
+		return nil, err
+	}
+	applyPollState(message.Poll, record, votes[record.ID])
+
+	// 匿名投票不暴露操作人，到期自动结束时没有操作人
+	if record.Anonymous || operatorID == "" {
+		operatorID = "server"
+	}
+	return &protocol.Message{
+		Type:           noticeType,
+		SenderID:       operatorID,
+		TargetID:       dbMessage.ID,
+		ConversationID: dbMessage.ConversationID,
+		GroupID:        dbMessage.RecipientID,
+		IsGroup:        true,
+		Poll:           message.Poll,
+		Timestamp:      time.Now().Unix(),
+	}, nil
+}
+
+// notifyGroupPoll 向投票所在群组的所有成员推送事件
+func (s *MessageService) notifyGroupPoll(ctx context.Context, notice *protocol.Message, dbMessage *model.Message) error {
+	members, err := s.messageParticipants(dbMessage)
+	if err != nil {
+		return err
+	}
+	return s.notifyUsers(ctx, notice, members)
+}
+
+// attachPolls 为一批协议消息中的投票填充计票结果和结束状态
+// 查询失败只记录日志，不影响消息列表的返回
+func (s *MessageService) attachPolls(messages []*protocol.Message) {
+	var pollIDs []string
+	for _, message := range messages {
+		if message.Poll != nil {
+			pollIDs = append(pollIDs, message.ID)
+		}
+	}
+	if len(pollIDs) == 0 {
+		return
+	}
+
+	var records []model.Poll
+	if err := s.db.Where("id IN ?", pollIDs).Find(&records).Error; err != nil {
+		log.Printf("加载投票状态失败: %v", err)
+		return
+	}
+	votes, err := s.loadPollVotes(pollIDs)
+	if err != nil {
+		log.Printf("加载投票记录失败: %v", err)
+		return
+	}
+
+	byID := make(map[string]*model.Poll, len(records))
+	for i := range records {
+		byID[records[i].ID] = &records[i]
+	}
+	for _, message := range messages {
+		if record, ok := byID[message.ID]; ok && message.Poll != nil {
+			applyPollState(message.Poll, record, votes[message.ID])
+		}
+	}
+}
+
+// loadPollVotes 批量查询投票记录，按投票时间排序
+func (s *MessageService) loadPollVotes(pollIDs []string) (map[string][]model.PollVote, error) {
+	var votes []model.PollVote
+	if err := s.db.Where("poll_id IN ?", pollIDs).
+		Order("created_at asc").
+		Find(&votes).Error; err != nil {
+		return nil, fmt.Errorf("查询投票记录失败: %w", err)
+	}
+
+	result := make(map[string][]model.PollVote, len(pollIDs))
+	for _, vote := range votes {
+		result[vote.PollID] = append(result[vote.PollID], vote)
+	}
+	return result, nil
+}
+
+// applyPollState 把计票结果和结束状态写入投票消息体
+func applyPollState(poll *protocol.Poll, record *model.Poll, votes []model.PollVote) {
+	poll.Multiple = record.Multiple
+	poll.Anonymous = record.Anonymous
+	poll.Closed = pollClosed(record, time.Now())
+	poll.ClosedAt = 0
+	if record.ClosedAt != nil {
+		poll.ClosedAt = record.ClosedAt.Unix()
+	} else if poll.Closed {
+		poll.ClosedAt = record.CloseAt.Unix()
+	}
+
+	byOption := make(map[string]*protocol.PollOption, len(poll.Options))
+	for _, option := range poll.Options {
+		option.Count = 0
+		option.VoterIDs = nil
+		byOption[option.ID] = option
+	}
+
+	voters := make(map[string]bool)
+	for _, vote := range votes {
+		option, ok := byOption[vote.OptionID]
+		if !ok {
+			continue
+		}
+		option.Count++
+		if !record.Anonymous {
+			option.VoterIDs = append(option.VoterIDs, vote.UserID)
+		}
+		voters[vote.UserID] = true
+	}
+	poll.VoterCount = len(voters)
+}
+
+// pollClosed 判断投票是否已结束：已手动结束，或已过自动结束时间（后台任务可能尚未处理）
+func pollClosed(record *model.Poll, now time.Time) bool {
+	return record.ClosedAt != nil || (record.CloseAt != nil && !record.CloseAt.After(now))
+}
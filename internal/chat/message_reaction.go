@@ -0,0 +1,178 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	"unicode/utf8"
+
+	"cursorIM/internal/constants"
+	"cursorIM/internal/model"
+	"cursorIM/internal/protocol"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxEmojiLength 单个表情允许的最大字符数（组合表情由多个码点构成）
+const maxEmojiLength = 16
+
+// ReactToMessage 添加或取消对消息的表情回应
+// 用户只能回应自己可见的消息（单聊双方或群成员）。回应不会作为消息写入会话，
+// 变更后向所有参与者推送包含最新汇总的回应事件
+func (s *MessageService) ReactToMessage(ctx context.Context, messageID string, userID string, emoji string, remove bool) (*protocol.Message, error) {
+	if messageID == "" {
+		return nil, errors.New("消息ID不能为空")
+	}
+	if emoji == "" {
+		return nil, errors.New("表情不能为空")
+	}
+	if utf8.RuneCountInString(emoji) > maxEmojiLength {
+		return nil, errors.New("表情过长")
+	}
+
+	var dbMessage model.Message
+	if err := s.db.Where("id = ?", messageID).Take(&dbMessage).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("消息不存在")
+		}
+		return nil, fmt.Errorf("查询消息失败: %w", err)
+	}
+
+	participants, err := s.messageParticipants(&dbMessage)
+	if err != nil {
+		return nil, err
+	}
+	if !containsString(participants, userID) {
+		return nil, errors.New("无权回应该消息")
+	}
+	if dbMessage.Recalled && !remove {
+		return nil, errors.New("消息已撤回，无法回应")
+	}
+
+	if remove {
+		err = s.db.Where("message_id = ? AND user_id = ? AND emoji = ?", messageID, userID, emoji).
+			Delete(&model.MessageReaction{}).Error
+	} else {
+		// 重复回应同一表情视为成功
+		err = s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.MessageReaction{
+			ID:        uuid.New().String(),
+			MessageID: messageID,
+			UserID:    userID,
+			Emoji:     emoji,
+			CreatedAt: time.Now(),
+		}).Error
+	}
+	if err != nil {
+		return nil, fmt.Errorf("更新表情回应失败: %w", err)
+	}
+
+	summaries, err := s.loadReactions([]string{messageID})
+	if err != nil {
+		return nil, err
+	}
+
+	action := "添加"
+	if remove {
+		action = "取消"
+	}
+	log.Printf("用户 %s 对消息 %s %s表情回应 %s", userID, messageID, action, emoji)
+
+	notice := reactionNotice(&dbMessage, userID, emoji, remove, summaries[messageID])
+	if err := s.notifyUsers(ctx, notice, participants); err != nil {
+		return notice, err
+	}
+
+	return notice, nil
+}
+
+// reactionNotice 构造表情回应变更事件
+func reactionNotice(dbMessage *model.Message, userID, emoji string, remove bool, reactions []*protocol.ReactionSummary) *protocol.Message {
+	notice := &protocol.Message{
+		Type:           constants.MessageTypeReaction,
+		SenderID:       userID,
+		TargetID:       dbMessage.ID,
+		ConversationID: dbMessage.ConversationID,
+		IsGroup:        dbMessage.IsGroup,
+		Content:        emoji,
+		Emoji:          emoji,
+		Remove:         remove,
+		Reactions:      reactions,
+		Timestamp:      time.Now().Unix(),
+	}
+	if dbMessage.IsGroup {
+		notice.GroupID = dbMessage.RecipientID
+	}
+	return notice
+}
+
+// loadReactions 批量查询消息的表情回应汇总，按表情首次出现的时间排序
+func (s *MessageService) loadReactions(messageIDs []string) (map[string][]*protocol.ReactionSummary, error) {
+	result := make(map[string][]*protocol.ReactionSummary)
+	if len(messageIDs) == 0 {
+		return result, nil
+	}
+
+	var reactions []model.MessageReaction
+	if err := s.db.Where("message_id IN ?", messageIDs).
+		Order("created_at asc").
+		Find(&reactions).Error; err != nil {
+		return nil, fmt.Errorf("查询表情回应失败: %w", err)
+	}
+
+	index := make(map[string]map[string]*protocol.ReactionSummary)
+	for _, reaction := range reactions {
+		byEmoji, ok := index[reaction.MessageID]
+		if !ok {
+			byEmoji = make(map[string]*protocol.ReactionSummary)
+			index[reaction.MessageID] = byEmoji
+		}
+
+		summary, ok := byEmoji[reaction.Emoji]
+		if !ok {
+			summary = &protocol.ReactionSummary{Emoji: reaction.Emoji}
+			byEmoji[reaction.Emoji] = summary
+			result[reaction.MessageID] = append(result[reaction.MessageID], summary)
+		}
+		summary.Count++
+		summary.UserIDs = append(summary.UserIDs, reaction.UserID)
+	}
+
+	return result, nil
+}
+
+// attachReactions 为一批协议消息填充表情回应汇总
+// 查询失败只记录日志，不影响历史消息的返回
+func (s *MessageService) attachReactions(messages []*protocol.Message) {
+	if len(messages) == 0 {
+		return
+	}
+
+	messageIDs := make([]string, 0, len(messages))
+	for _, message := range messages {
+		messageIDs = append(messageIDs, message.ID)
+	}
+
+	summaries, err := s.loadReactions(messageIDs)
+	if err != nil {
+		log.Printf("加载表情回应失败: %v", err)
+		return
+	}
+
+	for _, message := range messages {
+		message.Reactions = summaries[message.ID]
+	}
+}
+
+// containsString 判断字符串（用户ID、消息类型等）是否在列表中
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,354 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"cursorIM/internal/constants"
+	"cursorIM/internal/model"
+	"cursorIM/internal/protocol"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MessageReadInfo 单条消息的已读情况
+type MessageReadInfo struct {
+	MessageID   string   `json:"message_id"`
+	ReadCount   int      `json:"read_count"`
+	MemberCount int      `json:"member_count"`
+	ReadBy      []string `json:"read_by"`
+	UnreadBy    []string `json:"unread_by"`
+}
+
+// readableStatuses 可以被标记为已读的消息状态
+var readableStatuses = []string{
+	constants.MessageStatusUnsent,
+	constants.MessageStatusSent,
+	constants.MessageStatusDelivered,
+}
+
+// MarkMessagesAsRead 将会话中的消息全部标记为已读
+func (s *MessageService) MarkMessagesAsRead(ctx context.Context, conversationID string, userID string) error {
+	_, err := s.MarkConversationRead(ctx, conversationID, userID, 0)
+	return err
+}
+
+// MarkConversationRead 将用户在会话中的已读位置推进到 upToSeq（0 表示最新消息）
+// 已读位置越过的消息状态改为已读，并向这些消息的发送者推送已读回执。
+// 返回推进后的已读位置，已读位置不会后退
+func (s *MessageService) MarkConversationRead(ctx context.Context, conversationID string, userID string, upToSeq int64) (int64, error) {
+	if conversationID == "" {
+		return 0, errors.New("会话ID不能为空")
+	}
+
+	isGroup, err := s.conversationMembership(conversationID, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+
+	var maxSeq int64
+	if err := s.db.Model(&model.Message{}).
+		Where("conversation_id = ?", conversationID).
+		Select("COALESCE(MAX(seq), 0)").
+		Scan(&maxSeq).Error; err != nil {
+		return 0, fmt.Errorf("查询会话最新序列号失败: %w", err)
+	}
+	if upToSeq <= 0 || upToSeq > maxSeq {
+		upToSeq = maxSeq
+	}
+
+	var previous int64
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var state model.ConversationReadState
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("conversation_id = ? AND user_id = ?", conversationID, userID).
+			Limit(1).Find(&state).Error; err != nil {
+			return err
+		}
+
+		previous = state.LastReadSeq
+		if upToSeq <= previous {
+			return nil
+		}
+
+		// 会话列表的未读数按参与者的最后读取时间统计，推进到 upToSeq 对应消息的发送时间，
+		// 只读到中间位置时其后的消息仍计入未读
+		var readAt []time.Time
+		if err := tx.Model(&model.Message{}).
+			Where("conversation_id = ? AND seq = ?", conversationID, upToSeq).
+			Limit(1).Pluck("created_at", &readAt).Error; err != nil {
+			return err
+		}
+		if len(readAt) > 0 {
+			if err := tx.Model(&model.Participant{}).
+				Where("conversation_id = ? AND user_id = ?", conversationID, userID).
+				Where("last_read_at IS NULL OR last_read_at < ?", readAt[0]).
+				Update("last_read_at", readAt[0]).Error; err != nil {
+				return err
+			}
+		}
+
+		if state.ID == "" {
+			return tx.Create(&model.ConversationReadState{
+				ID:             uuid.New().String(),
+				ConversationID: conversationID,
+				UserID:         userID,
+				LastReadSeq:    upToSeq,
+				LastReadAt:     now,
+				UpdatedAt:      now,
+			}).Error
+		}
+		return tx.Model(&model.ConversationReadState{}).Where("id = ?", state.ID).Updates(map[string]interface{}{
+			"last_read_seq": upToSeq,
+			"last_read_at":  now,
+			"updated_at":    now,
+		}).Error
+	})
+	if err != nil {
+		return 0, fmt.Errorf("更新已读位置失败: %w", err)
+	}
+
+	if upToSeq <= previous {
+		return previous, nil
+	}
+
+	if err := s.markRangeRead(conversationID, userID, isGroup, previous, upToSeq); err != nil {
+		return upToSeq, err
+	}
+
+	if err := s.startReadTimers(conversationID, userID, previous, upToSeq); err != nil {
+		return upToSeq, err
+	}
+
+	if err := s.sendReadReceipts(ctx, conversationID, userID, isGroup, previous, upToSeq); err != nil {
+		return upToSeq, err
+	}
+
+	return upToSeq, nil
+}
+
+// conversationMembership 校验用户属于会话，返回会话是否为群聊（群聊以群组ID作为会话ID）
+func (s *MessageService) conversationMembership(conversationID, userID string) (bool, error) {
+	var count int64
+	s.db.Model(&model.GroupMember{}).
+		Where("group_id = ? AND user_id = ?", conversationID, userID).
+		Count(&count)
+	if count > 0 {
+		return true, nil
+	}
+
+	s.db.Model(&model.Participant{}).
+		Where("conversation_id = ? AND user_id = ?", conversationID, userID).
+		Count(&count)
+	if count > 0 {
+		return false, nil
+	}
+
+	return false, errors.New("您不是该会话的参与者")
+}
+
+// markRangeRead 更新已读位置越过的消息状态
+// 单聊消息被对方读过即为已读；群消息在除发送者外的所有成员都读过后才标记为已读
+func (s *MessageService) markRangeRead(conversationID, userID string, isGroup bool, fromSeq, toSeq int64) error {
+	if !isGroup {
+		err := s.db.Model(&model.Message{}).
+			Where("conversation_id = ? AND sender_id <> ? AND seq > ? AND seq <= ? AND status IN ?",
+				conversationID, userID, fromSeq, toSeq, readableStatuses).
+			Updates(map[string]interface{}{
+				"status":     constants.MessageStatusRead,
+				"updated_at": time.Now(),
+			}).Error
+		if err != nil {
+			return fmt.Errorf("更新消息已读状态失败: %w", err)
+		}
+
+		return s.db.Model(&model.PrivateMessage{}).
+			Where("receiver_id = ? AND `read` = ? AND id IN (?)", userID, false,
+				s.db.Model(&model.Message{}).Select("id").
+					Where("conversation_id = ? AND seq > ? AND seq <= ?", conversationID, fromSeq, toSeq)).
+			Update("read", true).Error
+	}
+
+	err := s.db.Exec(`
+		UPDATE messages m SET m.status = ?, m.updated_at = ?
+		WHERE m.conversation_id = ? AND m.seq > ? AND m.seq <= ? AND m.status IN ?
+		  AND NOT EXISTS (
+			SELECT 1 FROM group_members gm
+			LEFT JOIN conversation_read_states rs ON rs.conversation_id = gm.group_id AND rs.user_id = gm.user_id
+			WHERE gm.group_id = ? AND gm.user_id <> m.sender_id AND COALESCE(rs.last_read_seq, 0) < m.seq
+		  )
+	`, constants.MessageStatusRead, time.Now(), conversationID, fromSeq, toSeq, readableStatuses, conversationID).Error
+	if err != nil {
+		return fmt.Errorf("更新群消息已读状态失败: %w", err)
+	}
+	return nil
+}
+
+// sendReadReceipts 向已读位置越过的消息的发送者推送已读回执
+// 每个发送者一条回执：target_id 为其被读到的最新消息，seq 为读者的已读位置，
+// 群聊回执同时带上该消息的已读人数
+func (s *MessageService) sendReadReceipts(ctx context.Context, conversationID, userID string, isGroup bool, fromSeq, toSeq int64) error {
+	var latest []struct {
+		SenderID string
+		Seq      int64
+	}
+	err := s.db.Model(&model.Message{}).
+		Select("sender_id, MAX(seq) AS seq").
+		Where("conversation_id = ? AND sender_id NOT IN ? AND seq > ? AND seq <= ?",
+			conversationID, []string{userID, systemSenderID}, fromSeq, toSeq).
+		Group("sender_id").
+		Scan(&latest).Error
+	if err != nil {
+		return fmt.Errorf("查询已读消息的发送者失败: %w", err)
+	}
+
+	for _, row := range latest {
+		var target model.Message
+		if err := s.db.Where("conversation_id = ? AND seq = ?", conversationID, row.Seq).
+			Take(&target).Error; err != nil {
+			log.Printf("查询会话 %s 序列号 %d 的消息失败: %v", conversationID, row.Seq, err)
+			continue
+		}
+
+		receipt := &protocol.Message{
+			Type:           constants.MessageTypeRead,
+			SenderID:       userID,
+			TargetID:       target.ID,
+			ConversationID: conversationID,
+			Seq:            toSeq,
+			Status:         constants.MessageStatusRead,
+			IsGroup:        isGroup,
+			Timestamp:      time.Now().Unix(),
+		}
+		if isGroup {
+			receipt.GroupID = conversationID
+			if info, err := s.readInfo(&target); err == nil {
+				receipt.ReadCount = info.ReadCount
+				receipt.MemberCount = info.MemberCount
+			}
+		}
+
+		if err := s.notifyUsers(ctx, receipt, []string{row.SenderID}); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("用户 %s 在会话 %s 的已读位置推进到 %d，通知了 %d 个发送者", userID, conversationID, toSeq, len(latest))
+	return nil
+}
+
+// GetMessageReadInfo 获取消息的已读成员列表
+// 只有会话参与者可以查看
+func (s *MessageService) GetMessageReadInfo(ctx context.Context, messageID string, userID string) (*MessageReadInfo, error) {
+	var dbMessage model.Message
+	if err := s.db.Where("id = ?", messageID).Take(&dbMessage).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("消息不存在")
+		}
+		return nil, fmt.Errorf("查询消息失败: %w", err)
+	}
+
+	participants, err := s.messageParticipants(&dbMessage)
+	if err != nil {
+		return nil, err
+	}
+	if !containsString(participants, userID) {
+		return nil, errors.New("权限不足")
+	}
+
+	return s.readInfo(&dbMessage)
+}
+
+// readInfo 统计除发送者外的会话成员中哪些人的已读位置越过了该消息
+func (s *MessageService) readInfo(dbMessage *model.Message) (*MessageReadInfo, error) {
+	participants, err := s.messageParticipants(dbMessage)
+	if err != nil {
+		return nil, err
+	}
+
+	var readers []string
+	if err := s.db.Model(&model.ConversationReadState{}).
+		Where("conversation_id = ? AND last_read_seq >= ?", dbMessage.ConversationID, dbMessage.Seq).
+		Pluck("user_id", &readers).Error; err != nil {
+		return nil, fmt.Errorf("查询已读成员失败: %w", err)
+	}
+
+	hasRead := make(map[string]bool, len(readers))
+	for _, reader := range readers {
+		hasRead[reader] = true
+	}
+
+	info := &MessageReadInfo{
+		MessageID: dbMessage.ID,
+		ReadBy:    make([]string, 0),
+		UnreadBy:  make([]string, 0),
+	}
+	for _, member := range participants {
+		if member == dbMessage.SenderID {
+			continue
+		}
+		if hasRead[member] {
+			info.ReadBy = append(info.ReadBy, member)
+		} else {
+			info.UnreadBy = append(info.UnreadBy, member)
+		}
+	}
+	info.ReadCount = len(info.ReadBy)
+	info.MemberCount = info.ReadCount + len(info.UnreadBy)
+	return info, nil
+}
+
+// attachReadCounts 为一批群消息填充“N/M 人已读”
+// 每个会话只查询一次成员和已读位置；查询失败只记录日志
+func (s *MessageService) attachReadCounts(messages []*protocol.Message) {
+	type groupReadState struct {
+		members []string
+		seqs    map[string]int64
+	}
+	groups := make(map[string]*groupReadState)
+
+	for _, message := range messages {
+		if !message.IsGroup || message.Seq == 0 || message.Recalled {
+			continue
+		}
+
+		state, ok := groups[message.ConversationID]
+		if !ok {
+			state = &groupReadState{seqs: make(map[string]int64)}
+			if err := s.db.Model(&model.GroupMember{}).
+				Where("group_id = ?", message.RecipientID).
+				Pluck("user_id", &state.members).Error; err != nil {
+				log.Printf("查询群组 %s 成员失败: %v", message.RecipientID, err)
+				continue
+			}
+
+			var readStates []model.ConversationReadState
+			if err := s.db.Where("conversation_id = ?", message.ConversationID).Find(&readStates).Error; err != nil {
+				log.Printf("查询会话 %s 的已读位置失败: %v", message.ConversationID, err)
+				continue
+			}
+			for _, readState := range readStates {
+				state.seqs[readState.UserID] = readState.LastReadSeq
+			}
+			groups[message.ConversationID] = state
+		}
+
+		message.ReadCount, message.MemberCount = 0, 0
+		for _, member := range state.members {
+			if member == message.SenderID {
+				continue
+			}
+			message.MemberCount++
+			if state.seqs[member] >= message.Seq {
+				message.ReadCount++
+			}
+		}
+	}
+}
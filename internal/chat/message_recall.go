@@ -0,0 +1,175 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"cursorIM/internal/config"
+	"cursorIM/internal/constants"
+	"cursorIM/internal/model"
+	"cursorIM/internal/protocol"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RecallMessage 撤回消息
+// 发送者只能在配置的时限内撤回自己的消息，群管理员可以随时撤回群内任意消息。
+// 撤回后消息内容被清空，并向所有参与者推送撤回通知（离线用户进入离线队列）
+func (s *MessageService) RecallMessage(ctx context.Context, messageID string, userID string) (*protocol.Message, error) {
+	if messageID == "" {
+		return nil, errors.New("消息ID不能为空")
+	}
+
+	var dbMessage model.Message
+	if err := s.db.Where("id = ?", messageID).Take(&dbMessage).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("消息不存在")
+		}
+		return nil, fmt.Errorf("查询消息失败: %w", err)
+	}
+
+	groupID := ""
+	if dbMessage.IsGroup {
+		groupID = dbMessage.RecipientID
+	}
+
+	// 已撤回的消息重复撤回直接返回
+	if dbMessage.Recalled {
+		return recallNotice(&dbMessage, groupID, dbMessage.RecalledBy), nil
+	}
+
+	if !(dbMessage.IsGroup && s.isGroupAdmin(groupID, userID)) {
+		if dbMessage.SenderID != userID {
+			return nil, errors.New("只能撤回自己发送的消息")
+		}
+
+		window := time.Duration(config.GlobalConfig.Message.RecallWindow) * time.Second
+		if time.Since(dbMessage.CreatedAt) > window {
+			return nil, errors.New("消息已超过可撤回时间")
+		}
+	}
+
+	now := time.Now()
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Message{}).Where("id = ?", messageID).Updates(map[string]interface{}{
+			"recalled":    true,
+			"recalled_at": now,
+			"recalled_by": userID,
+			"content":     "",
+			"body":        "",
+			"updated_at":  now,
+		}).Error; err != nil {
+			return err
+		}
+
+		// 撤回的消息同时取消置顶
+		if err := tx.Where("message_id = ?", messageID).Delete(&model.PinnedMessage{}).Error; err != nil {
+			return err
+		}
+
+		if dbMessage.IsGroup {
+			return tx.Model(&model.GroupMessage{}).Where("id = ?", messageID).Updates(map[string]interface{}{
+				"recalled": true,
+				"content":  "",
+			}).Error
+		}
+		return tx.Model(&model.PrivateMessage{}).Where("id = ?", messageID).Updates(map[string]interface{}{
+			"recalled": true,
+			"content":  "",
+		}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("撤回消息失败: %w", err)
+	}
+
+	log.Printf("用户 %s 撤回了消息 %s (会话: %s)", userID, messageID, dbMessage.ConversationID)
+	s.unindexMessage(messageID)
+
+	notice := recallNotice(&dbMessage, groupID, userID)
+
+	participants, err := s.messageParticipants(&dbMessage)
+	if err != nil {
+		return notice, err
+	}
+	if err := s.notifyUsers(ctx, notice, participants); err != nil {
+		return notice, err
+	}
+
+	return notice, nil
+}
+
+// recallNotice 构造撤回通知
+func recallNotice(dbMessage *model.Message, groupID, operatorID string) *protocol.Message {
+	return &protocol.Message{
+		Type:           constants.MessageTypeRecall,
+		SenderID:       operatorID,
+		TargetID:       dbMessage.ID,
+		ConversationID: dbMessage.ConversationID,
+		IsGroup:        dbMessage.IsGroup,
+		GroupID:        groupID,
+		Recalled:       true,
+		Timestamp:      time.Now().Unix(),
+	}
+}
+
+// messageParticipants 获取消息所在会话的所有参与者
+func (s *MessageService) messageParticipants(dbMessage *model.Message) ([]string, error) {
+	if !dbMessage.IsGroup {
+		if dbMessage.SenderID == dbMessage.RecipientID {
+			return []string{dbMessage.SenderID}, nil
+		}
+		return []string{dbMessage.SenderID, dbMessage.RecipientID}, nil
+	}
+
+	var members []string
+	if err := s.db.Model(&model.GroupMember{}).
+		Where("group_id = ?", dbMessage.RecipientID).
+		Pluck("user_id", &members).Error; err != nil {
+		return nil, fmt.Errorf("获取群组成员失败: %w", err)
+	}
+	return members, nil
+}
+
+// isGroupAdmin 判断用户是否为群主或群管理员
+func (s *MessageService) isGroupAdmin(groupID, userID string) bool {
+	var count int64
+	s.db.Model(&model.Group{}).Where("id = ? AND owner_id = ?", groupID, userID).Count(&count)
+	if count > 0 {
+		return true
+	}
+
+	s.db.Model(&model.GroupMember{}).
+		Where("group_id = ? AND user_id = ? AND role IN ?", groupID, userID,
+			[]int{constants.GroupRoleAdmin, constants.GroupRoleOwner}).
+		Count(&count)
+	return count > 0
+}
+
+// IsGroupMember 判断用户是否为群成员
+func (s *MessageService) IsGroupMember(groupID, userID string) bool {
+	var count int64
+	s.db.Model(&model.GroupMember{}).Where("group_id = ? AND user_id = ?", groupID, userID).Count(&count)
+	return count > 0
+}
+
+// notifyUsers 向一组用户推送通知，每个用户一份独立的副本
+// 通知走连接管理器投递，不在线的用户由连接管理器存入离线队列
+func (s *MessageService) notifyUsers(ctx context.Context, notice *protocol.Message, userIDs []string) error {
+	for _, userID := range userIDs {
+		userNotice := *notice
+		userNotice.ID = uuid.New().String()
+		userNotice.RecipientID = userID
+
+		// 通知频道已满时等待，而不是丢弃通知
+		select {
+		case s.notifyChannel <- &userNotice:
+		case <-ctx.Done():
+			return fmt.Errorf("推送 %s 通知中断: %w", notice.Type, ctx.Err())
+		}
+	}
+	return nil
+}
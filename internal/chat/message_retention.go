@@ -0,0 +1,334 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"cursorIM/internal/constants"
+	"cursorIM/internal/model"
+	"cursorIM/internal/protocol"
+	"cursorIM/internal/redisclient"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 自毁消息存活时间的允许范围（秒）
+const (
+	minRetentionTTL = 5
+	maxRetentionTTL = 365 * 24 * 3600
+)
+
+// 到期消息清理参数
+const (
+	expiryReapInterval = 5 * time.Second
+	expiryReapBatch    = 500
+	expiryReapLockTTL  = 30 * time.Second // 清理锁的有效期，清理结束后主动释放，节点崩溃时到期自动释放
+)
+
+// releaseReaperLockScript 只释放本节点持有的清理锁
+var releaseReaperLockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// RetentionUpdate 自毁消息设置的修改，nil 表示不修改
+type RetentionUpdate struct {
+	TTL    *int    `json:"ttl"`     // 消息存活时间（秒），0 表示关闭
+	Mode   *string `json:"mode"`    // send 或 read
+	MinTTL *int    `json:"min_ttl"` // 最短保留时间（秒），只有群主/群管理员可以修改
+}
+
+// GetConversationRetention 获取会话的自毁消息设置，未设置时返回关闭状态
+func (s *MessageService) GetConversationRetention(ctx context.Context, conversationID string, userID string) (*model.ConversationRetention, error) {
+	if _, err := s.conversationMembership(conversationID, userID); err != nil {
+		return nil, err
+	}
+	return s.loadRetention(conversationID)
+}
+
+// SetConversationRetention 修改会话的自毁消息设置
+// 单聊双方都可以开启、关闭或调整存活时间；群聊只有群主/群管理员可以修改，
+// 并可以设置最短保留时间，设置后开启自毁时存活时间不能低于该值
+func (s *MessageService) SetConversationRetention(ctx context.Context, conversationID string, userID string, update RetentionUpdate) (*model.ConversationRetention, error) {
+	isGroup, err := s.conversationMembership(conversationID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if isGroup && (update.TTL != nil || update.Mode != nil) && !s.isGroupAdmin(conversationID, userID) {
+		return nil, errors.New("只有群主和群管理员可以修改群聊的自毁消息设置")
+	}
+
+	retention, err := s.loadRetention(conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.MinTTL != nil {
+		if !isGroup {
+			return nil, errors.New("只有群聊可以设置最短保留时间")
+		}
+		if !s.isGroupAdmin(conversationID, userID) {
+			return nil, errors.New("只有群主和群管理员可以设置最短保留时间")
+		}
+		if *update.MinTTL < 0 || *update.MinTTL > maxRetentionTTL {
+			return nil, errors.New("最短保留时间超出范围")
+		}
+		retention.MinTTL = *update.MinTTL
+		// 已开启的自毁时间低于新的下限时自动提高到下限
+		if retention.TTL > 0 && retention.TTL < retention.MinTTL {
+			retention.TTL = retention.MinTTL
+		}
+	}
+
+	if update.Mode != nil {
+		if *update.Mode != constants.RetentionModeSend && *update.Mode != constants.RetentionModeRead {
+			return nil, errors.New("无效的计时方式")
+		}
+		retention.Mode = *update.Mode
+	}
+
+	if update.TTL != nil {
+		ttl := *update.TTL
+		if ttl != 0 && (ttl < minRetentionTTL || ttl > maxRetentionTTL) {
+			return nil, fmt.Errorf("存活时间必须在 %d 秒到 %d 秒之间", minRetentionTTL, maxRetentionTTL)
+		}
+		if ttl != 0 && ttl < retention.MinTTL {
+			return nil, fmt.Errorf("群管理员要求消息至少保留 %d 秒", retention.MinTTL)
+		}
+		retention.TTL = ttl
+	}
+
+	retention.UpdatedBy = userID
+	retention.UpdatedAt = time.Now()
+	if err := s.db.Save(retention).Error; err != nil {
+		return nil, fmt.Errorf("保存自毁消息设置失败: %w", err)
+	}
+
+	log.Printf("用户 %s 修改了会话 %s 的自毁消息设置: ttl=%d, mode=%s, min_ttl=%d",
+		userID, conversationID, retention.TTL, retention.Mode, retention.MinTTL)
+	return retention, nil
+}
+
+// loadRetention 读取会话的自毁消息设置，不存在时返回默认值
+func (s *MessageService) loadRetention(conversationID string) (*model.ConversationRetention, error) {
+	retention := &model.ConversationRetention{ConversationID: conversationID}
+	err := s.db.Where("conversation_id = ?", conversationID).Take(retention).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("查询自毁消息设置失败: %w", err)
+	}
+	if retention.Mode == "" {
+		retention.Mode = constants.RetentionModeSend
+	}
+	return retention, nil
+}
+
+// applyRetention 按会话设置为新消息填充存活时间
+// 从发送时计时的消息直接确定到期时间，阅后计时的消息在首次被阅读时确定
+func (s *MessageService) applyRetention(message *protocol.Message) error {
+	message.TTL, message.ExpiresAt = 0, 0
+	if message.ConversationID == "" {
+		return nil
+	}
+
+	retention, err := s.loadRetention(message.ConversationID)
+	if err != nil {
+		return err
+	}
+	if retention.TTL <= 0 {
+		return nil
+	}
+
+	message.TTL = retention.TTL
+	if retention.Mode == constants.RetentionModeSend {
+		message.ExpiresAt = time.Now().Add(time.Duration(retention.TTL) * time.Second).Unix()
+	}
+	return nil
+}
+
+// startReadTimers 读者的已读位置越过阅后计时的消息时开始计时
+// 单聊由接收者阅读、群聊由除发送者外的第一个成员阅读时开始
+func (s *MessageService) startReadTimers(conversationID, userID string, fromSeq, toSeq int64) error {
+	err := s.db.Model(&model.Message{}).
+		Where("conversation_id = ? AND sender_id <> ? AND seq > ? AND seq <= ? AND ttl > 0 AND expires_at IS NULL",
+			conversationID, userID, fromSeq, toSeq).
+		Update("expires_at", gorm.Expr("DATE_ADD(?, INTERVAL ttl SECOND)", time.Now())).Error
+	if err != nil {
+		return fmt.Errorf("开始阅后计时失败: %w", err)
+	}
+	return nil
+}
+
+// RunExpiryReaper 定期删除到期的自毁消息，直到 ctx 取消
+// 多节点部署时通过 Redis 锁保证同一时刻只有一个节点在清理
+func (s *MessageService) RunExpiryReaper(ctx context.Context) {
+	ticker := time.NewTicker(expiryReapInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			release, ok := s.acquireReaperLock(ctx)
+			if !ok {
+				continue
+			}
+			for {
+				purged, err := s.PurgeExpiredMessages(ctx, time.Now())
+				if err != nil {
+					log.Printf("清理到期消息失败: %v", err)
+					break
+				}
+				if purged < expiryReapBatch || ctx.Err() != nil {
+					break
+				}
+			}
+			release()
+		}
+	}
+}
+
+// acquireReaperLock 获取本轮清理的锁，返回释放锁的函数
+func (s *MessageService) acquireReaperLock(ctx context.Context) (func(), bool) {
+	if !redisclient.IsRedisEnabled() {
+		return func() {}, true
+	}
+
+	client := redisclient.GetRedisClient()
+	token := uuid.New().String()
+	locked, err := client.SetNX(ctx, constants.RedisKeyMessageReaperLock, token, expiryReapLockTTL).Result()
+	if err != nil {
+		log.Printf("获取到期消息清理锁失败: %v", err)
+		return nil, false
+	}
+	if !locked {
+		return nil, false
+	}
+
+	return func() {
+		if err := releaseReaperLockScript.Run(context.Background(), client, []string{constants.RedisKeyMessageReaperLock}, token).Err(); err != nil {
+			log.Printf("释放到期消息清理锁失败: %v", err)
+		}
+	}, true
+}
+
+// PurgeExpiredMessages 硬删除一批到期的消息，并通知会话参与者清除本地副本
+// 返回删除的消息数
+func (s *MessageService) PurgeExpiredMessages(ctx context.Context, now time.Time) (int, error) {
+	var expired []model.Message
+	if err := s.db.Select("id", "conversation_id", "sender_id", "recipient_id", "is_group").
+		Where("expires_at IS NOT NULL AND expires_at <= ?", now).
+		Order("expires_at asc").
+		Limit(expiryReapBatch).
+		Find(&expired).Error; err != nil {
+		return 0, fmt.Errorf("查询到期消息失败: %w", err)
+	}
+	if len(expired) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]string, 0, len(expired))
+	for _, message := range expired {
+		ids = append(ids, message.ID)
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id IN ?", ids).Delete(&model.Message{}).Error; err != nil {
+			return err
+		}
+		// 尚未补发的离线通知（编辑、提及等）可能带有原文，一并删除
+		if err := tx.Where("target_id IN ?", ids).Delete(&model.OfflineNotice{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("id IN ?", ids).Delete(&model.PrivateMessage{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("id IN ?", ids).Delete(&model.GroupMessage{}).Error; err != nil {
+			return err
+		}
+		for _, related := range []interface{}{&model.MessageRevision{}, &model.MessageReaction{}, &model.MessageMention{}, &model.PinnedMessage{}} {
+			if err := tx.Where("message_id IN ?", ids).Delete(related).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("poll_id IN ?", ids).Delete(&model.PollVote{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("id IN ?", ids).Delete(&model.Poll{}).Error; err != nil {
+			return err
+		}
+		// 引用了到期消息的回复不再保留原文快照
+		return tx.Model(&model.Message{}).Where("reply_to_id IN ?", ids).
+			Update("quote_content", "").Error
+	})
+	if err != nil {
+		return 0, fmt.Errorf("删除到期消息失败: %w", err)
+	}
+
+	for _, id := range ids {
+		s.unindexMessage(id)
+	}
+
+	// 按会话通知参与者清除本地副本
+	byConversation := make(map[string][]*model.Message)
+	var order []string
+	for i := range expired {
+		conversationID := expired[i].ConversationID
+		if _, ok := byConversation[conversationID]; !ok {
+			order = append(order, conversationID)
+		}
+		byConversation[conversationID] = append(byConversation[conversationID], &expired[i])
+	}
+	for _, conversationID := range order {
+		messages := byConversation[conversationID]
+		if err := s.notifyPurge(ctx, messages); err != nil {
+			log.Printf("通知会话 %s 清除到期消息失败: %v", conversationID, err)
+		}
+	}
+
+	log.Printf("已删除 %d 条到期消息，涉及 %d 个会话", len(ids), len(order))
+	return len(ids), nil
+}
+
+// notifyPurge 通知会话参与者删除本地的到期消息
+func (s *MessageService) notifyPurge(ctx context.Context, messages []*model.Message) error {
+	first := messages[0]
+	ids := make([]string, 0, len(messages))
+	for _, message := range messages {
+		ids = append(ids, message.ID)
+	}
+
+	notice := &protocol.Message{
+		Type:           constants.MessageTypePurge,
+		SenderID:       "server",
+		ConversationID: first.ConversationID,
+		IsGroup:        first.IsGroup,
+		TargetIDs:      ids,
+		Timestamp:      time.Now().Unix(),
+	}
+	if first.IsGroup {
+		notice.GroupID = first.RecipientID
+	}
+
+	participants, err := s.messageParticipants(first)
+	if err != nil {
+		return err
+	}
+	return s.notifyUsers(ctx, notice, participants)
+}
+
+// expiresAtOrNil 将 Unix 秒转换为可空的到期时间
+func expiresAtOrNil(expiresAt int64) *time.Time {
+	if expiresAt == 0 {
+		return nil
+	}
+	t := time.Unix(expiresAt, 0)
+	return &t
+}
@@ -0,0 +1,358 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"cursorIM/internal/constants"
+	"cursorIM/internal/model"
+	"cursorIM/internal/protocol"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 定时消息的限制
+const (
+	maxScheduleAhead     = 365 * 24 * time.Hour // 最多提前一年
+	maxPendingScheduled  = 100                  // 每个用户同时等待发送的定时消息数
+	scheduledClaimExpiry = 5 * time.Minute      // 认领后超过该时间仍未完成视为节点宕机，重新放回队列
+)
+
+// scheduledClientMsgPrefix 定时消息未携带客户端消息ID时自动生成的前缀
+// 节点宕机后重新发送时依靠 client_msg_id 去重，保证只生成一条消息
+const scheduledClientMsgPrefix = "scheduled:"
+
+// ScheduleMessage 创建定时消息
+// 消息内容在创建时校验，到达 sendAt 后由调度器按普通消息发送
+func (s *MessageService) ScheduleMessage(ctx context.Context, userID string, message *protocol.Message, sendAt time.Time) (*model.ScheduledMessage, error) {
+	if message.Type == "" {
+		message.Type = constants.MessageTypeText
+	}
+	if isControlMessage(message.Type) || protocol.IsEphemeral(message.Type) ||
+		message.Type == constants.MessageTypeSystem {
+		return nil, errors.New("该类型的消息不支持定时发送")
+	}
+	if err := s.prepareBody(message); err != nil {
+		return nil, err
+	}
+	if message.Content == "" {
+		return nil, errors.New("消息内容不能为空")
+	}
+	if message.RecipientID == "" {
+		return nil, errors.New("消息缺少接收者ID")
+	}
+	if err := validateSendAt(sendAt); err != nil {
+		return nil, err
+	}
+	if message.IsGroup && !s.IsGroupMember(message.RecipientID, userID) {
+		return nil, errors.New("您不是该群组的成员")
+	}
+
+	var pending int64
+	if err := s.db.Model(&model.ScheduledMessage{}).
+		Where("sender_id = ? AND status IN ?", userID,
+			[]string{constants.ScheduledStatusPending, constants.ScheduledStatusSending}).
+		Count(&pending).Error; err != nil {
+		return nil, fmt.Errorf("查询定时消息失败: %w", err)
+	}
+	if pending >= maxPendingScheduled {
+		return nil, fmt.Errorf("等待发送的定时消息不能超过 %d 条", maxPendingScheduled)
+	}
+
+	scheduled := &model.ScheduledMessage{
+		ID:          uuid.New().String(),
+		SenderID:    userID,
+		RecipientID: message.RecipientID,
+		IsGroup:     message.IsGroup,
+		ContentType: message.Type,
+		Content:     message.Content,
+		SendAt:      sendAt,
+		Status:      constants.ScheduledStatusPending,
+	}
+
+	// 服务端字段在发送时重新生成
+	message.ID = ""
+	message.SenderID = userID
+	message.ConversationID = ""
+	message.Seq = 0
+	message.Timestamp = 0
+	message.Status = ""
+	if message.ClientMsgID == "" {
+		message.ClientMsgID = scheduledClientMsgPrefix + scheduled.ID
+	}
+
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return nil, fmt.Errorf("序列化定时消息失败: %w", err)
+	}
+	scheduled.Payload = string(payload)
+
+	if err := s.db.Create(scheduled).Error; err != nil {
+		return nil, fmt.Errorf("保存定时消息失败: %w", err)
+	}
+
+	log.Printf("用户 %s 创建了定时消息 %s，计划于 %s 发送", userID, scheduled.ID, sendAt.Format(time.RFC3339))
+	return scheduled, nil
+}
+
+// ListScheduledMessages 列出用户的定时消息，按计划发送时间排序
+// status 为空时返回等待发送的消息
+func (s *MessageService) ListScheduledMessages(ctx context.Context, userID string, status string) ([]model.ScheduledMessage, error) {
+	db := s.db.Where("sender_id = ?", userID)
+	if status == "" {
+		db = db.Where("status IN ?", []string{constants.ScheduledStatusPending, constants.ScheduledStatusSending})
+	} else {
+		db = db.Where("status = ?", status)
+	}
+
+	scheduled := make([]model.ScheduledMessage, 0)
+	if err := db.Order("send_at asc").Find(&scheduled).Error; err != nil {
+		return nil, fmt.Errorf("查询定时消息失败: %w", err)
+	}
+	return scheduled, nil
+}
+
+// UpdateScheduledMessage 修改等待发送的定时消息的内容或发送时间，nil 表示不修改
+func (s *MessageService) UpdateScheduledMessage(ctx context.Context, scheduledID string, userID string, content *string, sendAt *time.Time) (*model.ScheduledMessage, error) {
+	if content == nil && sendAt == nil {
+		return nil, errors.New("没有需要修改的内容")
+	}
+	if content != nil && *content == "" {
+		return nil, errors.New("消息内容不能为空")
+	}
+	if sendAt != nil {
+		if err := validateSendAt(*sendAt); err != nil {
+			return nil, err
+		}
+	}
+
+	scheduled, err := s.findScheduledMessage(scheduledID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if scheduled.Status != constants.ScheduledStatusPending {
+		return nil, errors.New("定时消息已发送或已取消，无法修改")
+	}
+
+	updates := map[string]interface{}{"updated_at": time.Now()}
+	if content != nil {
+		var message protocol.Message
+		if err := json.Unmarshal([]byte(scheduled.Payload), &message); err != nil {
+			return nil, fmt.Errorf("解析定时消息失败: %w", err)
+		}
+		message.Content = *content
+		payload, err := json.Marshal(&message)
+		if err != nil {
+			return nil, fmt.Errorf("序列化定时消息失败: %w", err)
+		}
+		updates["content"] = *content
+		updates["payload"] = string(payload)
+		scheduled.Content = *content
+		scheduled.Payload = string(payload)
+	}
+	if sendAt != nil {
+		updates["send_at"] = *sendAt
+		scheduled.SendAt = *sendAt
+	}
+
+	// 只修改仍在等待的消息，避免与调度器的认领竞争
+	result := s.db.Model(&model.ScheduledMessage{}).
+		Where("id = ? AND status = ?", scheduledID, constants.ScheduledStatusPending).
+		Updates(updates)
+	if result.Error != nil {
+		return nil, fmt.Errorf("修改定时消息失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("定时消息已发送或已取消，无法修改")
+	}
+
+	log.Printf("用户 %s 修改了定时消息 %s", userID, scheduledID)
+	return scheduled, nil
+}
+
+// CancelScheduledMessage 取消等待发送的定时消息
+func (s *MessageService) CancelScheduledMessage(ctx context.Context, scheduledID string, userID string) error {
+	if _, err := s.findScheduledMessage(scheduledID, userID); err != nil {
+		return err
+	}
+
+	result := s.db.Model(&model.ScheduledMessage{}).
+		Where("id = ? AND status = ?", scheduledID, constants.ScheduledStatusPending).
+		Updates(map[string]interface{}{
+			"status":     constants.ScheduledStatusCanceled,
+			"updated_at": time.Now(),
+		})
+	if result.Error != nil {
+		return fmt.Errorf("取消定时消息失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("定时消息已发送或已取消")
+	}
+
+	log.Printf("用户 %s 取消了定时消息 %s", userID, scheduledID)
+	return nil
+}
+
+// DueScheduledMessages 查询已到发送时间的定时消息
+func (s *MessageService) DueScheduledMessages(ctx context.Context, now time.Time, limit int) ([]model.ScheduledMessage, error) {
+	var due []model.ScheduledMessage
+	err := s.db.Where("status = ? AND send_at <= ?", constants.ScheduledStatusPending, now).
+		Order("send_at asc").
+		Limit(limit).
+		Find(&due).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询到期的定时消息失败: %w", err)
+	}
+	return due, nil
+}
+
+// ClaimScheduledMessage 认领一条到期的定时消息（pending -> sending）
+// 多个节点同时认领时只有一个会成功
+func (s *MessageService) ClaimScheduledMessage(ctx context.Context, scheduledID string) (bool, error) {
+	result := s.db.Model(&model.ScheduledMessage{}).
+		Where("id = ? AND status = ?", scheduledID, constants.ScheduledStatusPending).
+		Updates(map[string]interface{}{
+			"status":     constants.ScheduledStatusSending,
+			"updated_at": time.Now(),
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("认领定时消息失败: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// CompleteScheduledMessage 记录定时消息的发送结果
+func (s *MessageService) CompleteScheduledMessage(ctx context.Context, scheduledID string, messageID string, sendErr error) error {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":     constants.ScheduledStatusSent,
+		"message_id": messageID,
+		"sent_at":    now,
+		"updated_at": now,
+	}
+	if sendErr != nil {
+		reason := sendErr.Error()
+		if runes := []rune(reason); len(runes) > 255 {
+			reason = string(runes[:255])
+		}
+		updates = map[string]interface{}{
+			"status":     constants.ScheduledStatusFailed,
+			"error":      reason,
+			"updated_at": now,
+		}
+	}
+
+	return s.db.Model(&model.ScheduledMessage{}).
+		Where("id = ? AND status = ?", scheduledID, constants.ScheduledStatusSending).
+		Updates(updates).Error
+}
+
+// RequeueStaleScheduledMessages 将认领后长时间未完成的定时消息放回队列
+// 重新发送时由 client_msg_id 去重，已经保存过的消息不会重复生成，只补发给尚未收到的接收者（见 ScheduledResend）
+func (s *MessageService) RequeueStaleScheduledMessages(ctx context.Context) error {
+	result := s.db.Model(&model.ScheduledMessage{}).
+		Where("status = ? AND updated_at < ?", constants.ScheduledStatusSending, time.Now().Add(-scheduledClaimExpiry)).
+		Updates(map[string]interface{}{
+			"status":     constants.ScheduledStatusPending,
+			"updated_at": time.Now(),
+		})
+	if result.Error != nil {
+		return fmt.Errorf("恢复超时的定时消息失败: %w", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("%d 条定时消息认领超时，已重新放回队列", result.RowsAffected)
+	}
+	return nil
+}
+
+// ScheduledResend 查询定时消息是否已经保存过（节点在保存后、投递完成前宕机）
+// 已保存时返回首次保存的消息和尚未收到它的接收者，未保存时返回 nil。
+// 单聊以接收者是否已确认送达为准；群消息不跟踪逐个成员的送达，以成员的已读位置为准
+func (s *MessageService) ScheduledResend(ctx context.Context, scheduled *model.ScheduledMessage) (*protocol.Message, []string, error) {
+	payload, err := ScheduledPayload(scheduled)
+	if err != nil {
+		return nil, nil, err
+	}
+	if payload.ClientMsgID == "" {
+		return nil, nil, nil
+	}
+
+	original, err := s.findClientMessage(ctx, scheduled.SenderID, payload.ClientMsgID)
+	if err != nil || original == nil {
+		return nil, nil, err
+	}
+	message := messageFromModel(original)
+	if original.Recalled {
+		return message, nil, nil
+	}
+
+	if !original.IsGroup {
+		if original.Status == constants.MessageStatusSent || original.Status == constants.MessageStatusUnsent {
+			return message, []string{original.RecipientID}, nil
+		}
+		return message, nil, nil
+	}
+
+	var members []string
+	if err := s.db.Model(&model.GroupMember{}).
+		Where("group_id = ? AND user_id <> ?", original.RecipientID, original.SenderID).
+		Pluck("user_id", &members).Error; err != nil {
+		return nil, nil, fmt.Errorf("查询群组成员失败: %w", err)
+	}
+	var readers []string
+	if err := s.db.Model(&model.ConversationReadState{}).
+		Where("conversation_id = ? AND last_read_seq >= ?", original.ConversationID, original.Seq).
+		Pluck("user_id", &readers).Error; err != nil {
+		return nil, nil, fmt.Errorf("查询群成员已读位置失败: %w", err)
+	}
+
+	recipients := make([]string, 0, len(members))
+	for _, member := range members {
+		if !containsString(readers, member) {
+			recipients = append(recipients, member)
+		}
+	}
+	return message, recipients, nil
+}
+
+// ScheduledPayload 还原定时消息对应的协议消息
+func ScheduledPayload(scheduled *model.ScheduledMessage) (*protocol.Message, error) {
+	var message protocol.Message
+	if err := json.Unmarshal([]byte(scheduled.Payload), &message); err != nil {
+		return nil, fmt.Errorf("解析定时消息失败: %w", err)
+	}
+	message.SenderID = scheduled.SenderID
+	return &message, nil
+}
+
+// findScheduledMessage 查询用户自己的定时消息
+func (s *MessageService) findScheduledMessage(scheduledID, userID string) (*model.ScheduledMessage, error) {
+	var scheduled model.ScheduledMessage
+	if err := s.db.Where("id = ?", scheduledID).Take(&scheduled).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("定时消息不存在")
+		}
+		return nil, fmt.Errorf("查询定时消息失败: %w", err)
+	}
+	if scheduled.SenderID != userID {
+		return nil, errors.New("无权操作该定时消息")
+	}
+	return &scheduled, nil
+}
+
+// validateSendAt 校验计划发送时间
+func validateSendAt(sendAt time.Time) error {
+	now := time.Now()
+	if !sendAt.After(now) {
+		return errors.New("发送时间必须晚于当前时间")
+	}
+	if sendAt.After(now.Add(maxScheduleAhead)) {
+		return errors.New("发送时间不能超过一年")
+	}
+	return nil
+}
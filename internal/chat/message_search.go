@@ -0,0 +1,154 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"unicode/utf8"
+
+	"cursorIM/internal/model"
+	"cursorIM/internal/protocol"
+	"cursorIM/internal/search"
+
+	"gorm.io/gorm"
+)
+
+// 搜索结果分页的默认/最大条数，以及关键词的最大长度
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+	maxSearchTextLen   = 100
+)
+
+// searchRebuildBatch 重建进程内索引时每批读取的消息数
+const searchRebuildBatch = 1000
+
+// MessageSearchQuery 消息搜索条件
+// ConversationID 为空时在用户所在的全部会话和群组中搜索
+type MessageSearchQuery struct {
+	Text           string
+	ConversationID string
+	SenderID       string
+	ContentType    string
+	From           int64 // 起始时间（Unix 秒）
+	To             int64 // 结束时间（Unix 秒）
+	Limit          int
+	Offset         int
+}
+
+// SearchMessages 在用户可见的会话中全文搜索消息
+func (s *MessageService) SearchMessages(ctx context.Context, userID string, query MessageSearchQuery) (*search.Result, error) {
+	if query.Text == "" {
+		return nil, errors.New("搜索关键词不能为空")
+	}
+	if utf8.RuneCountInString(query.Text) > maxSearchTextLen {
+		return nil, errors.New("搜索关键词过长")
+	}
+	if query.From > 0 && query.To > 0 && query.From > query.To {
+		return nil, errors.New("起始时间不能晚于结束时间")
+	}
+
+	index := search.GetIndex()
+	if index == nil {
+		return nil, errors.New("搜索服务未启用")
+	}
+
+	conversationIDs, err := s.getUserConversationIDs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if query.ConversationID != "" {
+		if !containsString(conversationIDs, query.ConversationID) {
+			return nil, errors.New("您不是该会话的参与者")
+		}
+		conversationIDs = []string{query.ConversationID}
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+	offset := query.Offset
+	if offset < 0 {
+		offset = 0
+	}
+
+	return index.Search(ctx, &search.Query{
+		Text:            query.Text,
+		ConversationIDs: conversationIDs,
+		SenderID:        query.SenderID,
+		ContentType:     query.ContentType,
+		From:            query.From,
+		To:              query.To,
+		Limit:           limit,
+		Offset:          offset,
+	})
+}
+
+// indexMessage 将新保存或编辑后的消息写入需要由应用维护的索引
+func (s *MessageService) indexMessage(doc *search.Document) {
+	index := search.GetIndex()
+	if index == nil || index.SelfMaintained() {
+		return
+	}
+	if err := index.Index(doc); err != nil {
+		log.Printf("索引消息 %s 失败: %v", doc.ID, err)
+	}
+}
+
+// unindexMessage 从需要由应用维护的索引中移除消息
+func (s *MessageService) unindexMessage(messageID string) {
+	index := search.GetIndex()
+	if index == nil || index.SelfMaintained() {
+		return
+	}
+	if err := index.Delete(messageID); err != nil {
+		log.Printf("从索引中移除消息 %s 失败: %v", messageID, err)
+	}
+}
+
+// RebuildSearchIndex 从数据库重建进程内索引，由存储维护的索引直接跳过
+func (s *MessageService) RebuildSearchIndex(ctx context.Context) error {
+	index := search.GetIndex()
+	if index == nil || index.SelfMaintained() {
+		return nil
+	}
+
+	var total int
+	var batch []model.Message
+	err := s.db.WithContext(ctx).
+		Where("recalled = ? AND content <> ''", false).
+		FindInBatches(&batch, searchRebuildBatch, func(tx *gorm.DB, _ int) error {
+			for i := range batch {
+				if err := index.Index(search.DocumentFromModel(&batch[i])); err != nil {
+					return err
+				}
+			}
+			total += len(batch)
+			return nil
+		}).Error
+	if err != nil {
+		return fmt.Errorf("重建消息索引失败: %w", err)
+	}
+
+	log.Printf("消息索引重建完成，共 %d 条消息", total)
+	return nil
+}
+
+// searchDocument 由协议消息构造索引文档
+func searchDocument(message *protocol.Message) *search.Document {
+	return &search.Document{
+		ID:             message.ID,
+		ConversationID: message.ConversationID,
+		SenderID:       message.SenderID,
+		ContentType:    message.Type,
+		Content:        message.Content,
+		Timestamp:      message.Timestamp,
+		Seq:            message.Seq,
+		IsGroup:        message.IsGroup,
+	}
+}
@@ -2,10 +2,13 @@ package chat
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
+	"cursorIM/internal/constants"
 	"cursorIM/internal/database"
 	"cursorIM/internal/model"
 	"cursorIM/internal/protocol"
@@ -18,12 +21,14 @@ type MessageService struct {
 	db            *gorm.DB
 	notifyChannel chan *protocol.Message
 	connManager   interface{} // We'll use this to access the connection manager
+	sequences     *SequenceAllocator
 }
 
 func NewMessageService() *MessageService {
 	return &MessageService{
 		db:            database.GetDB(),
 		notifyChannel: make(chan *protocol.Message, 100),
+		sequences:     NewSequenceAllocator(),
 	}
 }
 
@@ -49,26 +54,127 @@ func (s *MessageService) processNotifications() {
 	}
 }
 
-// SaveMessage 保存一条消息到数据库
+// SaveMessage 保存一条客户端发送的消息到数据库
+// 转发来源和聊天记录只能由服务器在转发时填充，客户端提供的值被忽略
 func (s *MessageService) SaveMessage(ctx context.Context, message *protocol.Message) error {
-	// 不保存心跳消息
-	if message.Type == "ping" || message.Type == "pong" {
+	if message.Type == constants.MessageTypeChatRecord {
+		return errors.New("聊天记录只能通过转发发送")
+	}
+	if message.Type == constants.MessageTypeSystem {
+		return errors.New("系统消息只能由服务器发送")
+	}
+	if isControlMessage(message.Type) {
+		return errors.New("该类型的消息不能作为聊天消息发送")
+	}
+	if message.IsGroup {
+		if err := s.checkMuted(message.RecipientID, message.SenderID); err != nil {
+			return err
+		}
+	}
+	message.Forward = nil
+	message.Record = nil
+	if err := s.prepareBody(message); err != nil {
+		return err
+	}
+	return s.saveMessage(ctx, message)
+}
+
+// isControlMessage 判断是否为通知或控制帧，这类消息不是聊天记录，不能作为消息保存、定时发送
+func isControlMessage(msgType string) bool {
+	return protocol.IsNotice(msgType) || protocol.IsControl(msgType)
+}
+
+// saveMessage 保存一条消息到数据库
+func (s *MessageService) saveMessage(ctx context.Context, message *protocol.Message) error {
+	// 不保存心跳消息和临时信号
+	if message.Type == "ping" || message.Type == "pong" || protocol.IsEphemeral(message.Type) {
 		return nil
 	}
 
+	// 客户端重发的消息直接返回首次保存的结果
+	if message.ClientMsgID != "" {
+		original, err := s.findClientMessage(ctx, message.SenderID, message.ClientMsgID)
+		if err != nil {
+			return err
+		}
+		if original != nil {
+			applyOriginal(message, original)
+			return ErrDuplicateMessage
+		}
+	}
+
 	// 确保消息有唯一ID
 	if message.ID == "" {
 		message.ID = uuid.New().String()
 	}
 
+	// 群消息总是以群组ID作为会话ID
+	if message.IsGroup {
+		if message.ConversationID != "" && message.ConversationID != message.RecipientID {
+			return errors.New("群消息的会话ID必须是群组ID")
+		}
+		message.ConversationID = message.RecipientID
+	}
+
+	// 回复和话题消息需要校验父消息并填充引用快照
+	if message.ReplyToID != "" || message.ThreadID != "" {
+		if err := s.prepareReply(message); err != nil {
+			return err
+		}
+	}
+
+	// 校验提及并展开需要提醒的用户
+	mentioned, err := s.prepareMentions(message)
+	if err != nil {
+		return err
+	}
+
+	// 按会话的自毁消息设置确定存活时间
+	if err := s.applyRetention(message); err != nil {
+		return err
+	}
+
+	// 分配会话内序列号
+	if message.ConversationID != "" && message.Seq == 0 {
+		seq, err := s.sequences.Next(ctx, message.ConversationID)
+		if err != nil {
+			return err
+		}
+		message.Seq = seq
+	}
+
 	// 判断是群聊还是单聊消息
 	if message.IsGroup {
 		// 保存为群聊消息
-		return s.saveGroupMessage(ctx, message)
+		err = s.saveGroupMessage(ctx, message)
 	} else {
 		// 保存为单聊消息
-		return s.savePrivateMessage(ctx, message)
+		err = s.savePrivateMessage(ctx, message)
 	}
+	if err != nil {
+		// 并发重发时由数据库唯一索引拦截
+		if message.ClientMsgID != "" && isDuplicateKey(err) {
+			if original, findErr := s.findClientMessage(ctx, message.SenderID, message.ClientMsgID); findErr == nil && original != nil {
+				applyOriginal(message, original)
+				return ErrDuplicateMessage
+			}
+		}
+		return err
+	}
+	s.rememberClientMessage(ctx, message)
+	s.indexMessage(searchDocument(message))
+
+	// 话题回复更新根消息的回复数
+	if message.ThreadID != "" {
+		s.onThreadReply(message)
+	}
+
+	// 提及记录保存失败不影响消息本身
+	if err := s.saveMentions(ctx, message, mentioned); err != nil {
+		log.Printf("处理消息 %s 的提及失败: %v", message.ID, err)
+	}
+
+	return nil
 }
 
 // savePrivateMessage 保存单聊消息
@@ -93,13 +199,8 @@ func (s *MessageService) savePrivateMessage(ctx context.Context, message *protoc
 		Content:    message.Content,
 		SentAt:     time.Now(),
 		Read:       false,
-	}
-
-	// 保存消息
-	err := s.db.Create(&privateMsg).Error
-	if err != nil {
-		log.Printf("保存单聊消息到数据库失败: %v", err)
-		return err
+		ReplyToID:  message.ReplyToID,
+		ThreadID:   message.ThreadID,
 	}
 
 	// 同时保存到通用消息表（兼容现有逻辑）
@@ -110,17 +211,43 @@ func (s *MessageService) savePrivateMessage(ctx context.Context, message *protoc
 		RecipientID:    message.RecipientID,
 		Content:        message.Content,
 		ContentType:    message.Type,
+		Seq:            message.Seq,
 		Status:         status,
 		Timestamp:      message.Timestamp,
 		IsGroup:        false,
 		Type:           message.Type,
+		ClientMsgID:    clientMsgIDOrNil(message.ClientMsgID),
+		ReplyToID:      message.ReplyToID,
+		ThreadID:       message.ThreadID,
+		Mentions:       strings.Join(message.Mentions, ","),
+		MentionAll:     message.MentionAll,
+		MentionAdmins:  message.MentionAdmins,
+		TTL:            message.TTL,
+		ExpiresAt:      expiresAtOrNil(message.ExpiresAt),
 		CreatedAt:      time.Now(),
 		UpdatedAt:      time.Now(),
 	}
+	applyQuote(&dbMessage, message.Quote)
+	if err := applyForward(&dbMessage, message); err != nil {
+		return err
+	}
+	if err := applyBody(&dbMessage, message); err != nil {
+		return err
+	}
 
-	err = s.db.Create(&dbMessage).Error
+	// 两张表在同一事务中写入，重复消息被唯一索引拦截时不会留下半条记录
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&privateMsg).Error; err != nil {
+			log.Printf("保存单聊消息到数据库失败: %v", err)
+			return err
+		}
+		if err := tx.Create(&dbMessage).Error; err != nil {
+			log.Printf("保存消息到通用表失败: %v", err)
+			return err
+		}
+		return nil
+	})
 	if err != nil {
-		log.Printf("保存消息到通用表失败: %v", err)
 		return err
 	}
 
@@ -134,19 +261,14 @@ func (s *MessageService) savePrivateMessage(ctx context.Context, message *protoc
 func (s *MessageService) saveGroupMessage(ctx context.Context, message *protocol.Message) error {
 	// 创建群聊消息记录
 	groupMsg := model.GroupMessage{
-		ID:       message.ID,
-		GroupID:  message.RecipientID, // 对于群聊，RecipientID是GroupID
-		SenderID: message.SenderID,
-		Type:     message.Type,
-		Content:  message.Content,
-		SentAt:   time.Now(),
-	}
-
-	// 保存消息
-	err := s.db.Create(&groupMsg).Error
-	if err != nil {
-		log.Printf("保存群聊消息到数据库失败: %v", err)
-		return err
+		ID:        message.ID,
+		GroupID:   message.RecipientID, // 对于群聊，RecipientID是GroupID
+		SenderID:  message.SenderID,
+		Type:      message.Type,
+		Content:   message.Content,
+		SentAt:    time.Now(),
+		ReplyToID: message.ReplyToID,
+		ThreadID:  message.ThreadID,
 	}
 
 	// 同时保存到通用消息表（兼容现有逻辑）
@@ -157,17 +279,46 @@ func (s *MessageService) saveGroupMessage(ctx context.Context, message *protocol
 		RecipientID:    message.RecipientID,
 		Content:        message.Content,
 		ContentType:    message.Type,
+		Seq:            message.Seq,
 		Status:         "sent",
 		Timestamp:      message.Timestamp,
 		IsGroup:        true,
 		Type:           message.Type,
+		ClientMsgID:    clientMsgIDOrNil(message.ClientMsgID),
+		ReplyToID:      message.ReplyToID,
+		ThreadID:       message.ThreadID,
+		Mentions:       strings.Join(message.Mentions, ","),
+		MentionAll:     message.MentionAll,
+		MentionAdmins:  message.MentionAdmins,
+		TTL:            message.TTL,
+		ExpiresAt:      expiresAtOrNil(message.ExpiresAt),
 		CreatedAt:      time.Now(),
 		UpdatedAt:      time.Now(),
 	}
+	applyQuote(&dbMessage, message.Quote)
+	if err := applyForward(&dbMessage, message); err != nil {
+		return err
+	}
+	if err := applyBody(&dbMessage, message); err != nil {
+		return err
+	}
 
-	err = s.db.Create(&dbMessage).Error
+	// 两张表在同一事务中写入，重复消息被唯一索引拦截时不会留下半条记录
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&groupMsg).Error; err != nil {
+			log.Printf("保存群聊消息到数据库失败: %v", err)
+			return err
+		}
+		if err := tx.Create(&dbMessage).Error; err != nil {
+			log.Printf("保存群聊消息到通用表失败: %v", err)
+			return err
+		}
+		if message.Poll != nil {
+			return tx.Create(pollRecord(message)).Error
+		}
+		return nil
+	})
 	if err != nil {
-		log.Printf("保存群聊消息到通用表失败: %v", err)
 		return err
 	}
 
@@ -177,74 +328,21 @@ func (s *MessageService) saveGroupMessage(ctx context.Context, message *protocol
 	return nil
 }
 
-// GetPrivateMessages 获取两个用户之间的单聊消息
-func (s *MessageService) GetPrivateMessages(ctx context.Context, userID, otherUserID string, limit int) ([]*protocol.Message, error) {
-	var dbMessages []model.PrivateMessage
-
-	// 查询两个用户之间的消息
-	err := s.db.Where("(sender_id = ? AND receiver_id = ?) OR (sender_id = ? AND receiver_id = ?)",
-		userID, otherUserID, otherUserID, userID).
-		Order("sent_at DESC").
-		Limit(limit).
-		Find(&dbMessages).Error
-
-	if err != nil {
-		return nil, err
-	}
-
-	// 转换为协议消息
-	var messages []*protocol.Message
-	for i := len(dbMessages) - 1; i >= 0; i-- { // 反转顺序，最早的消息在前
-		msg := dbMessages[i]
-		messages = append(messages, &protocol.Message{
-			ID:          msg.ID,
-			SenderID:    msg.SenderID,
-			RecipientID: msg.ReceiverID,
-			Content:     msg.Content,
-			Type:        msg.Type,
-			Timestamp:   msg.SentAt.Unix(),
-			IsGroup:     false,
-		})
-	}
-
-	return messages, nil
-}
-
-// GetGroupMessages 获取群组消息历史
-func (s *MessageService) GetGroupMessages(ctx context.Context, groupID string, limit int) ([]*protocol.Message, error) {
-	var dbMessages []model.GroupMessage
-
-	// 查询群组消息
-	err := s.db.Where("group_id = ?", groupID).
-		Order("sent_at DESC").
-		Limit(limit).
-		Find(&dbMessages).Error
-
-	if err != nil {
-		return nil, err
-	}
-
-	// 转换为协议消息
-	var messages []*protocol.Message
-	for i := len(dbMessages) - 1; i >= 0; i-- { // 反转顺序，最早的消息在前
-		msg := dbMessages[i]
-		messages = append(messages, &protocol.Message{
-			ID:          msg.ID,
-			SenderID:    msg.SenderID,
-			RecipientID: msg.GroupID,
-			Content:     msg.Content,
-			Type:        msg.Type,
-			Timestamp:   msg.SentAt.Unix(),
-			IsGroup:     true,
-		})
-	}
-
-	return messages, nil
-}
-
 // BroadcastToGroup 向群组广播消息
 func (s *MessageService) BroadcastToGroup(ctx context.Context, message *protocol.Message) error {
 	groupID := message.RecipientID
+	if message.GroupID != "" {
+		groupID = message.GroupID
+	}
+
+	// 连接管理器支持群消息扇出时，整条消息交给它按节点批量投递
+	if gm, ok := s.connManager.(interface{ SendGroupMessage(*protocol.Message) error }); ok {
+		groupMsg := *message
+		groupMsg.IsGroup = true
+		groupMsg.RecipientID = groupID
+		groupMsg.GroupID = groupID
+		return gm.SendGroupMessage(&groupMsg)
+	}
 
 	// 获取群组成员
 	var members []model.GroupMember
@@ -257,22 +355,23 @@ func (s *MessageService) BroadcastToGroup(ctx context.Context, message *protocol
 	for _, member := range members {
 		if member.UserID != message.SenderID {
 			groupMsg := &protocol.Message{
-				ID:          message.ID,
-				Type:        message.Type,
-				SenderID:    message.SenderID,
-				RecipientID: member.UserID,
-				Content:     message.Content,
-				Timestamp:   message.Timestamp,
-				IsGroup:     true,
-				GroupID:     groupID,
+				ID:             message.ID,
+				Type:           message.Type,
+				SenderID:       message.SenderID,
+				RecipientID:    member.UserID,
+				Content:        message.Content,
+				Timestamp:      message.Timestamp,
+				ConversationID: message.ConversationID,
+				IsGroup:        true,
+				GroupID:        groupID,
 			}
 
-			// 发送通知
+			// 通知频道已满时等待，而不是丢弃消息
 			select {
 			case s.notifyChannel <- groupMsg:
 				// 消息已发送到通知频道
-			default:
-				log.Printf("通知频道已满，丢弃给用户 %s 的群聊消息", member.UserID)
+			case <-ctx.Done():
+				return fmt.Errorf("群组 %s 消息广播中断: %w", groupID, ctx.Err())
 			}
 		}
 	}
@@ -282,67 +381,207 @@ func (s *MessageService) BroadcastToGroup(ctx context.Context, message *protocol
 
 // GetMessagesByConversation 获取特定会话的消息历史
 func (s *MessageService) GetMessagesByConversation(ctx context.Context, conversationID string, limit int64) ([]*protocol.Message, error) {
-	var dbMessages []model.Message
+	batch, err := s.GetMessageHistory(ctx, conversationID, HistoryQuery{Limit: int(limit)})
+	if err != nil {
+		return nil, err
+	}
+	return batch.Messages, nil
+}
 
-	// 查询消息
-	err := s.db.Where("conversation_id = ?", conversationID).
-		Order("timestamp desc").
-		Limit(int(limit)).
-		Find(&dbMessages).Error
+// 增量同步每个会话单次返回的默认/最大消息数
+const (
+	defaultSyncLimit = 100
+	maxSyncLimit     = 500
+)
+
+// ConversationSync 单个会话的增量同步结果
+type ConversationSync struct {
+	ConversationID string              `json:"conversation_id"`
+	Messages       []*protocol.Message `json:"messages"`
+	LastSeq        int64               `json:"last_seq"` // 本次返回的最后一条消息的序列号，作为下次同步的起点
+	HasMore        bool                `json:"has_more"`
+}
+
+// SyncMessages 返回用户所在的每个会话中序列号大于 cursors 的消息
+// cursors 中没有的会话从头开始同步
+func (s *MessageService) SyncMessages(ctx context.Context, userID string, cursors map[string]int64, limit int) ([]ConversationSync, error) {
+	if limit <= 0 {
+		limit = defaultSyncLimit
+	}
+	if limit > maxSyncLimit {
+		limit = maxSyncLimit
+	}
 
+	conversationIDs, err := s.getUserConversationIDs(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
 
-	// 转换为协议消息
-	var messages []*protocol.Message
-	for i := len(dbMessages) - 1; i >= 0; i-- { // 反转顺序
-		msg := dbMessages[i]
-		messages = append(messages, &protocol.Message{
-			ID:             msg.ID,
-			ConversationID: msg.ConversationID,
-			SenderID:       msg.SenderID,
-			Content:        msg.Content,
-			Type:           msg.ContentType,
-			Timestamp:      msg.Timestamp,
-			Status:         msg.Status,
-			RecipientID:    msg.RecipientID,
+	results := make([]ConversationSync, 0)
+	for _, conversationID := range conversationIDs {
+		after := cursors[conversationID]
+
+		var dbMessages []model.Message
+		err := s.db.Where("conversation_id = ? AND seq > ?", conversationID, after).
+			Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+			Order("seq asc").
+			Limit(limit + 1).
+			Find(&dbMessages).Error
+		if err != nil {
+			return nil, fmt.Errorf("同步会话 %s 的消息失败: %w", conversationID, err)
+		}
+
+		if len(dbMessages) == 0 {
+			continue
+		}
+
+		hasMore := len(dbMessages) > limit
+		if hasMore {
+			dbMessages = dbMessages[:limit]
+		}
+
+		messages := make([]*protocol.Message, 0, len(dbMessages))
+		for i := range dbMessages {
+			messages = append(messages, messageFromModel(&dbMessages[i]))
+		}
+		s.attachReactions(messages)
+		s.attachPolls(messages)
+		s.attachReadCounts(messages)
+
+		results = append(results, ConversationSync{
+			ConversationID: conversationID,
+			Messages:       messages,
+			LastSeq:        dbMessages[len(dbMessages)-1].Seq,
+			HasMore:        hasMore,
 		})
 	}
 
-	return messages, nil
+	return results, nil
 }
 
-// GetMessages 获取两个用户之间的消息历史
-func (s *MessageService) GetMessages(ctx context.Context, userID string, otherUserID string, limit int64) ([]*protocol.Message, error) {
-	// 首先找到这两个用户之间的会话
-	var conversationID string
-	err := s.db.Raw(`
-		SELECT c.id FROM conversations c
-		JOIN participants p1 ON c.id = p1.conversation_id
-		JOIN participants p2 ON c.id = p2.conversation_id
-		WHERE c.is_group = ? AND p1.user_id = ? AND p2.user_id = ?
-	`, false, userID, otherUserID).Scan(&conversationID).Error
+// getUserConversationIDs 获取用户所在的所有会话ID（包括所在群组）
+func (s *MessageService) getUserConversationIDs(ctx context.Context, userID string) ([]string, error) {
+	var conversationIDs []string
+	if err := s.db.Model(&model.Participant{}).
+		Where("user_id = ?", userID).
+		Pluck("conversation_id", &conversationIDs).Error; err != nil {
+		return nil, fmt.Errorf("查询用户会话失败: %w", err)
+	}
 
-	if err != nil {
-		return nil, err
+	// 群消息以群组ID作为会话ID；群成员通常已是群聊会话的参与者，合并时去重
+	var groupIDs []string
+	if err := s.db.Model(&model.GroupMember{}).
+		Where("user_id = ?", userID).
+		Pluck("group_id", &groupIDs).Error; err != nil {
+		return nil, fmt.Errorf("查询用户群组失败: %w", err)
 	}
 
-	if conversationID == "" {
-		// 没有会话，返回空消息列表
-		return []*protocol.Message{}, nil
+	return uniqueStrings(append(conversationIDs, groupIDs...)), nil
+}
+
+// messageFromModel 将数据库消息转换为协议消息，已撤回的消息只保留占位信息
+func messageFromModel(msg *model.Message) *protocol.Message {
+	message := &protocol.Message{
+		ID:             msg.ID,
+		ConversationID: msg.ConversationID,
+		SenderID:       msg.SenderID,
+		RecipientID:    msg.RecipientID,
+		Content:        msg.Content,
+		Type:           msg.ContentType,
+		Timestamp:      msg.Timestamp,
+		Status:         msg.Status,
+		IsGroup:        msg.IsGroup,
+		Seq:            msg.Seq,
+		Recalled:       msg.Recalled,
+		ReplyToID:      msg.ReplyToID,
+		ThreadID:       msg.ThreadID,
+		ReplyCount:     msg.ReplyCount,
+		ClientMsgID:    derefString(msg.ClientMsgID),
+		MentionAll:     msg.MentionAll,
+		MentionAdmins:  msg.MentionAdmins,
+		TTL:            msg.TTL,
+		ExpiresAt:      unixOrZero(msg.ExpiresAt),
+	}
+	if msg.Mentions != "" {
+		message.Mentions = strings.Split(msg.Mentions, ",")
+	}
+	if msg.ReplyToID != "" {
+		message.Quote = &protocol.QuotedMessage{
+			ID:       msg.ReplyToID,
+			SenderID: msg.QuoteSenderID,
+			Type:     msg.QuoteType,
+			Content:  msg.QuoteContent,
+		}
+	}
+	if msg.ForwardFromID != "" || msg.ForwardConversationID != "" {
+		message.Forward = &protocol.ForwardInfo{
+			MessageID:      msg.ForwardFromID,
+			SenderID:       msg.ForwardSenderID,
+			ConversationID: msg.ForwardConversationID,
+		}
 	}
+	message.Record = protocol.DecodeChatRecord(msg.Record)
+	protocol.DecodeBody(message, msg.Body)
+	if msg.IsGroup {
+		message.GroupID = msg.RecipientID
+	}
+	if msg.EditedAt != nil {
+		message.EditedAt = msg.EditedAt.Unix()
+	}
+	if msg.Recalled {
+		message.Content = ""
+	}
+	return message
+}
+
+// GetMessages 获取两个用户之间的消息历史
+func (s *MessageService) GetMessages(ctx context.Context, userID string, otherUserID string, limit int64) ([]*protocol.Message, error) {
+	// 两个用户之间的单聊会话ID是确定的
+	conversationID := model.PrivateConversationID(userID, otherUserID)
 
 	// 获取会话中的消息
 	return s.GetMessagesByConversation(ctx, conversationID, limit)
 }
 
-// MarkMessagesAsRead 将消息标记为已读
-func (s *MessageService) MarkMessagesAsRead(ctx context.Context, conversationID string, userID string) error {
-	// 更新参与者的最后读取时间
-	return s.db.Model(&model.Participant{}).
-		Where("conversation_id = ? AND user_id = ?", conversationID, userID).
-		Update("last_read_at", time.Now()).Error
+// MarkMessageDelivered 客户端确认收到消息后将其标记为已送达
+// 返回原消息以及状态是否发生变化（已读的消息不会回退为已送达）
+// 群消息在表中只有一行，单个成员的确认不能代表全员送达，因此不改变状态，群消息的进度以已读人数为准
+func (s *MessageService) MarkMessageDelivered(ctx context.Context, messageID string, userID string) (*model.Message, bool, error) {
+	var dbMessage model.Message
+	if err := s.db.Where("id = ?", messageID).Take(&dbMessage).Error; err != nil {
+		return nil, false, fmt.Errorf("查询消息 %s 失败: %w", messageID, err)
+	}
+
+	// 发送者自己的确认（多端同步）不改变状态
+	if dbMessage.SenderID == userID {
+		return &dbMessage, false, nil
+	}
+
+	// 只有接收者或群成员的确认才有效
+	if dbMessage.IsGroup {
+		var count int64
+		s.db.Model(&model.GroupMember{}).
+			Where("group_id = ? AND user_id = ?", dbMessage.RecipientID, userID).
+			Count(&count)
+		if count == 0 {
+			return nil, false, fmt.Errorf("用户 %s 不是群组 %s 的成员", userID, dbMessage.RecipientID)
+		}
+		return &dbMessage, false, nil
+	} else if dbMessage.RecipientID != userID {
+		return nil, false, fmt.Errorf("用户 %s 不是消息 %s 的接收者", userID, messageID)
+	}
+
+	result := s.db.Model(&model.Message{}).
+		Where("id = ? AND status IN ?", messageID, []string{constants.MessageStatusSent, constants.MessageStatusUnsent}).
+		Updates(map[string]interface{}{
+			"status":     constants.MessageStatusDelivered,
+			"updated_at": time.Now(),
+		})
+	if result.Error != nil {
+		return nil, false, result.Error
+	}
+
+	return &dbMessage, result.RowsAffected > 0, nil
 }
 
 // BroadcastStatus broadcasts user status changes
@@ -0,0 +1,40 @@
+package chat
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"cursorIM/internal/constants"
+	"cursorIM/internal/protocol"
+
+	"github.com/google/uuid"
+)
+
+// systemSenderID 群系统消息的发送者
+const systemSenderID = "system"
+
+// SendSystemMessage 向群聊时间线写入一条系统消息并推送给所有群成员
+// 系统消息和普通群消息一样分配会话序列号，会出现在历史记录和增量同步中
+func (s *MessageService) SendSystemMessage(ctx context.Context, groupID, content string) (*protocol.Message, error) {
+	message := &protocol.Message{
+		ID:             uuid.New().String(),
+		Type:           constants.MessageTypeSystem,
+		SenderID:       systemSenderID,
+		RecipientID:    groupID,
+		GroupID:        groupID,
+		ConversationID: groupID,
+		IsGroup:        true,
+		Content:        content,
+		Status:         constants.MessageStatusSent,
+		Timestamp:      time.Now().Unix(),
+	}
+	if err := s.saveMessage(ctx, message); err != nil {
+		return nil, err
+	}
+
+	if err := s.deliver(message); err != nil {
+		log.Printf("投递群组 %s 的系统消息 %s 失败: %v", groupID, message.ID, err)
+	}
+	return message, nil
+}
@@ -0,0 +1,181 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"cursorIM/internal/model"
+	"cursorIM/internal/protocol"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ThreadInfo 话题概要
+type ThreadInfo struct {
+	Root        *protocol.Message `json:"root"`
+	ReplyCount  int               `json:"reply_count"`
+	UnreadCount int64             `json:"unread_count"`
+}
+
+// prepareReply 校验回复/话题消息的父消息，并填充引用快照和话题ID
+// 父消息必须属于同一会话；回复话题内的消息时自动归入该话题
+func (s *MessageService) prepareReply(message *protocol.Message) error {
+	if message.ReplyToID != "" {
+		parent, err := s.findConversationMessage(message.ReplyToID, message.ConversationID)
+		if err != nil {
+			return err
+		}
+
+		// 引用快照：撤回的消息不保留内容
+		quote := &protocol.QuotedMessage{
+			ID:       parent.ID,
+			SenderID: parent.SenderID,
+			Type:     parent.ContentType,
+		}
+		if !parent.Recalled {
+			quote.Content = parent.Content
+		}
+		message.Quote = quote
+
+		if message.ThreadID == "" && parent.ThreadID != "" {
+			message.ThreadID = parent.ThreadID
+		}
+	}
+
+	if message.ThreadID != "" {
+		root, err := s.findConversationMessage(message.ThreadID, message.ConversationID)
+		if err != nil {
+			return err
+		}
+		// 话题只有一层，指向话题内消息时使用其根消息
+		if root.ThreadID != "" {
+			message.ThreadID = root.ThreadID
+		}
+	}
+
+	return nil
+}
+
+// findConversationMessage 查找会话内的消息
+func (s *MessageService) findConversationMessage(messageID, conversationID string) (*model.Message, error) {
+	var dbMessage model.Message
+	if err := s.db.Where("id = ?", messageID).Take(&dbMessage).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("消息 %s 不存在", messageID)
+		}
+		return nil, fmt.Errorf("查询消息失败: %w", err)
+	}
+	if dbMessage.ConversationID != conversationID {
+		return nil, fmt.Errorf("消息 %s 不属于会话 %s", messageID, conversationID)
+	}
+	return &dbMessage, nil
+}
+
+// applyQuote 将引用快照写入数据库消息
+func applyQuote(dbMessage *model.Message, quote *protocol.QuotedMessage) {
+	if quote == nil {
+		return
+	}
+	dbMessage.QuoteSenderID = quote.SenderID
+	dbMessage.QuoteType = quote.Type
+	dbMessage.QuoteContent = quote.Content
+}
+
+// onThreadReply 话题收到新回复：根消息回复数加一，回复者视为已读
+func (s *MessageService) onThreadReply(message *protocol.Message) {
+	err := s.db.Model(&model.Message{}).
+		Where("id = ?", message.ThreadID).
+		Update("reply_count", gorm.Expr("reply_count + 1")).Error
+	if err != nil {
+		log.Printf("更新话题 %s 的回复数失败: %v", message.ThreadID, err)
+	}
+
+	if err := s.MarkThreadRead(context.Background(), message.ThreadID, message.SenderID); err != nil {
+		log.Printf("更新用户 %s 在话题 %s 的已读状态失败: %v", message.SenderID, message.ThreadID, err)
+	}
+}
+
+// GetThreadMessages 获取话题内的回复
+func (s *MessageService) GetThreadMessages(ctx context.Context, threadID string, limit int64) ([]*protocol.Message, error) {
+	var dbMessages []model.Message
+
+	// 查询回复
+	err := s.db.Where("thread_id = ?", threadID).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+		Order("seq desc, timestamp desc").
+		Limit(int(limit)).
+		Find(&dbMessages).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	// 转换为协议消息
+	var messages []*protocol.Message
+	for i := len(dbMessages) - 1; i >= 0; i-- { // 反转顺序
+		messages = append(messages, messageFromModel(&dbMessages[i]))
+	}
+	s.attachReactions(messages)
+	s.attachPolls(messages)
+	s.attachReadCounts(messages)
+
+	return messages, nil
+}
+
+// GetThreadInfo 获取话题的根消息、回复数以及用户的未读回复数
+func (s *MessageService) GetThreadInfo(ctx context.Context, threadID string, userID string) (*ThreadInfo, error) {
+	var root model.Message
+	if err := s.db.Where("id = ?", threadID).Take(&root).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("话题不存在")
+		}
+		return nil, fmt.Errorf("查询话题失败: %w", err)
+	}
+
+	var state model.ThreadReadState
+	if err := s.db.Where("thread_id = ? AND user_id = ?", threadID, userID).Limit(1).Find(&state).Error; err != nil {
+		return nil, fmt.Errorf("查询话题已读状态失败: %w", err)
+	}
+
+	var unread int64
+	query := s.db.Model(&model.Message{}).
+		Where("thread_id = ? AND sender_id <> ?", threadID, userID)
+	if !state.LastReadAt.IsZero() {
+		query = query.Where("created_at > ?", state.LastReadAt)
+	}
+	if err := query.Count(&unread).Error; err != nil {
+		return nil, fmt.Errorf("统计话题未读数失败: %w", err)
+	}
+
+	rootMessage := messageFromModel(&root)
+	s.attachReactions([]*protocol.Message{rootMessage})
+	s.attachPolls([]*protocol.Message{rootMessage})
+	s.attachReadCounts([]*protocol.Message{rootMessage})
+
+	return &ThreadInfo{
+		Root:        rootMessage,
+		ReplyCount:  root.ReplyCount,
+		UnreadCount: unread,
+	}, nil
+}
+
+// MarkThreadRead 将话题标记为已读
+func (s *MessageService) MarkThreadRead(ctx context.Context, threadID string, userID string) error {
+	now := time.Now()
+	state := model.ThreadReadState{
+		ID:         uuid.New().String(),
+		ThreadID:   threadID,
+		UserID:     userID,
+		LastReadAt: now,
+		UpdatedAt:  now,
+	}
+	return s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "thread_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"last_read_at", "updated_at"}),
+	}).Create(&state).Error
+}
@@ -0,0 +1,157 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+
+	"cursorIM/internal/constants"
+	"cursorIM/internal/database"
+	"cursorIM/internal/model"
+	"cursorIM/internal/redisclient"
+
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+)
+
+// seqScript 原子地分配下一个序列号
+// 计数器不存在时返回 -1，由调用方从数据库取得起点后重试；
+// ARGV[1] 非空时作为下限，保证计数器不会落后于数据库中已分配的序列号
+var seqScript = redis.NewScript(`
+local cur = redis.call('GET', KEYS[1])
+if not cur then
+	if ARGV[1] == '' then
+		return -1
+	end
+	redis.call('SET', KEYS[1], ARGV[1])
+elseif ARGV[1] ~= '' and tonumber(cur) < tonumber(ARGV[1]) then
+	redis.call('SET', KEYS[1], ARGV[1])
+end
+return redis.call('INCR', KEYS[1])
+`)
+
+// reseedConversations 记录曾经走过数据库分配的会话
+// Redis 恢复后这些会话的计数器需要以数据库为下限重新校准
+var reseedConversations sync.Map
+
+// SequenceAllocator 会话消息序列号分配器
+// 优先使用 Redis INCR，Redis 不可用时退回数据库行锁
+type SequenceAllocator struct {
+	db *gorm.DB
+}
+
+// NewSequenceAllocator 创建序列号分配器
+func NewSequenceAllocator() *SequenceAllocator {
+	return &SequenceAllocator{
+		db: database.GetDB(),
+	}
+}
+
+// Next 为会话分配下一个序列号
+func (a *SequenceAllocator) Next(ctx context.Context, conversationID string) (int64, error) {
+	if conversationID == "" {
+		return 0, fmt.Errorf("会话ID不能为空")
+	}
+
+	if redisclient.IsRedisEnabled() {
+		seq, err := a.nextFromRedis(ctx, conversationID)
+		if err == nil {
+			return seq, nil
+		}
+		log.Printf("Redis 分配会话 %s 的序列号失败，使用数据库分配: %v", conversationID, err)
+	}
+
+	seq, err := a.nextFromDB(conversationID)
+	if err != nil {
+		return 0, err
+	}
+	reseedConversations.Store(conversationID, true)
+	return seq, nil
+}
+
+// nextFromRedis 使用 Redis 计数器分配序列号
+func (a *SequenceAllocator) nextFromRedis(ctx context.Context, conversationID string) (int64, error) {
+	client := redisclient.GetRedisClient()
+	keys := []string{fmt.Sprintf(constants.RedisKeyConversationSeq, conversationID)}
+
+	floor := ""
+	if _, ok := reseedConversations.Load(conversationID); ok {
+		maxSeq, err := a.maxSeq(conversationID)
+		if err != nil {
+			return 0, err
+		}
+		floor = strconv.FormatInt(maxSeq, 10)
+	}
+
+	seq, err := seqScript.Run(ctx, client, keys, floor).Int64()
+	if err != nil {
+		return 0, err
+	}
+
+	if seq < 0 {
+		// 计数器不存在（首次使用或 Redis 数据丢失），以数据库中的最大值为起点
+		maxSeq, err := a.maxSeq(conversationID)
+		if err != nil {
+			return 0, err
+		}
+		seq, err = seqScript.Run(ctx, client, keys, strconv.FormatInt(maxSeq, 10)).Int64()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	reseedConversations.Delete(conversationID)
+	return seq, nil
+}
+
+// nextFromDB 使用数据库行锁分配序列号
+func (a *SequenceAllocator) nextFromDB(conversationID string) (int64, error) {
+	var seq int64
+	err := a.db.Transaction(func(tx *gorm.DB) error {
+		var maxSeq int64
+		if err := tx.Model(&model.Message{}).
+			Where("conversation_id = ?", conversationID).
+			Select("COALESCE(MAX(seq), 0)").
+			Scan(&maxSeq).Error; err != nil {
+			return err
+		}
+
+		// LAST_INSERT_ID(expr) 让同一连接上的后续查询拿到本次分配的值
+		if err := tx.Exec(`
+			INSERT INTO conversation_sequences (conversation_id, last_seq, updated_at)
+			VALUES (?, LAST_INSERT_ID(?), NOW())
+			ON DUPLICATE KEY UPDATE last_seq = LAST_INSERT_ID(GREATEST(last_seq, ?) + 1), updated_at = NOW()
+		`, conversationID, maxSeq+1, maxSeq).Error; err != nil {
+			return err
+		}
+
+		return tx.Raw("SELECT LAST_INSERT_ID()").Scan(&seq).Error
+	})
+	if err != nil {
+		return 0, fmt.Errorf("分配会话 %s 的序列号失败: %w", conversationID, err)
+	}
+	return seq, nil
+}
+
+// maxSeq 查询会话在数据库中已分配的最大序列号
+func (a *SequenceAllocator) maxSeq(conversationID string) (int64, error) {
+	var maxSeq int64
+	err := a.db.Model(&model.Message{}).
+		Where("conversation_id = ?", conversationID).
+		Select("COALESCE(MAX(seq), 0)").
+		Scan(&maxSeq).Error
+	if err != nil {
+		return 0, err
+	}
+
+	var counter model.ConversationSequence
+	if err := a.db.Where("conversation_id = ?", conversationID).Limit(1).Find(&counter).Error; err != nil {
+		return 0, err
+	}
+	if counter.LastSeq > maxSeq {
+		maxSeq = counter.LastSeq
+	}
+	return maxSeq, nil
+}
@@ -30,8 +30,26 @@ type Config struct {
 		Password string `yaml:"password"`
 		DB       int    `yaml:"db"`
 	} `yaml:"redisclient"`
+
+	Message struct {
+		RecallWindow int `yaml:"recall_window"` // 消息可撤回时限（秒）
+		DedupWindow  int `yaml:"dedup_window"`  // 客户端消息ID在 Redis 中的去重时限（秒）
+	} `yaml:"message"`
+
+	Search struct {
+		Engine string `yaml:"engine"` // 消息搜索引擎：mysql（FULLTEXT ngram）或 memory（进程内索引）
+	} `yaml:"search"`
 }
 
+// 默认消息撤回时限与去重时限（秒）
+const (
+	defaultRecallWindow = 120
+	defaultDedupWindow  = 86400
+)
+
+// defaultSearchEngine 默认消息搜索引擎
+const defaultSearchEngine = "mysql"
+
 // GlobalConfig 全局配置
 var GlobalConfig = &Config{}
 
@@ -53,6 +71,11 @@ func Init() error {
 		GlobalConfig.Redis.Password = ""
 		GlobalConfig.Redis.DB = 0
 
+		GlobalConfig.Message.RecallWindow = defaultRecallWindow
+		GlobalConfig.Message.DedupWindow = defaultDedupWindow
+
+		GlobalConfig.Search.Engine = defaultSearchEngine
+
 		return nil
 	}
 
@@ -80,6 +103,21 @@ func Init() error {
 		GlobalConfig.Redis.Port = 6379
 	}
 
+	// 确保撤回时限有值
+	if GlobalConfig.Message.RecallWindow <= 0 {
+		GlobalConfig.Message.RecallWindow = defaultRecallWindow
+	}
+
+	// 确保去重时限有值
+	if GlobalConfig.Message.DedupWindow <= 0 {
+		GlobalConfig.Message.DedupWindow = defaultDedupWindow
+	}
+
+	// 确保搜索引擎有值
+	if GlobalConfig.Search.Engine == "" {
+		GlobalConfig.Search.Engine = defaultSearchEngine
+	}
+
 	log.Printf("配置加载成功: Redis=%s:%d", GlobalConfig.Redis.Host, GlobalConfig.Redis.Port)
 	return nil
 }
@@ -0,0 +1,146 @@
+package connection
+
+import (
+	"sync"
+	"time"
+
+	"cursorIM/internal/constants"
+	"cursorIM/internal/protocol"
+)
+
+// ACK 重传参数
+const (
+	AckTimeout       = 5 * time.Second // 首次等待确认的时间，之后按指数退避
+	AckMaxRetries    = 3               // 最大重传次数，超过后认为连接不可用
+	AckWindowSize    = 1024            // 每个连接允许的最大未确认消息数
+	AckCheckInterval = 1 * time.Second // 检查超时消息的间隔
+)
+
+// pendingAck 等待客户端确认的消息
+type pendingAck struct {
+	message  *protocol.Message
+	attempts int
+	deadline time.Time
+}
+
+// AckWindow 每个连接的未确认消息窗口
+// 客户端在握手时声明支持确认或发送第一条 ACK 后才开始跟踪和重传，不发送 ACK 的旧客户端不受影响
+type AckWindow struct {
+	pending map[string]*pendingAck
+	enabled bool
+	mutex   sync.Mutex
+}
+
+// NewAckWindow 创建未确认消息窗口
+func NewAckWindow() *AckWindow {
+	return &AckWindow{
+		pending: make(map[string]*pendingAck),
+	}
+}
+
+// RequiresAck 判断消息是否需要客户端确认
+// 心跳、状态、错误、回执本身以及临时信号都不需要确认
+func RequiresAck(message *protocol.Message) bool {
+	if message.ID == "" || protocol.IsEphemeral(message.Type) {
+		return false
+	}
+
+	switch message.Type {
+	case constants.MessageTypePing, constants.MessageTypePong, constants.MessageTypeStatus,
+		constants.MessageTypeAck, constants.MessageTypeError:
+		return false
+	}
+	return true
+}
+
+// Enable 开启确认跟踪，之后写出的消息需要客户端确认
+func (w *AckWindow) Enable() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.enabled = true
+}
+
+// Track 记录一条已写出、等待确认的消息，未开启确认跟踪时不记录
+// 窗口已满时返回 false，调用方应视连接为不可用
+func (w *AckWindow) Track(message *protocol.Message) bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if !w.enabled {
+		return true
+	}
+
+	if entry, ok := w.pending[message.ID]; ok {
+		// 重传的消息只刷新截止时间
+		entry.deadline = time.Now().Add(backoff(entry.attempts))
+		return true
+	}
+
+	if len(w.pending) >= AckWindowSize {
+		return false
+	}
+
+	w.pending[message.ID] = &pendingAck{
+		message:  message,
+		deadline: time.Now().Add(AckTimeout),
+	}
+	return true
+}
+
+// Ack 确认消息，返回被确认的消息，不在窗口中时返回 nil
+// 客户端的第一条确认同时开启确认跟踪
+func (w *AckWindow) Ack(messageID string) *protocol.Message {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.enabled = true
+
+	entry, ok := w.pending[messageID]
+	if !ok {
+		return nil
+	}
+	delete(w.pending, messageID)
+	return entry.message
+}
+
+// Due 返回已超时需要重传的消息
+// 如果有消息超过最大重传次数，exhausted 为 true
+func (w *AckWindow) Due(now time.Time) (resend []*protocol.Message, exhausted bool) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	for _, entry := range w.pending {
+		if now.Before(entry.deadline) {
+			continue
+		}
+
+		if entry.attempts >= AckMaxRetries {
+			exhausted = true
+			continue
+		}
+
+		entry.attempts++
+		entry.deadline = now.Add(backoff(entry.attempts))
+		resend = append(resend, entry.message)
+	}
+
+	return resend, exhausted
+}
+
+// Drain 取出所有未确认的消息并清空窗口
+func (w *AckWindow) Drain() []*protocol.Message {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	messages := make([]*protocol.Message, 0, len(w.pending))
+	for _, entry := range w.pending {
+		messages = append(messages, entry.message)
+	}
+	w.pending = make(map[string]*pendingAck)
+	return messages
+}
+
+// backoff 计算第 attempts 次重传后的等待时间
+func backoff(attempts int) time.Duration {
+	return AckTimeout << uint(attempts)
+}
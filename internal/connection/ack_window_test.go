@@ -0,0 +1,131 @@
+package connection
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"cursorIM/internal/constants"
+	"cursorIM/internal/protocol"
+)
+
+func TestRequiresAck(t *testing.T) {
+	tests := []struct {
+		name    string
+		message *protocol.Message
+		want    bool
+	}{
+		{"聊天消息", &protocol.Message{ID: "m1", Type: constants.MessageTypeText}, true},
+		{"没有ID", &protocol.Message{Type: constants.MessageTypeText}, false},
+		{"心跳", &protocol.Message{ID: "m2", Type: constants.MessageTypePing}, false},
+		{"心跳响应", &protocol.Message{ID: "m3", Type: constants.MessageTypePong}, false},
+		{"状态", &protocol.Message{ID: "m4", Type: constants.MessageTypeStatus}, false},
+		{"回执", &protocol.Message{ID: "m5", Type: constants.MessageTypeAck}, false},
+		{"错误", &protocol.Message{ID: "m6", Type: constants.MessageTypeError}, false},
+		{"临时信号", &protocol.Message{ID: "m7", Type: constants.MessageTypeTyping}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RequiresAck(tt.message); got != tt.want {
+				t.Errorf("RequiresAck() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAckWindowOptIn(t *testing.T) {
+	tests := []struct {
+		name        string
+		enable      func(w *AckWindow)
+		wantPending int
+	}{
+		{"未开启时不跟踪", func(w *AckWindow) {}, 0},
+		{"握手开启", func(w *AckWindow) { w.Enable() }, 1},
+		{"第一条确认开启", func(w *AckWindow) { w.Ack("earlier") }, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewAckWindow()
+			tt.enable(w)
+
+			if !w.Track(&protocol.Message{ID: "m1"}) {
+				t.Fatal("Track() = false, want true")
+			}
+			if got := len(w.Drain()); got != tt.wantPending {
+				t.Errorf("pending = %d, want %d", got, tt.wantPending)
+			}
+		})
+	}
+}
+
+func TestAckWindowDue(t *testing.T) {
+	start := time.Now()
+
+	tests := []struct {
+		name          string
+		elapsed       []time.Duration // 依次检查的时间点（相对 Track 时刻）
+		wantResends   int
+		wantExhausted bool
+	}{
+		{"未超时", []time.Duration{AckTimeout - time.Second}, 0, false},
+		{"首次超时重传", []time.Duration{AckTimeout}, 1, false},
+		{"退避期间不重复重传", []time.Duration{AckTimeout, AckTimeout + time.Second}, 1, false},
+		{"按退避重传到上限", []time.Duration{5 * time.Second, 15 * time.Second, 35 * time.Second}, 3, false},
+		{"超过上限", []time.Duration{5 * time.Second, 15 * time.Second, 35 * time.Second, 75 * time.Second}, 3, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewAckWindow()
+			w.Enable()
+			w.Track(&protocol.Message{ID: "m1"})
+			w.pending["m1"].deadline = start.Add(AckTimeout)
+
+			resends := 0
+			exhausted := false
+			for _, elapsed := range tt.elapsed {
+				resend, ex := w.Due(start.Add(elapsed))
+				resends += len(resend)
+				exhausted = exhausted || ex
+			}
+
+			if resends != tt.wantResends {
+				t.Errorf("resends = %d, want %d", resends, tt.wantResends)
+			}
+			if exhausted != tt.wantExhausted {
+				t.Errorf("exhausted = %v, want %v", exhausted, tt.wantExhausted)
+			}
+		})
+	}
+}
+
+func TestAckWindowAck(t *testing.T) {
+	w := NewAckWindow()
+	w.Enable()
+	w.Track(&protocol.Message{ID: "m1"})
+
+	if w.Ack("m1") == nil {
+		t.Error("Ack(m1) = nil, want message")
+	}
+	if w.Ack("m1") != nil {
+		t.Error("重复确认 Ack(m1) != nil, want nil")
+	}
+	if resend, exhausted := w.Due(time.Now().Add(time.Hour)); len(resend) != 0 || exhausted {
+		t.Errorf("已确认的消息仍被重传: resend=%d exhausted=%v", len(resend), exhausted)
+	}
+}
+
+func TestAckWindowFull(t *testing.T) {
+	w := NewAckWindow()
+	w.Enable()
+	for i := 0; i < AckWindowSize; i++ {
+		if !w.Track(&protocol.Message{ID: fmt.Sprintf("m%d", i)}) {
+			t.Fatalf("第 %d 条消息 Track() = false", i)
+		}
+	}
+	if w.Track(&protocol.Message{ID: "overflow"}) {
+		t.Error("窗口已满时 Track() = true, want false")
+	}
+}
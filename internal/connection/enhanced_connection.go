@@ -16,6 +16,15 @@ type EnhancedConnection interface {
 
 	// SetMessageAdapter 设置消息适配器
 	SetMessageAdapter(adapter *protocol.MessageAdapter)
+
+	// AckMessage 确认客户端已收到消息
+	AckMessage(messageID string) bool
+
+	// EnableAcks 开启送达确认，客户端在握手时声明支持确认时调用
+	EnableAcks()
+
+	// DrainUnacked 取出所有未被客户端确认的消息
+	DrainUnacked() []*protocol.Message
 }
 
 // ProtocolAwareConnection 协议感知的连接基础结构
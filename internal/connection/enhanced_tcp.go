@@ -8,6 +8,7 @@ import (
 	"net"
 	"time"
 
+	"cursorIM/internal/constants"
 	"cursorIM/internal/protocol"
 
 	"github.com/google/uuid"
@@ -21,6 +22,7 @@ type EnhancedTCPConnection struct {
 	connType string
 	send     chan *protocol.Message
 	done     chan struct{}
+	acks     *AckWindow // 未确认消息窗口
 	reader   *bufio.Reader
 	writer   *bufio.Writer
 }
@@ -40,6 +42,7 @@ func NewEnhancedTCPConnection(conn net.Conn, userID string, connType string) *En
 		connType:                connType,
 		send:                    make(chan *protocol.Message, 256),
 		done:                    make(chan struct{}),
+		acks:                    NewAckWindow(),
 		reader:                  bufio.NewReader(conn),
 		writer:                  bufio.NewWriter(conn),
 	}
@@ -144,6 +147,49 @@ func (c *EnhancedTCPConnection) GetSendChannel() <-chan *protocol.Message {
 	return c.send
 }
 
+// AckMessage 确认客户端已收到消息
+func (c *EnhancedTCPConnection) AckMessage(messageID string) bool {
+	return c.acks.Ack(messageID) != nil
+}
+
+// EnableAcks 开启送达确认，之后推送的消息需要客户端确认
+func (c *EnhancedTCPConnection) EnableAcks() {
+	c.acks.Enable()
+}
+
+// DrainUnacked 取出所有未被客户端确认的消息
+func (c *EnhancedTCPConnection) DrainUnacked() []*protocol.Message {
+	return c.acks.Drain()
+}
+
+// writeTracked 写出消息，需要确认的消息记录到未确认窗口
+func (c *EnhancedTCPConnection) writeTracked(message *protocol.Message) error {
+	if err := c.SendMessageWithProtocol(message, c.GetProtocolType()); err != nil {
+		return err
+	}
+
+	if RequiresAck(message) && !c.acks.Track(message) {
+		return fmt.Errorf("未确认消息窗口已满")
+	}
+	return nil
+}
+
+// resendUnacked 重传超时未确认的消息，超过最大重传次数时返回错误
+func (c *EnhancedTCPConnection) resendUnacked() error {
+	resend, exhausted := c.acks.Due(time.Now())
+	if exhausted {
+		return fmt.Errorf("消息超过最大重传次数仍未确认")
+	}
+
+	for _, message := range resend {
+		log.Printf("重传未确认消息 %s 到用户 %s", message.ID, c.userID)
+		if err := c.writeTracked(message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // StartReading 开始从TCP读取消息
 func (c *EnhancedTCPConnection) StartReading(msgHandler func(*protocol.Message)) {
 	defer c.Close()
@@ -217,15 +263,6 @@ func (c *EnhancedTCPConnection) StartReading(msgHandler func(*protocol.Message))
 				message.Timestamp = time.Now().Unix()
 			}
 
-			// 确保有会话ID
-			if message.ConversationID == "" && message.Type == "message" {
-				if message.RecipientID != "" {
-					tempConvID := uuid.New().String()
-					message.ConversationID = tempConvID
-					log.Printf("为消息生成临时会话ID: %s", tempConvID)
-				}
-			}
-
 			// 处理ping消息
 			if message.Type == "ping" {
 				pongMsg := &protocol.Message{
@@ -240,8 +277,17 @@ func (c *EnhancedTCPConnection) StartReading(msgHandler func(*protocol.Message))
 				continue
 			}
 
+			// 处理客户端的送达确认
+			// 通知本身没有送达状态，确认后不再交给上层更新消息状态
+			if message.Type == constants.MessageTypeAck {
+				if acked := c.acks.Ack(message.ID); acked == nil || !protocol.IsNotice(acked.Type) {
+					msgHandler(message)
+				}
+				continue
+			}
+
 			// 检查消息接收者
-			if message.RecipientID == "" && message.Type != "status" {
+			if message.RecipientID == "" && protocol.RequiresRecipient(message.Type) {
 				log.Printf("警告: 用户 %s 发送的消息没有接收者ID", c.userID)
 				if message.Type == "message" {
 					errorMsg := &protocol.Message{
@@ -266,8 +312,10 @@ func (c *EnhancedTCPConnection) StartReading(msgHandler func(*protocol.Message))
 // StartWriting 开始向TCP写入消息
 func (c *EnhancedTCPConnection) StartWriting() {
 	ticker := time.NewTicker(PingPeriod)
+	retryTicker := time.NewTicker(AckCheckInterval)
 	defer func() {
 		ticker.Stop()
+		retryTicker.Stop()
 		c.conn.Close()
 	}()
 
@@ -281,13 +329,19 @@ func (c *EnhancedTCPConnection) StartWriting() {
 			}
 
 			// 使用连接的默认协议类型发送消息
-			if err := c.SendMessageWithProtocol(message, c.GetProtocolType()); err != nil {
+			if err := c.writeTracked(message); err != nil {
 				log.Printf("发送消息失败: %v", err)
 				return
 			}
 
 			log.Printf("✅ 成功发送消息到用户 %s (协议: %s)", c.userID, c.GetProtocolType())
 
+		case <-retryTicker.C:
+			if err := c.resendUnacked(); err != nil {
+				log.Printf("用户 %s 的连接不可用: %v", c.userID, err)
+				return
+			}
+
 		case <-ticker.C:
 			// 检查连接是否已关闭
 			select {
@@ -0,0 +1,144 @@
+package connection
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"cursorIM/internal/constants"
+	"cursorIM/internal/database"
+	"cursorIM/internal/model"
+	"cursorIM/internal/protocol"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// 群成员缓存过期时间
+const groupMemberCacheTTL = 5 * time.Minute
+
+// GroupDeliveryEnvelope 群消息跨节点批量投递信封
+// 一个节点的所有接收者共用一次发布
+type GroupDeliveryEnvelope struct {
+	Message    *protocol.Message `json:"message"`
+	Recipients []string          `json:"recipients"`
+}
+
+// GroupMemberCache 群成员缓存，优先读 Redis，未命中时回源数据库
+type GroupMemberCache struct {
+	redisClient  *redis.Client
+	redisEnabled bool
+	ctx          context.Context
+}
+
+// NewGroupMemberCache 创建群成员缓存
+func NewGroupMemberCache(redisClient *redis.Client, redisEnabled bool) *GroupMemberCache {
+	return &GroupMemberCache{
+		redisClient:  redisClient,
+		redisEnabled: redisEnabled,
+		ctx:          context.Background(),
+	}
+}
+
+// GetMembers 获取群组的成员ID列表
+func (c *GroupMemberCache) GetMembers(groupID string) ([]string, error) {
+	key := fmt.Sprintf(constants.RedisKeyGroupMembers, groupID)
+
+	if c.redisEnabled {
+		members, err := c.redisClient.SMembers(c.ctx, key).Result()
+		if err == nil && len(members) > 0 {
+			return members, nil
+		}
+	}
+
+	// 缓存未命中，查询数据库
+	var rows []model.GroupMember
+	if err := database.GetDB().Where("group_id = ?", groupID).Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("查询群组成员失败: %w", err)
+	}
+
+	members := make([]string, 0, len(rows))
+	for _, row := range rows {
+		members = append(members, row.UserID)
+	}
+
+	if c.redisEnabled && len(members) > 0 {
+		values := make([]interface{}, len(members))
+		for i, member := range members {
+			values[i] = member
+		}
+
+		pipe := c.redisClient.Pipeline()
+		pipe.Del(c.ctx, key)
+		pipe.SAdd(c.ctx, key, values...)
+		pipe.Expire(c.ctx, key, groupMemberCacheTTL)
+		if _, err := pipe.Exec(c.ctx); err != nil {
+			log.Printf("缓存群组 %s 的成员列表失败: %v", groupID, err)
+		}
+	}
+
+	return members, nil
+}
+
+// Invalidate 使群成员缓存失效
+func (c *GroupMemberCache) Invalidate(groupID string) {
+	if !c.redisEnabled {
+		return
+	}
+	key := fmt.Sprintf(constants.RedisKeyGroupMembers, groupID)
+	if err := c.redisClient.Del(c.ctx, key).Err(); err != nil {
+		log.Printf("清除群组 %s 的成员缓存失败: %v", groupID, err)
+	}
+}
+
+// needsGroupFanout 判断消息是否为尚未扇出的群消息（接收者为群组本身）
+func needsGroupFanout(message *protocol.Message) bool {
+	return message.IsGroup && (message.GroupID == "" || message.GroupID == message.RecipientID)
+}
+
+// groupIDOf 获取群消息的群组ID
+func groupIDOf(message *protocol.Message) string {
+	if message.GroupID != "" {
+		return message.GroupID
+	}
+	return message.RecipientID
+}
+
+// groupMessageFor 为单个群成员复制一份群消息
+func groupMessageFor(message *protocol.Message, groupID, memberID string) *protocol.Message {
+	memberMsg := *message
+	memberMsg.RecipientID = memberID
+	memberMsg.GroupID = groupID
+	memberMsg.IsGroup = true
+	return &memberMsg
+}
+
+// groupRecipients 获取群消息的接收者（排除发送者）
+func groupRecipients(cache *GroupMemberCache, message *protocol.Message) ([]string, error) {
+	members, err := cache.GetMembers(groupIDOf(message))
+	if err != nil {
+		return nil, err
+	}
+
+	recipients := make([]string, 0, len(members))
+	for _, member := range members {
+		if member != message.SenderID {
+			recipients = append(recipients, member)
+		}
+	}
+	return recipients, nil
+}
+
+// publishGroupEnvelope 将群消息及其接收者列表作为一次发布发送到指定频道
+func publishGroupEnvelope(ctx context.Context, client *redis.Client, channel string, message *protocol.Message, recipients []string) error {
+	data, err := json.Marshal(&GroupDeliveryEnvelope{
+		Message:    message,
+		Recipients: recipients,
+	})
+	if err != nil {
+		return fmt.Errorf("序列化群消息信封失败: %w", err)
+	}
+
+	return client.Publish(ctx, channel, data).Err()
+}
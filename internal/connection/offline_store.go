@@ -0,0 +1,349 @@
+package connection
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"cursorIM/internal/constants"
+	"cursorIM/internal/database"
+	"cursorIM/internal/model"
+	"cursorIM/internal/protocol"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// storeOfflineMessage 存储离线消息
+// 已经持久化过的消息只把状态改为未发送，避免重复插入同一主键；通知进入单独的离线通知队列
+func storeOfflineMessage(message *protocol.Message) error {
+	// 确保消息有唯一ID
+	if message.ID == "" {
+		message.ID = uuid.New().String()
+	}
+
+	// 确保接收者ID不为空
+	if message.RecipientID == "" {
+		log.Printf("警告: 离线消息接收者ID为空，无法存储")
+		return fmt.Errorf("接收者ID不能为空")
+	}
+
+	if protocol.IsNotice(message.Type) {
+		return storeOfflineNotice(message)
+	}
+
+	// 将消息标记为未发送状态
+	message.Status = constants.MessageStatusUnsent
+
+	db := database.GetDB()
+
+	var existing model.Message
+	err := db.Select("id", "recipient_id", "is_group").Where("id = ?", message.ID).Take(&existing).Error
+	if err == nil {
+		if existing.RecipientID != message.RecipientID {
+			if !existing.IsGroup {
+				log.Printf("消息 %s 的接收者是 %s，不为 %s 存储离线副本", message.ID, existing.RecipientID, message.RecipientID)
+				return nil
+			}
+			// 群消息在通用表中只有一行，为该成员记录一条离线投递记录
+			log.Printf("记录群消息 %s 的离线投递, 接收者=%s", message.ID, message.RecipientID)
+			return db.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.GroupOfflineMessage{
+				ID:        uuid.New().String(),
+				UserID:    message.RecipientID,
+				MessageID: message.ID,
+				CreatedAt: time.Now(),
+			}).Error
+		}
+
+		log.Printf("标记消息 %s 为离线消息, 接收者=%s", message.ID, message.RecipientID)
+		return db.Model(&model.Message{}).
+			Where("id = ?", message.ID).
+			Update("status", constants.MessageStatusUnsent).Error
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("查询离线消息失败: %w", err)
+	}
+
+	// 保存到数据库
+	dbMessage := model.Message{
+		ID:             message.ID,
+		ConversationID: message.ConversationID,
+		Seq:            message.Seq,
+		TargetID:       message.TargetID,
+		SenderID:       message.SenderID,
+		RecipientID:    message.RecipientID,
+		Content:        message.Content,
+		ContentType:    message.Type,
+		Status:         message.Status,
+		Timestamp:      message.Timestamp,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}
+
+	// 投票、卡片等结构化内容保存在消息体中
+	body, err := protocol.EncodeBody(message)
+	if err != nil {
+		return fmt.Errorf("序列化离线消息体失败: %w", err)
+	}
+	dbMessage.Body = body
+
+	log.Printf("存储离线消息: ID=%s, 发送者=%s, 接收者=%s",
+		dbMessage.ID, dbMessage.SenderID, dbMessage.RecipientID)
+
+	return db.Create(&dbMessage).Error
+}
+
+// storeOfflineNotice 将通知整帧存入离线通知队列，同一通知对同一用户只保存一次
+func storeOfflineNotice(message *protocol.Message) error {
+	if message.Timestamp == 0 {
+		message.Timestamp = time.Now().Unix()
+	}
+
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("序列化离线通知失败: %w", err)
+	}
+
+	log.Printf("存储离线通知: ID=%s, 类型=%s, 接收者=%s", message.ID, message.Type, message.RecipientID)
+
+	return database.GetDB().Clauses(clause.OnConflict{DoNothing: true}).Create(&model.OfflineNotice{
+		ID:        uuid.New().String(),
+		UserID:    message.RecipientID,
+		MessageID: message.ID,
+		TargetID:  message.TargetID,
+		Payload:   string(payload),
+		Timestamp: message.Timestamp,
+		CreatedAt: time.Now(),
+	}).Error
+}
+
+// getOfflineMessages 获取用户的离线消息（含群消息的离线投递记录和离线通知），按时间先后排列
+func getOfflineMessages(userID string) ([]*protocol.Message, error) {
+	var messages []*protocol.Message
+	db := database.GetDB()
+
+	// 查询数据库获取离线消息
+	var dbMessages []model.Message
+	err := db.Where("recipient_id = ? AND status = ?", userID, constants.MessageStatusUnsent).
+		Order("timestamp asc").
+		Find(&dbMessages).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("查询离线消息失败: %w", err)
+	}
+
+	// 转换为协议消息
+	for _, msg := range dbMessages {
+		messages = append(messages, offlineMessage(msg, userID))
+	}
+
+	groupMessages, err := getGroupOfflineMessages(userID)
+	if err != nil {
+		return nil, err
+	}
+	notices, err := getOfflineNotices(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(groupMessages) > 0 || len(notices) > 0 {
+		messages = append(messages, groupMessages...)
+		messages = append(messages, notices...)
+		sort.SliceStable(messages, func(i, j int) bool {
+			return messages[i].Timestamp < messages[j].Timestamp
+		})
+	}
+
+	log.Printf("找到用户 %s 的 %d 条离线消息", userID, len(messages))
+	return messages, nil
+}
+
+// getGroupOfflineMessages 按离线投递记录获取群消息
+// 记录对应的消息已被删除（如自毁消息到期）时一并清理记录
+func getGroupOfflineMessages(userID string) ([]*protocol.Message, error) {
+	db := database.GetDB()
+
+	var messageIDs []string
+	if err := db.Model(&model.GroupOfflineMessage{}).
+		Where("user_id = ?", userID).
+		Pluck("message_id", &messageIDs).Error; err != nil {
+		return nil, fmt.Errorf("查询群消息离线投递记录失败: %w", err)
+	}
+	if len(messageIDs) == 0 {
+		return nil, nil
+	}
+
+	var dbMessages []model.Message
+	if err := db.Where("id IN ?", messageIDs).Order("timestamp asc").Find(&dbMessages).Error; err != nil {
+		return nil, fmt.Errorf("查询离线群消息失败: %w", err)
+	}
+
+	found := make(map[string]bool, len(dbMessages))
+	messages := make([]*protocol.Message, 0, len(dbMessages))
+	for _, msg := range dbMessages {
+		found[msg.ID] = true
+		message := offlineMessage(msg, userID)
+		message.GroupID = msg.RecipientID
+		message.IsGroup = true
+		messages = append(messages, message)
+	}
+
+	var missing []string
+	for _, id := range messageIDs {
+		if !found[id] {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		if err := db.Where("user_id = ? AND message_id IN ?", userID, missing).
+			Delete(&model.GroupOfflineMessage{}).Error; err != nil {
+			log.Printf("清理用户 %s 的失效离线投递记录失败: %v", userID, err)
+		}
+	}
+
+	return messages, nil
+}
+
+// getOfflineNotices 读取用户离线通知队列中的通知，无法解析的通知直接删除
+func getOfflineNotices(userID string) ([]*protocol.Message, error) {
+	db := database.GetDB()
+
+	var notices []model.OfflineNotice
+	if err := db.Where("user_id = ?", userID).
+		Order("timestamp asc, created_at asc").
+		Find(&notices).Error; err != nil {
+		return nil, fmt.Errorf("查询离线通知失败: %w", err)
+	}
+
+	var broken []string
+	messages := make([]*protocol.Message, 0, len(notices))
+	for _, notice := range notices {
+		var message protocol.Message
+		if err := json.Unmarshal([]byte(notice.Payload), &message); err != nil {
+			log.Printf("解析离线通知 %s 失败: %v", notice.ID, err)
+			broken = append(broken, notice.ID)
+			continue
+		}
+		message.RecipientID = userID
+		messages = append(messages, &message)
+	}
+
+	if len(broken) > 0 {
+		if err := db.Where("id IN ?", broken).Delete(&model.OfflineNotice{}).Error; err != nil {
+			log.Printf("清理用户 %s 的无效离线通知失败: %v", userID, err)
+		}
+	}
+
+	return messages, nil
+}
+
+// offlineMessage 将离线表中的消息转换为发给指定用户的协议消息
+func offlineMessage(msg model.Message, userID string) *protocol.Message {
+	message := &protocol.Message{
+		ID:             msg.ID,
+		ConversationID: msg.ConversationID,
+		SenderID:       msg.SenderID,
+		RecipientID:    userID,
+		Content:        msg.Content,
+		Type:           msg.ContentType,
+		Timestamp:      msg.Timestamp,
+		Status:         msg.Status,
+		Seq:            msg.Seq,
+		TargetID:       msg.TargetID,
+		Recalled:       msg.Recalled,
+		TTL:            msg.TTL,
+	}
+	if msg.ExpiresAt != nil {
+		message.ExpiresAt = msg.ExpiresAt.Unix()
+	}
+	if msg.ForwardFromID != "" || msg.ForwardConversationID != "" {
+		message.Forward = &protocol.ForwardInfo{
+			MessageID:      msg.ForwardFromID,
+			SenderID:       msg.ForwardSenderID,
+			ConversationID: msg.ForwardConversationID,
+		}
+	}
+	message.Record = protocol.DecodeChatRecord(msg.Record)
+	protocol.DecodeBody(message, msg.Body)
+	return message
+}
+
+// markOfflineMessagesAsSent 标记离线消息为已发送，并删除已补发的群消息离线投递记录和离线通知
+func markOfflineMessagesAsSent(userID string, messages []*protocol.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	var ids []string
+	for _, msg := range messages {
+		ids = append(ids, msg.ID)
+	}
+
+	db := database.GetDB()
+	if err := db.Where("user_id = ? AND message_id IN ?", userID, ids).
+		Delete(&model.GroupOfflineMessage{}).Error; err != nil {
+		return err
+	}
+	if err := db.Where("user_id = ? AND message_id IN ?", userID, ids).
+		Delete(&model.OfflineNotice{}).Error; err != nil {
+		return err
+	}
+
+	// 更新消息状态，已被客户端确认的消息不回退
+	return db.Model(&model.Message{}).
+		Where("id IN ? AND recipient_id = ? AND status = ?", ids, userID, constants.MessageStatusUnsent).
+		Update("status", constants.MessageStatusSent).Error
+}
+
+// deliverOfflineMessages 用户上线后投递离线消息
+func deliverOfflineMessages(userID string, send func(*protocol.Message) error) {
+	// 从数据库获取离线消息
+	offlineMessages, err := getOfflineMessages(userID)
+	if err != nil {
+		log.Printf("获取用户 %s 的离线消息失败: %v", userID, err)
+		return
+	}
+
+	if len(offlineMessages) == 0 {
+		log.Printf("用户 %s 没有离线消息", userID)
+		return
+	}
+
+	log.Printf("为用户 %s 发送 %d 条离线消息", userID, len(offlineMessages))
+
+	// 发送离线消息
+	for _, msg := range offlineMessages {
+		if err := send(msg); err != nil {
+			log.Printf("发送离线消息失败: %v", err)
+		}
+	}
+
+	// 标记消息为已发送
+	if err := markOfflineMessagesAsSent(userID, offlineMessages); err != nil {
+		log.Printf("标记离线消息为已发送失败: %v", err)
+	} else {
+		log.Printf("用户 %s 的 %d 条离线消息已标记为已发送", userID, len(offlineMessages))
+	}
+}
+
+// requeueUnacked 将连接中未被确认的消息重新存为离线消息
+func requeueUnacked(conn Connection) {
+	ackConn, ok := conn.(EnhancedConnection)
+	if !ok {
+		return
+	}
+
+	unacked := ackConn.DrainUnacked()
+	for _, msg := range unacked {
+		if err := storeOfflineMessage(msg); err != nil {
+			log.Printf("存储未确认消息 %s 失败: %v", msg.ID, err)
+		}
+	}
+
+	if len(unacked) > 0 {
+		log.Printf("用户 %s 的 %d 条未确认消息已转为离线消息", conn.GetUserID(), len(unacked))
+	}
+}
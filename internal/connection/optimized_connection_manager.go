@@ -23,6 +23,7 @@ type OptimizedConnectionManager struct {
 	messageQueueChan chan *protocol.Message
 	statusManager    *status.Manager
 	userRegistry     *UserConnectionRegistry // 用户连接路由表
+	groupMembers     *GroupMemberCache       // 群成员缓存
 	serverID         string                  // 当前服务器ID
 	serverAddr       string                  // 当前服务器地址
 	mutex            sync.RWMutex
@@ -55,6 +56,7 @@ func NewOptimizedConnectionManager(serverID, serverAddr string) *OptimizedConnec
 		messageQueueChan: make(chan *protocol.Message, 1000),
 		statusManager:    statusMgr,
 		userRegistry:     userRegistry,
+		groupMembers:     NewGroupMemberCache(redisClient, redisEnabled),
 		serverID:         serverID,
 		serverAddr:       serverAddr,
 		ctx:              ctx,
@@ -126,10 +128,11 @@ func (m *OptimizedConnectionManager) UnregisterConnection(userID string, connTyp
 	}
 	m.mutex.Unlock()
 
-	// 关闭连接
+	// 关闭连接，未确认的消息转为离线消息
 	for _, conn := range connsToClose {
 		if conn != nil {
 			_ = conn.Close()
+			requeueUnacked(conn)
 		}
 	}
 
@@ -164,6 +167,11 @@ func (m *OptimizedConnectionManager) UnregisterConnection(userID string, connTyp
 
 // SendMessage 发送消息（优化版 - 使用路由表）
 func (m *OptimizedConnectionManager) SendMessage(message *protocol.Message) error {
+	// 群消息走扇出路径
+	if needsGroupFanout(message) {
+		return m.SendGroupMessage(message)
+	}
+
 	// 检查是否是本地用户
 	if m.userRegistry.IsUserLocal(message.RecipientID) {
 		// 本地用户，直接放入处理队列
@@ -192,6 +200,75 @@ func (m *OptimizedConnectionManager) SendMessage(message *protocol.Message) erro
 	return m.sendToTargetServer(message, connInfo.ServerID)
 }
 
+// SendGroupMessage 群消息扇出
+// 按成员所在服务器分组，每个服务器只发布一次批量消息
+func (m *OptimizedConnectionManager) SendGroupMessage(message *protocol.Message) error {
+	groupID := groupIDOf(message)
+
+	recipients, err := groupRecipients(m.groupMembers, message)
+	if err != nil {
+		return err
+	}
+
+	if len(recipients) == 0 {
+		return nil
+	}
+
+	// 无Redis时只能投递本地用户，processMessage 对不在线的成员存为离线消息
+	if !m.redisEnabled {
+		for _, memberID := range recipients {
+			m.processMessage(groupMessageFor(message, groupID, memberID))
+		}
+		return nil
+	}
+
+	byServer, offline, err := m.userRegistry.GroupUsersByServer(recipients)
+	if err != nil {
+		log.Printf("查找群组 %s 成员所在服务器失败: %v", groupID, err)
+	}
+
+	for serverID, users := range byServer {
+		if serverID == m.serverID {
+			// 本地成员直接投递，不经过消息队列
+			for _, memberID := range users {
+				m.processMessage(groupMessageFor(message, groupID, memberID))
+			}
+			continue
+		}
+
+		m.sendGroupToTargetServer(message, serverID, users)
+	}
+
+	for _, memberID := range offline {
+		m.storeOfflineMessage(groupMessageFor(message, groupID, memberID))
+	}
+
+	log.Printf("[Optimized] 群组 %s 消息已扇出: %d 个服务器, %d 个离线成员", groupID, len(byServer), len(offline))
+	return nil
+}
+
+// sendGroupToTargetServer 将群消息批量发送到目标服务器
+func (m *OptimizedConnectionManager) sendGroupToTargetServer(message *protocol.Message, targetServerID string, recipients []string) {
+	channel := fmt.Sprintf("server_group_msg:%s", targetServerID)
+
+	if err := publishGroupEnvelope(m.ctx, m.redisClient, channel, message, recipients); err != nil {
+		log.Printf("发送群消息到服务器 %s 失败: %v", targetServerID, err)
+		// 发送失败，存储为离线消息
+		groupID := groupIDOf(message)
+		for _, memberID := range recipients {
+			m.storeOfflineMessage(groupMessageFor(message, groupID, memberID))
+		}
+		return
+	}
+
+	log.Printf("[Optimized] 群消息已批量路由到服务器 %s (%d 个接收者)", targetServerID, len(recipients))
+}
+
+// InvalidateGroupMembers 使群成员缓存失效
+func (m *OptimizedConnectionManager) InvalidateGroupMembers(groupID string) {
+	m.groupMembers.Invalidate(groupID)
+}
+
 // sendToTargetServer 发送消息到目标服务器
 func (m *OptimizedConnectionManager) sendToTargetServer(message *protocol.Message, targetServerID string) error {
 	// 使用专用的服务器间通信频道
@@ -226,8 +303,9 @@ func (m *OptimizedConnectionManager) processMessage(message *protocol.Message) {
 	m.mutex.RUnlock()
 
 	if !ok {
-		log.Printf("警告: 接收者 %s 不在本服务器上", recipientID)
-		// 这种情况不应该发生，因为路由表已经确保消息只发送到正确的服务器
+		// 接收者在路由期间下线，存储为离线消息
+		log.Printf("接收者 %s 不在本服务器上，存储为离线消息", recipientID)
+		m.storeOfflineMessage(message)
 		return
 	}
 
@@ -290,13 +368,19 @@ func (m *OptimizedConnectionManager) Run(ctx context.Context) {
 func (m *OptimizedConnectionManager) startServerMessageListener() {
 	// 订阅当前服务器的专用频道
 	channel := fmt.Sprintf("server_msg:%s", m.serverID)
-	pubsub := m.redisClient.Subscribe(m.ctx, channel)
+	groupChannel := fmt.Sprintf("server_group_msg:%s", m.serverID)
+	pubsub := m.redisClient.Subscribe(m.ctx, channel, groupChannel)
 	defer pubsub.Close()
 
-	log.Printf("[Optimized] 开始监听服务器频道: %s", channel)
+	log.Printf("[Optimized] 开始监听服务器频道: %s, %s", channel, groupChannel)
 
 	ch := pubsub.Channel()
 	for msg := range ch {
+		if msg.Channel == groupChannel {
+			m.handleGroupEnvelope(msg.Payload)
+			continue
+		}
+
 		var message protocol.Message
 		if err := json.Unmarshal([]byte(msg.Payload), &message); err != nil {
 			log.Printf("解析服务器间消息失败: %v", err)
@@ -313,26 +397,53 @@ func (m *OptimizedConnectionManager) startServerMessageListener() {
 	}
 }
 
-// 其他方法保持相同...
+// handleGroupEnvelope 处理其他服务器发来的群消息批量投递
+func (m *OptimizedConnectionManager) handleGroupEnvelope(payload string) {
+	var envelope GroupDeliveryEnvelope
+	if err := json.Unmarshal([]byte(payload), &envelope); err != nil || envelope.Message == nil {
+		log.Printf("解析群消息信封失败: %v", err)
+		return
+	}
+
+	groupID := groupIDOf(envelope.Message)
+	for _, memberID := range envelope.Recipients {
+		memberMsg := groupMessageFor(envelope.Message, groupID, memberID)
+		if m.userRegistry.IsUserLocal(memberID) {
+			m.processMessage(memberMsg)
+		} else {
+			// 用户已在路由期间下线
+			m.storeOfflineMessage(memberMsg)
+		}
+	}
+
+	log.Printf("[Optimized] 收到群组 %s 的批量消息: %d 个接收者", groupID, len(envelope.Recipients))
+}
+
+// sendOfflineMessages 发送离线消息
 func (m *OptimizedConnectionManager) sendOfflineMessages(userID string) {
-	// ... 同 redis_manager.go 中的实现
+	deliverOfflineMessages(userID, m.SendMessage)
 }
 
+// storeOfflineMessage 存储离线消息
+// 临时信号和控制帧（回执、错误、响应等）只对当时在线的连接有意义，接收者不在线时直接丢弃
 func (m *OptimizedConnectionManager) storeOfflineMessage(message *protocol.Message) error {
-	// ... 同 redis_manager.go 中的实现
-	return nil
+	if protocol.IsEphemeral(message.Type) || protocol.IsControl(message.Type) {
+		return nil
+	}
+	return storeOfflineMessage(message)
 }
 
+// GetOfflineMessages 获取离线消息
 func (m *OptimizedConnectionManager) GetOfflineMessages(userID string) ([]*protocol.Message, error) {
-	// ... 同 redis_manager.go 中的实现
-	return nil, nil
+	return getOfflineMessages(userID)
 }
 
+// MarkOfflineMessagesAsSent 标记离线消息为已发送
 func (m *OptimizedConnectionManager) MarkOfflineMessagesAsSent(userID string, messages []*protocol.Message) error {
-	// ... 同 redis_manager.go 中的实现
-	return nil
+	return markOfflineMessagesAsSent(userID, messages)
 }
 
+// Close 关闭连接管理器
 func (m *OptimizedConnectionManager) Close() error {
 	m.cancel()
 
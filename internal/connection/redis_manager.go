@@ -8,14 +8,11 @@ import (
 	"sync"
 	"time"
 
-	"cursorIM/internal/database"
-	"cursorIM/internal/model"
 	"cursorIM/internal/protocol"
 	"cursorIM/internal/redisclient"
 	"cursorIM/internal/status"
 
 	"github.com/go-redis/redis/v8"
-	"github.com/google/uuid"
 )
 
 // RedisConnectionManager 使用 Redis 实现的连接管理器
@@ -26,14 +23,17 @@ type RedisConnectionManager struct {
 	connectionsByType    map[string]map[string]Connection // 连接类型 -> 用户ID -> 连接 (保留最新连接引用)
 	messageQueueChan     chan *protocol.Message
 	connectionUpdateChan chan struct{}
-	statusManager        *status.Manager // 状态管理器
+	statusManager        *status.Manager         // 状态管理器
+	groupMembers         *GroupMemberCache       // 群成员缓存
+	userRegistry         *UserConnectionRegistry // 用户连接路由表，群消息按节点投递
+	serverID             string                  // 当前服务器ID
 	mutex                sync.RWMutex
 	ctx                  context.Context
 	cancel               context.CancelFunc
 }
 
 // NewRedisConnectionManager 创建新的 Redis 连接管理器
-func NewRedisConnectionManager() *RedisConnectionManager {
+func NewRedisConnectionManager(serverID string) *RedisConnectionManager {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	// 使用统一的Redis客户端
@@ -49,6 +49,12 @@ func NewRedisConnectionManager() *RedisConnectionManager {
 		log.Printf("[Redis] connection established successfully")
 	}
 
+	// 创建用户连接路由表
+	userRegistry := NewUserConnectionRegistry(redisClient, serverID, "")
+	if redisEnabled {
+		userRegistry.StartHeartbeat()
+	}
+
 	return &RedisConnectionManager{
 		redisClient:          redisClient,
 		redisEnabled:         redisEnabled,
@@ -57,6 +63,9 @@ func NewRedisConnectionManager() *RedisConnectionManager {
 		messageQueueChan:     make(chan *protocol.Message, 1000),
 		connectionUpdateChan: make(chan struct{}, 100),
 		statusManager:        statusMgr,
+		groupMembers:         NewGroupMemberCache(redisClient, redisEnabled),
+		userRegistry:         userRegistry,
+		serverID:             serverID,
 		ctx:                  ctx,
 		cancel:               cancel,
 	}
@@ -100,6 +109,11 @@ func (m *RedisConnectionManager) RegisterConnection(userID string, conn Connecti
 			return fmt.Errorf("序列化连接信息失败: %w", err)
 		}
 
+		// 注册到路由表
+		if err := m.userRegistry.RegisterUser(userID, connType); err != nil {
+			log.Printf("注册用户到路由表失败: %v", err)
+		}
+
 		// 存储到 Redis
 		key := fmt.Sprintf("conn:%s:%s", userID, connType)
 		err = m.redisClient.Set(m.ctx, key, connInfoBytes, 30*time.Minute).Err()
@@ -142,36 +156,7 @@ func (m *RedisConnectionManager) RegisterConnection(userID string, conn Connecti
 
 // sendOfflineMessages 发送离线消息
 func (m *RedisConnectionManager) sendOfflineMessages(userID string) {
-	// 从数据库获取离线消息
-	offlineMessages, err := m.GetOfflineMessages(userID)
-	if err != nil {
-		log.Printf("获取用户 %s 的离线消息失败: %v", userID, err)
-		return
-	}
-
-	if len(offlineMessages) == 0 {
-		log.Printf("用户 %s 没有离线消息", userID)
-		return
-	}
-
-	log.Printf("为用户 %s 发送 %d 条离线消息", userID, len(offlineMessages))
-
-	// 发送离线消息
-	for _, msg := range offlineMessages {
-		err := m.SendMessage(msg)
-		if err != nil {
-			log.Printf("发送离线消息失败: %v", err)
-			continue
-		}
-	}
-
-	// 标记消息为已发送
-	err = m.MarkOfflineMessagesAsSent(userID, offlineMessages)
-	if err != nil {
-		log.Printf("标记离线消息为已发送失败: %v", err)
-	} else {
-		log.Printf("用户 %s 的 %d 条离线消息已标记为已发送", userID, len(offlineMessages))
-	}
+	deliverOfflineMessages(userID, m.SendMessage)
 }
 
 // UnregisterConnection 注销一个连接
@@ -211,6 +196,7 @@ func (m *RedisConnectionManager) UnregisterConnection(userID string, connType st
 		if conn != nil {
 			// 忽略关闭错误，因为连接可能已经关闭
 			_ = conn.Close()
+			requeueUnacked(conn)
 		}
 	}
 
@@ -241,8 +227,13 @@ func (m *RedisConnectionManager) UnregisterConnection(userID string, connType st
 	hasOtherConns := len(m.connections[userID]) > 0
 	m.mutex.RUnlock()
 
-	// 如果没有其他连接，更新用户状态为离线
+	// 如果没有其他连接，从路由表注销并更新用户状态为离线
 	if !hasOtherConns {
+		if m.redisEnabled {
+			if err := m.userRegistry.UnregisterUser(userID); err != nil {
+				log.Printf("从路由表注销用户失败: %v", err)
+			}
+		}
 		if err := m.statusManager.UpdateUserStatus(userID, connType, false); err != nil {
 			log.Printf("更新用户 %s 的离线状态失败: %v", userID, err)
 		}
@@ -260,6 +251,11 @@ func (m *RedisConnectionManager) UnregisterConnection(userID string, connType st
 
 // SendMessage 发送消息
 func (m *RedisConnectionManager) SendMessage(message *protocol.Message) error {
+	// 群消息走扇出路径，不占用每个成员的队列位置
+	if needsGroupFanout(message) {
+		return m.sendGroupMessage(message)
+	}
+
 	// 将消息放入本地队列
 	select {
 	case m.messageQueueChan <- message:
@@ -412,47 +408,72 @@ func (m *RedisConnectionManager) processMessage(message *protocol.Message) {
 		}
 	}
 
-	// 处理群组消息
-	if message.IsGroup {
-		// TODO: 实现群聊消息转发
-		log.Printf("群组消息转发功能尚未实现")
-	}
 }
 
-// storeOfflineMessage 存储离线消息
-func (m *RedisConnectionManager) storeOfflineMessage(message *protocol.Message) error {
-	// 将消息标记为未发送状态
-	message.Status = "unsent"
+// sendGroupMessage 群消息扇出
+// 按路由表将成员按所在节点分组：本地成员直接投递，其他节点每个节点发布一次，离线成员存为离线消息
+func (m *RedisConnectionManager) sendGroupMessage(message *protocol.Message) error {
+	groupID := groupIDOf(message)
+
+	recipients, err := groupRecipients(m.groupMembers, message)
+	if err != nil {
+		return err
+	}
 
-	// 确保消息有唯一ID
-	if message.ID == "" {
-		message.ID = uuid.New().String()
+	if len(recipients) == 0 {
+		return nil
+	}
+
+	// 无Redis时只能投递本地用户，processMessage 对不在线的成员存为离线消息
+	if !m.redisEnabled {
+		for _, memberID := range recipients {
+			m.processMessage(groupMessageFor(message, groupID, memberID))
+		}
+		return nil
 	}
 
-	// 确保接收者ID不为空
-	if message.RecipientID == "" {
-		log.Printf("警告: 离线消息接收者ID为空，无法存储")
-		return fmt.Errorf("接收者ID不能为空")
+	byServer, offline, err := m.userRegistry.GroupUsersByServer(recipients)
+	if err != nil {
+		log.Printf("查找群组 %s 成员所在服务器失败: %v", groupID, err)
+	}
+
+	for serverID, users := range byServer {
+		if serverID == m.serverID {
+			for _, memberID := range users {
+				m.processMessage(groupMessageFor(message, groupID, memberID))
+			}
+			continue
+		}
+
+		channel := fmt.Sprintf("server_group_msg:%s", serverID)
+		if err := publishGroupEnvelope(m.ctx, m.redisClient, channel, message, users); err != nil {
+			log.Printf("发送群消息到服务器 %s 失败: %v", serverID, err)
+			offline = append(offline, users...)
+		}
 	}
 
-	// 保存到数据库
-	dbMessage := model.Message{
-		ID:             message.ID,
-		ConversationID: message.ConversationID,
-		SenderID:       message.SenderID,
-		RecipientID:    message.RecipientID,
-		Content:        message.Content,
-		ContentType:    message.Type,
-		Status:         message.Status,
-		Timestamp:      message.Timestamp,
-		CreatedAt:      time.Now(),
-		UpdatedAt:      time.Now(),
+	for _, memberID := range offline {
+		if err := m.storeOfflineMessage(groupMessageFor(message, groupID, memberID)); err != nil {
+			log.Printf("存储群组 %s 成员 %s 的离线消息失败: %v", groupID, memberID, err)
+		}
 	}
 
-	log.Printf("存储离线消息: ID=%s, 发送者=%s, 接收者=%s",
-		dbMessage.ID, dbMessage.SenderID, dbMessage.RecipientID)
+	log.Printf("群组 %s 消息已扇出: %d 个服务器, %d 个离线成员", groupID, len(byServer), len(offline))
+	return nil
+}
 
-	return database.GetDB().Create(&dbMessage).Error
+// InvalidateGroupMembers 使群成员缓存失效
+func (m *RedisConnectionManager) InvalidateGroupMembers(groupID string) {
+	m.groupMembers.Invalidate(groupID)
+}
+
+// storeOfflineMessage 存储离线消息
+// 临时信号和控制帧（回执、错误、响应等）只对当时在线的连接有意义，接收者不在线时直接丢弃
+func (m *RedisConnectionManager) storeOfflineMessage(message *protocol.Message) error {
+	if protocol.IsEphemeral(message.Type) || protocol.IsControl(message.Type) {
+		return nil
+	}
+	return storeOfflineMessage(message)
 }
 
 // checkUserOnline 检查用户是否在线（在任何服务器上）
@@ -494,13 +515,22 @@ func (m *RedisConnectionManager) Run(ctx context.Context) {
 
 // startRedisSubscription 启动 Redis 消息订阅
 func (m *RedisConnectionManager) startRedisSubscription() {
-	// 订阅所有用户的消息
+	// 订阅所有用户的消息，以及发往本节点的群消息
+	groupChannel := fmt.Sprintf("server_group_msg:%s", m.serverID)
 	pubsub := m.redisClient.PSubscribe(m.ctx, "message_to:*")
 	defer pubsub.Close()
+	if err := pubsub.Subscribe(m.ctx, groupChannel); err != nil {
+		log.Printf("订阅群消息频道 %s 失败: %v", groupChannel, err)
+	}
 
 	// 处理接收到的消息
 	ch := pubsub.Channel()
 	for msg := range ch {
+		if msg.Channel == groupChannel {
+			m.handleGroupEnvelope(msg.Payload)
+			continue
+		}
+
 		// 解析消息内容
 		var message protocol.Message
 		if err := json.Unmarshal([]byte(msg.Payload), &message); err != nil {
@@ -528,6 +558,23 @@ func (m *RedisConnectionManager) startRedisSubscription() {
 	}
 }
 
+// handleGroupEnvelope 处理其他节点发来的群消息批量投递
+// 在路由期间下线或投递失败的成员由 processMessage 存为离线消息
+func (m *RedisConnectionManager) handleGroupEnvelope(payload string) {
+	var envelope GroupDeliveryEnvelope
+	if err := json.Unmarshal([]byte(payload), &envelope); err != nil || envelope.Message == nil {
+		log.Printf("解析群消息信封失败: %v", err)
+		return
+	}
+
+	groupID := groupIDOf(envelope.Message)
+	for _, memberID := range envelope.Recipients {
+		m.processMessage(groupMessageFor(envelope.Message, groupID, memberID))
+	}
+
+	log.Printf("收到群组 %s 的批量消息: %d 个接收者", groupID, len(envelope.Recipients))
+}
+
 // updateConnectionHeartbeats 更新所有连接的心跳
 func (m *RedisConnectionManager) updateConnectionHeartbeats() {
 	if !m.redisEnabled {
@@ -554,6 +601,11 @@ func (m *RedisConnectionManager) updateConnectionHeartbeats() {
 func (m *RedisConnectionManager) Close() error {
 	m.cancel()
 
+	// 清理路由表
+	if m.redisEnabled {
+		m.userRegistry.CleanupServerUsers()
+	}
+
 	// 关闭所有连接
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
@@ -578,50 +630,10 @@ func (m *RedisConnectionManager) Close() error {
 
 // GetOfflineMessages 获取离线消息
 func (m *RedisConnectionManager) GetOfflineMessages(userID string) ([]*protocol.Message, error) {
-	var messages []*protocol.Message
-
-	// 查询数据库获取离线消息
-	var dbMessages []model.Message
-	err := database.GetDB().Where("recipient_id = ? AND status = ?", userID, "unsent").
-		Order("timestamp asc").
-		Find(&dbMessages).Error
-
-	if err != nil {
-		return nil, fmt.Errorf("查询离线消息失败: %w", err)
-	}
-
-	log.Printf("找到用户 %s 的 %d 条离线消息", userID, len(dbMessages))
-
-	// 转换为协议消息
-	for _, msg := range dbMessages {
-		messages = append(messages, &protocol.Message{
-			ID:             msg.ID,
-			ConversationID: msg.ConversationID,
-			SenderID:       msg.SenderID,
-			RecipientID:    userID,
-			Content:        msg.Content,
-			Type:           msg.ContentType,
-			Timestamp:      msg.Timestamp,
-			Status:         msg.Status,
-		})
-	}
-
-	return messages, nil
+	return getOfflineMessages(userID)
 }
 
 // MarkOfflineMessagesAsSent 标记离线消息为已发送
 func (m *RedisConnectionManager) MarkOfflineMessagesAsSent(userID string, messages []*protocol.Message) error {
-	if len(messages) == 0 {
-		return nil
-	}
-
-	var ids []string
-	for _, msg := range messages {
-		ids = append(ids, msg.ID)
-	}
-
-	// 更新消息状态
-	return database.GetDB().Model(&model.Message{}).
-		Where("id IN ?", ids).
-		Update("status", "sent").Error
+	return markOfflineMessagesAsSent(userID, messages)
 }
@@ -134,6 +134,57 @@ func (r *UserConnectionRegistry) FindUserServer(userID string) (*UserConnectionI
 	return &connInfo, nil
 }
 
+// GroupUsersByServer 批量查找用户所在的服务器
+// 返回 服务器ID -> 用户ID列表 的映射，以及不在线的用户
+func (r *UserConnectionRegistry) GroupUsersByServer(userIDs []string) (map[string][]string, []string, error) {
+	byServer := make(map[string][]string)
+	var offline []string
+	var remote []string
+
+	// 首先筛出本地用户
+	r.mutex.RLock()
+	for _, userID := range userIDs {
+		if r.localUsers[userID] {
+			byServer[r.serverID] = append(byServer[r.serverID], userID)
+		} else {
+			remote = append(remote, userID)
+		}
+	}
+	r.mutex.RUnlock()
+
+	if len(remote) == 0 {
+		return byServer, offline, nil
+	}
+
+	// 一次 MGET 查询所有非本地用户的连接信息
+	keys := make([]string, len(remote))
+	for i, userID := range remote {
+		keys[i] = fmt.Sprintf("user_registry:%s", userID)
+	}
+
+	values, err := r.redisClient.MGet(r.ctx, keys...).Result()
+	if err != nil {
+		return byServer, remote, fmt.Errorf("批量查询用户连接信息失败: %w", err)
+	}
+
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			offline = append(offline, remote[i])
+			continue
+		}
+
+		var connInfo UserConnectionInfo
+		if err := json.Unmarshal([]byte(data), &connInfo); err != nil || connInfo.ServerID == "" {
+			offline = append(offline, remote[i])
+			continue
+		}
+		byServer[connInfo.ServerID] = append(byServer[connInfo.ServerID], remote[i])
+	}
+
+	return byServer, offline, nil
+}
+
 // IsUserLocal 检查用户是否在本地连接
 func (r *UserConnectionRegistry) IsUserLocal(userID string) bool {
 	r.mutex.RLock()
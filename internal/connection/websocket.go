@@ -129,24 +129,6 @@ func (c *WebSocketConnection) StartReading(msgHandler func(*protocol.Message)) {
 			message.Timestamp = time.Now().Unix()
 		}
 
-		// 确保有会话ID
-		if message.ConversationID == "" && message.Type == "message" {
-			log.Printf("警告: 消息缺少会话ID，可能无法正确处理")
-			// 尝试使用接收者ID作为会话ID的情况下
-			if message.RecipientID != "" {
-				participantIDs := []string{c.userID, message.RecipientID}
-				// 确保ID排序是一致的
-				if c.userID > message.RecipientID {
-					participantIDs[0] = message.RecipientID
-					participantIDs[1] = c.userID
-				}
-				// 生成一个更短的临时会话ID (使用UUID)
-				tempConvID := uuid.New().String()
-				message.ConversationID = tempConvID
-				log.Printf("为消息生成临时会话ID: %s (用户: %s -> %s)", tempConvID, c.userID, message.RecipientID)
-			}
-		}
-
 		// 如果是ping消息，直接回复pong而不转发
 		if message.Type == "ping" {
 			pongMsg := &protocol.Message{
@@ -163,8 +145,8 @@ func (c *WebSocketConnection) StartReading(msgHandler func(*protocol.Message)) {
 			continue
 		}
 
-		// 确保消息有接收者ID
-		if message.RecipientID == "" && message.Type != "status" {
+		// 确保消息有接收者ID（送达确认只携带消息ID）
+		if message.RecipientID == "" && protocol.RequiresRecipient(message.Type) {
 			log.Printf("警告: 用户 %s 发送的消息没有接收者ID，无法处理", c.userID)
 			log.Printf("消息内容: %+v", message)
 
@@ -16,12 +16,68 @@ const (
 
 // 消息类型常量
 const (
-	MessageTypeText   = "text"
-	MessageTypeImage  = "image"
-	MessageTypeFile   = "file"
-	MessageTypePing   = "ping"
-	MessageTypePong   = "pong"
-	MessageTypeStatus = "status"
+	MessageTypeText     = "text"
+	MessageTypeImage    = "image"
+	MessageTypeFile     = "file"
+	MessageTypePing     = "ping"
+	MessageTypePong     = "pong"
+	MessageTypeStatus   = "status"
+	MessageTypeAck      = "ack" // 送达确认（客户端确认收到 / 服务端回执）
+	MessageTypeError    = "error"
+	MessageTypeSync     = "sync"     // 增量同步（客户端请求 / 服务端按会话返回）
+	MessageTypeResponse = "response" // 指令执行结果
+	MessageTypeRecall   = "recall"   // 撤回消息（客户端指令 / 服务端通知）
+	MessageTypeEdit     = "edit"     // 编辑消息（客户端指令 / 服务端更新事件）
+	MessageTypeReaction = "reaction" // 表情回应（客户端指令 / 服务端变更事件）
+	MessageTypeMention  = "mention"  // 被提及提醒（服务端事件，不受会话免打扰影响）
+	MessageTypeRead     = "read"     // 已读（客户端上报已读位置 / 服务端已读回执）
+	MessageTypePurge    = "purge"    // 清除本地消息（服务端通知，自毁消息到期后已删除）
+	MessageTypePin      = "pin"      // 置顶/取消置顶消息（服务端变更事件）
+
+	MessageTypeAnnouncement = "announcement" // 群公告发布/修改/删除（服务端变更事件）
+
+	MessageTypeChatRecord = "chat_record" // 合并转发的聊天记录卡片
+
+	// 结构化消息：消息体放在对应的字段中，content 为服务器生成的摘要
+	MessageTypeLocation = "location" // 位置
+	MessageTypeContact  = "contact"  // 名片
+	MessageTypeSticker  = "sticker"  // 表情包
+	MessageTypeLink     = "link"     // 链接卡片
+	MessageTypePoll     = "poll"     // 群投票
+
+	MessageTypeVote      = "vote"       // 投票（客户端指令 / 服务端实时计票事件）
+	MessageTypePollClose = "poll_close" // 结束投票（客户端指令 / 服务端最终结果事件）
+
+	MessageTypeSystem      = "system"       // 群系统消息（由服务器写入群聊时间线，如成员角色变更）
+	MessageTypeJoinRequest = "join_request" // 入群申请（服务端事件，推送给群主/管理员和申请人）
+	MessageTypeGroupEvent  = "group_event"  // 群组变更（服务端事件，成员和群资料变化时推送给相关成员）
+
+	// 临时信号：只投递给在线接收者，不保存、不进入离线队列
+	MessageTypeTyping    = "typing"    // 正在输入
+	MessageTypeRecording = "recording" // 正在录音
+)
+
+// 消息状态常量
+const (
+	MessageStatusUnsent    = "unsent"    // 待投递（离线消息）
+	MessageStatusSent      = "sent"      // 已发送
+	MessageStatusDelivered = "delivered" // 客户端已确认收到
+	MessageStatusRead      = "read"      // 已读
+)
+
+// 定时消息状态常量
+const (
+	ScheduledStatusPending  = "pending"  // 等待发送
+	ScheduledStatusSending  = "sending"  // 已被调度器认领，正在发送
+	ScheduledStatusSent     = "sent"     // 已发送
+	ScheduledStatusCanceled = "canceled" // 已取消
+	ScheduledStatusFailed   = "failed"   // 发送失败
+)
+
+// 自毁消息计时方式
+const (
+	RetentionModeSend = "send" // 从发送时开始计时
+	RetentionModeRead = "read" // 从首次被阅读时开始计时
 )
 
 // 会话类型常量
@@ -33,7 +89,42 @@ const (
 // 群组角色常量
 const (
 	GroupRoleMember = 0 // 普通成员
-	GroupRoleAdmin  = 1 // 管理员/群主
+	GroupRoleAdmin  = 1 // 管理员
+	GroupRoleOwner  = 2 // 群主
+)
+
+// 业务错误码（error 消息的 error_code）
+const (
+	ErrorCodeMemberMuted    = "member_muted"     // 发送者在群内被禁言
+	ErrorCodeGroupMuted     = "group_muted"      // 群组开启了全员禁言
+	ErrorCodeNotGroupMember = "not_group_member" // 发送者不是群成员
+)
+
+// 群组变更事件（group_event 消息的 event 字段）
+const (
+	GroupEventCreated            = "created"             // 创建群组
+	GroupEventMemberJoined       = "member_joined"       // 成员加入（邀请、申请通过或邀请链接）
+	GroupEventMemberLeft         = "member_left"         // 成员退出
+	GroupEventMemberRemoved      = "member_removed"      // 成员被移出
+	GroupEventRenamed            = "renamed"             // 修改群名称
+	GroupEventDescriptionChanged = "description_changed" // 修改群简介
+	GroupEventRoleChanged        = "role_changed"        // 成员角色变更
+	GroupEventOwnerChanged       = "owner_changed"       // 转让群主
+	GroupEventDissolved          = "dissolved"           // 解散群组
+)
+
+// 入群方式
+const (
+	GroupJoinOpen       = "open"        // 任何人都可以直接加入
+	GroupJoinApproval   = "approval"    // 申请后由群主/管理员审批
+	GroupJoinInviteOnly = "invite_only" // 只能由群主/管理员邀请或通过邀请链接加入
+)
+
+// 入群申请状态
+const (
+	JoinRequestPending  = "pending"  // 等待审批
+	JoinRequestApproved = "approved" // 已通过
+	JoinRequestRejected = "rejected" // 已拒绝
 )
 
 // 好友状态常量
@@ -50,11 +141,16 @@ const (
 
 // Redis键前缀
 const (
-	RedisKeyUserStatus      = "user:%s:status"
-	RedisKeyUserConnections = "user:%s:connections"
-	RedisKeyUserLastActive  = "user:%s:last_active"
-	RedisKeyOnlineUsers     = "online_users"
-	RedisKeyConnection      = "conn:%s:%s" // conn:userID:connectionType
+	RedisKeyUserStatus        = "user:%s:status"
+	RedisKeyUserConnections   = "user:%s:connections"
+	RedisKeyUserLastActive    = "user:%s:last_active"
+	RedisKeyOnlineUsers       = "online_users"
+	RedisKeyConnection        = "conn:%s:%s" // conn:userID:connectionType
+	RedisKeyGroupMembers      = "group:%s:members"
+	RedisKeyConversationSeq   = "conversation:%s:seq"
+	RedisKeyClientMessage     = "client_msg:%s:%s"      // client_msg:senderID:clientMsgID -> 服务端消息ID
+	RedisKeyScheduledLock     = "scheduled_msg:%s:lock" // 定时消息发送锁，值为持有锁的服务器ID
+	RedisKeyMessageReaperLock = "message_reaper:lock"   // 到期消息清理锁，同一时刻只有一个节点清理
 )
 
 // HTTP状态码
@@ -53,6 +53,34 @@ func InitDB() (*gorm.DB, error) {
 		return nil, err
 	}
 
+	// 删除旧版本离线存储写入消息表的通知（一次性迁移，成功后之后启动时跳过）
+	if err := model.RunMigrationOnce(db, "purge_legacy_control_messages", func() error {
+		return model.PurgeLegacyControlMessages(db)
+	}); err != nil {
+		log.Printf("删除旧通知消息失败: %v", err)
+	}
+
+	// 为旧消息补齐会话序列号（一次性迁移，成功后之后启动时跳过）
+	if err := model.RunMigrationOnce(db, "backfill_message_sequences", func() error {
+		return model.BackfillMessageSequences(db)
+	}); err != nil {
+		log.Printf("补齐消息序列号失败: %v", err)
+	}
+
+	// 为旧群组补齐群主角色（一次性迁移，成功后之后启动时跳过）
+	if err := model.RunMigrationOnce(db, "backfill_group_owners", func() error {
+		return model.BackfillGroupOwners(db)
+	}); err != nil {
+		log.Printf("补齐群主角色失败: %v", err)
+	}
+
+	// 为旧群组补齐群聊会话和参与者（一次性迁移，成功后之后启动时跳过）
+	if err := model.RunMigrationOnce(db, "backfill_group_conversations", func() error {
+		return model.BackfillGroupConversations(db)
+	}); err != nil {
+		log.Printf("补齐群聊会话失败: %v", err)
+	}
+
 	DB = db
 	return db, nil
 }
@@ -0,0 +1,291 @@
+package group
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"cursorIM/internal/constants"
+	"cursorIM/internal/model"
+	"cursorIM/internal/protocol"
+	"cursorIM/internal/protocol/pb"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 群简介和群公告的长度限制（字符数）
+const (
+	maxDescriptionLength  = 500
+	maxAnnouncementLength = 2000
+)
+
+// SetConnectionManager 设置连接管理器，用于向群成员推送公告变更事件
+func (s *GroupService) SetConnectionManager(manager interface{}) {
+	s.connManager = manager
+}
+
+// GetGroupInfo 获取群组信息（含群简介和成员列表），只有群成员可以查看
+func (s *GroupService) GetGroupInfo(ctx context.Context, groupID, userID string) (*pb.GroupInfo, error) {
+	var group model.Group
+	if err := s.db.First(&group, "id = ?", groupID).Error; err != nil {
+		return nil, errors.New("群组不存在")
+	}
+
+	var memberIDs []string
+	if err := s.db.Model(&model.GroupMember{}).
+		Where("group_id = ?", groupID).
+		Order("joined_at asc").
+		Pluck("user_id", &memberIDs).Error; err != nil {
+		return nil, err
+	}
+
+	isMember := false
+	for _, memberID := range memberIDs {
+		if memberID == userID {
+			isMember = true
+			break
+		}
+	}
+	if !isMember {
+		return nil, errors.New("您不是群成员")
+	}
+
+	return &pb.GroupInfo{
+		Id:          group.ID,
+		Name:        group.Name,
+		Description: group.Description,
+		OwnerId:     group.OwnerID,
+		MemberIds:   memberIDs,
+		CreatedAt:   group.CreatedAt.Unix(),
+		UpdatedAt:   group.UpdatedAt.Unix(),
+	}, nil
+}
+
+// UpdateGroupDescription 更新群简介（只有群主和管理员可以修改），修改后在群内发送系统消息和群组事件，
+// 简介未变化时直接返回成功
+func (s *GroupService) UpdateGroupDescription(ctx context.Context, groupID, userID, description string) error {
+	if utf8.RuneCountInString(description) > maxDescriptionLength {
+		return fmt.Errorf("群简介不能超过%d个字符", maxDescriptionLength)
+	}
+
+	if err := s.checkAdmin(groupID, userID); err != nil {
+		return err
+	}
+
+	result := s.db.Model(&model.Group{}).
+		Where("id = ? AND (description IS NULL OR description <> ?)", groupID, description).
+		Updates(map[string]interface{}{
+			"description": description,
+			"updated_at":  time.Now(),
+		})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+
+	content := fmt.Sprintf("%s 修改了群简介", s.displayName(userID))
+	if description == "" {
+		content = fmt.Sprintf("%s 清空了群简介", s.displayName(userID))
+	}
+	s.publishEvent(ctx, groupID, content, &protocol.GroupEvent{
+		Event:       constants.GroupEventDescriptionChanged,
+		OperatorID:  userID,
+		Description: description,
+	})
+	return nil
+}
+
+// ListAnnouncements 获取群公告列表，最新发布的在前，所有群成员都可以查看
+func (s *GroupService) ListAnnouncements(ctx context.Context, groupID, userID string) ([]model.GroupAnnouncement, error) {
+	if err := s.checkMember(groupID, userID); err != nil {
+		return nil, err
+	}
+
+	var announcements []model.GroupAnnouncement
+	err := s.db.Where("group_id = ?", groupID).
+		Order("created_at desc").
+		Find(&announcements).Error
+	return announcements, err
+}
+
+// CreateAnnouncement 发布群公告（只有群主和管理员可以发布），发布后推送给所有群成员
+func (s *GroupService) CreateAnnouncement(ctx context.Context, groupID, userID, content string) (*model.GroupAnnouncement, error) {
+	content, err := normalizeAnnouncement(content)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkAdmin(groupID, userID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	announcement := &model.GroupAnnouncement{
+		ID:        uuid.New().String(),
+		GroupID:   groupID,
+		Content:   content,
+		CreatedBy: userID,
+		UpdatedBy: userID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.db.Create(announcement).Error; err != nil {
+		return nil, err
+	}
+
+	log.Printf("用户 %s 在群组 %s 发布公告 %s", userID, groupID, announcement.ID)
+	s.notifyAnnouncement(announcement, userID, false)
+	return announcement, nil
+}
+
+// UpdateAnnouncement 修改群公告（只有群主和管理员可以修改）
+func (s *GroupService) UpdateAnnouncement(ctx context.Context, groupID, announcementID, userID, content string) (*model.GroupAnnouncement, error) {
+	content, err := normalizeAnnouncement(content)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkAdmin(groupID, userID); err != nil {
+		return nil, err
+	}
+
+	announcement, err := s.findAnnouncement(groupID, announcementID)
+	if err != nil {
+		return nil, err
+	}
+
+	announcement.Content = content
+	announcement.UpdatedBy = userID
+	announcement.UpdatedAt = time.Now()
+	if err := s.db.Model(&model.GroupAnnouncement{}).
+		Where("id = ?", announcement.ID).
+		Updates(map[string]interface{}{
+			"content":    announcement.Content,
+			"updated_by": announcement.UpdatedBy,
+			"updated_at": announcement.UpdatedAt,
+		}).Error; err != nil {
+		return nil, err
+	}
+
+	s.notifyAnnouncement(announcement, userID, false)
+	return announcement, nil
+}
+
+// DeleteAnnouncement 删除群公告（只有群主和管理员可以删除）
+func (s *GroupService) DeleteAnnouncement(ctx context.Context, groupID, announcementID, userID string) error {
+	if err := s.checkAdmin(groupID, userID); err != nil {
+		return err
+	}
+
+	announcement, err := s.findAnnouncement(groupID, announcementID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.db.Delete(announcement).Error; err != nil {
+		return err
+	}
+
+	log.Printf("用户 %s 删除了群组 %s 的公告 %s", userID, groupID, announcement.ID)
+	s.notifyAnnouncement(announcement, userID, true)
+	return nil
+}
+
+// findAnnouncement 查找群组中的公告
+func (s *GroupService) findAnnouncement(groupID, announcementID string) (*model.GroupAnnouncement, error) {
+	var announcement model.GroupAnnouncement
+	if err := s.db.Where("id = ? AND group_id = ?", announcementID, groupID).Take(&announcement).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("公告不存在")
+		}
+		return nil, err
+	}
+	return &announcement, nil
+}
+
+// normalizeAnnouncement 去掉公告首尾空白并校验长度
+func normalizeAnnouncement(content string) (string, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return "", errors.New("公告内容不能为空")
+	}
+	if utf8.RuneCountInString(content) > maxAnnouncementLength {
+		return "", fmt.Errorf("公告内容不能超过%d个字符", maxAnnouncementLength)
+	}
+	return content, nil
+}
+
+// checkMember 校验用户是群成员
+func (s *GroupService) checkMember(groupID, userID string) error {
+	var count int64
+	s.db.Model(&model.GroupMember{}).Where("group_id = ? AND user_id = ?", groupID, userID).Count(&count)
+	if count == 0 {
+		return errors.New("您不是群成员")
+	}
+	return nil
+}
+
+// checkAdmin 校验用户是群主或群管理员（群主的角色为 GroupRoleOwner）
+func (s *GroupService) checkAdmin(groupID, userID string) error {
+	var member model.GroupMember
+	if err := s.db.First(&member, "group_id = ? AND user_id = ?", groupID, userID).Error; err != nil {
+		return errors.New("您不是群成员")
+	}
+
+	if member.Role == constants.GroupRoleMember {
+		return errors.New("权限不足")
+	}
+	return nil
+}
+
+// notifyAnnouncement 向所有群成员推送公告变更事件
+// target_id 为公告ID，remove 表示公告已删除；推送失败只记录日志
+func (s *GroupService) notifyAnnouncement(announcement *model.GroupAnnouncement, operatorID string, remove bool) {
+	notice := &protocol.Message{
+		Type:           constants.MessageTypeAnnouncement,
+		SenderID:       operatorID,
+		TargetID:       announcement.ID,
+		ConversationID: announcement.GroupID,
+		GroupID:        announcement.GroupID,
+		IsGroup:        true,
+		Content:        announcement.Content,
+		Remove:         remove,
+		Timestamp:      time.Now().Unix(),
+	}
+	s.notifyMembers(announcement.GroupID, notice)
+}
+
+// notifyMembers 向群组的每个成员推送一份独立的通知副本
+func (s *GroupService) notifyMembers(groupID string, notice *protocol.Message) {
+	var memberIDs []string
+	if err := s.db.Model(&model.GroupMember{}).
+		Where("group_id = ?", groupID).
+		Pluck("user_id", &memberIDs).Error; err != nil {
+		log.Printf("获取群组 %s 成员失败: %v", groupID, err)
+		return
+	}
+	s.notifyUsers(notice, memberIDs)
+}
+
+// notifyUsers 向一组用户推送通知，每个用户一份独立的副本
+// 不在线的用户由连接管理器存入离线队列
+func (s *GroupService) notifyUsers(notice *protocol.Message, userIDs []string) {
+	cm, ok := s.connManager.(interface{ SendMessage(*protocol.Message) error })
+	if !ok {
+		log.Printf("群组 %s 的 %s 通知无法发送，连接管理器未设置或不支持SendMessage", notice.GroupID, notice.Type)
+		return
+	}
+
+	for _, userID := range userIDs {
+		userNotice := *notice
+		userNotice.ID = uuid.New().String()
+		userNotice.RecipientID = userID
+		if err := cm.SendMessage(&userNotice); err != nil {
+			log.Printf("向用户 %s 推送 %s 通知失败: %v", userID, notice.Type, err)
+		}
+	}
+}
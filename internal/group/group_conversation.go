@@ -0,0 +1,62 @@
+package group
+
+import (
+	"time"
+
+	"cursorIM/internal/constants"
+	"cursorIM/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 群组对应一个以群组ID作为会话ID的群聊会话，群成员即会话参与者。
+// 群组的创建、成员变更、改名和解散在同一事务中同步会话，会话列表、未读数和已读位置对群聊和单聊一致
+
+// createGroupConversation 创建群组对应的群聊会话
+func createGroupConversation(tx *gorm.DB, group *model.Group) error {
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.Conversation{
+		ID:        group.ID,
+		Name:      group.Name,
+		Type:      constants.ConversationTypeGroup,
+		IsGroup:   true,
+		LastTime:  group.CreatedAt,
+		CreatedAt: group.CreatedAt,
+		UpdatedAt: group.CreatedAt,
+	}).Error
+}
+
+// addParticipant 将群成员加入群聊会话，加入前的消息不计入未读
+func addParticipant(tx *gorm.DB, groupID, userID string) error {
+	now := time.Now()
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.Participant{
+		ID:             model.ParticipantID(groupID, userID),
+		ConversationID: groupID,
+		UserID:         userID,
+		JoinedAt:       now,
+		LastReadAt:     now,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}).Error
+}
+
+// removeParticipant 将离开群组的用户移出群聊会话，会话不再出现在其会话列表中
+func removeParticipant(tx *gorm.DB, groupID, userID string) error {
+	return tx.Where("conversation_id = ? AND user_id = ?", groupID, userID).Delete(&model.Participant{}).Error
+}
+
+// renameGroupConversation 同步群聊会话的名称
+func renameGroupConversation(tx *gorm.DB, groupID, name string) error {
+	return tx.Model(&model.Conversation{}).Where("id = ?", groupID).Updates(map[string]interface{}{
+		"name":       name,
+		"updated_at": time.Now(),
+	}).Error
+}
+
+// deleteGroupConversation 删除解散群组的群聊会话及其参与者，历史消息保留
+func deleteGroupConversation(tx *gorm.DB, groupID string) error {
+	if err := tx.Delete(&model.Participant{}, "conversation_id = ?", groupID).Error; err != nil {
+		return err
+	}
+	return tx.Delete(&model.Conversation{}, "id = ?", groupID).Error
+}
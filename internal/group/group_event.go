@@ -0,0 +1,53 @@
+package group
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"cursorIM/internal/constants"
+	"cursorIM/internal/model"
+	"cursorIM/internal/protocol"
+)
+
+// publishEvent 在群内发送系统消息，并向群成员推送结构化的群组变更事件
+// formerMemberIDs 为已经不在群内、但需要知道这次变更的用户（退出、被移出或群组解散前的成员），
+// 他们会单独收到系统消息和事件
+func (s *GroupService) publishEvent(ctx context.Context, groupID, content string, event *protocol.GroupEvent, formerMemberIDs ...string) {
+	if notice := s.sendSystemMessage(ctx, groupID, content); notice != nil {
+		for _, userID := range formerMemberIDs {
+			s.notifyUser(userID, notice)
+		}
+	}
+
+	var memberIDs []string
+	if err := s.db.Model(&model.GroupMember{}).
+		Where("group_id = ?", groupID).
+		Pluck("user_id", &memberIDs).Error; err != nil {
+		log.Printf("获取群组 %s 成员失败: %v", groupID, err)
+	}
+
+	senderID := event.OperatorID
+	if senderID == "" {
+		senderID = "server"
+	}
+	s.notifyUsers(&protocol.Message{
+		Type:           constants.MessageTypeGroupEvent,
+		SenderID:       senderID,
+		ConversationID: groupID,
+		GroupID:        groupID,
+		IsGroup:        true,
+		Content:        content,
+		GroupEvent:     event,
+		Timestamp:      time.Now().Unix(),
+	}, append(memberIDs, formerMemberIDs...))
+}
+
+// memberJoinedEvent 构造成员加入事件
+func memberJoinedEvent(operatorID string, memberIDs ...string) *protocol.GroupEvent {
+	return &protocol.GroupEvent{
+		Event:      constants.GroupEventMemberJoined,
+		OperatorID: operatorID,
+		MemberIDs:  memberIDs,
+	}
+}
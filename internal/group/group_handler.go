@@ -2,8 +2,10 @@ package group
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 )
 
 // CreateGroupRequest 创建群组请求
@@ -21,35 +23,238 @@ type UpdateGroupNameRequest struct {
 	Name string `json:"name" binding:"required"`
 }
 
+// UpdateGroupDescriptionRequest 更新群简介请求，空字符串表示清空
+type UpdateGroupDescriptionRequest struct {
+	Description string `json:"description"`
+}
+
+// TransferOwnershipRequest 转让群主请求
+type TransferOwnershipRequest struct {
+	UserID string `json:"userId" binding:"required"`
+}
+
+// MuteRequest 禁言请求，duration 为禁言时长（秒）；全员禁言时 0 表示直到手动关闭
+type MuteRequest struct {
+	Duration int64 `json:"duration"`
+}
+
+// JoinPolicyRequest 修改入群方式请求
+type JoinPolicyRequest struct {
+	Policy string `json:"policy" binding:"required"`
+}
+
+// JoinGroupRequest 主动入群请求，message 为申请附言
+type JoinGroupRequest struct {
+	Message string `json:"message"`
+}
+
+// CreateInviteRequest 创建邀请链接请求，expires_in 为有效期（秒，0 表示默认 7 天），max_uses 为 0 表示不限次数
+type CreateInviteRequest struct {
+	ExpiresIn int64 `json:"expires_in"`
+	MaxUses   int   `json:"max_uses"`
+}
+
+// AnnouncementRequest 发布/修改群公告请求
+type AnnouncementRequest struct {
+	Content string `json:"content" binding:"required"`
+}
+
 // CreateGroup 创建群组
-func CreateGroup(c *gin.Context) {
-	userID, exists := c.Get("userID")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+func CreateGroup(service *GroupService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+			return
+		}
+
+		var req CreateGroupRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		group, err := service.CreateGroup(c.Request.Context(), userID.(string), req.Name)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "群组创建成功",
+			"group":   group,
+		})
+	}
+}
+
+// InviteUser 邀请用户入群
+func InviteUser(service *GroupService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+			return
+		}
+
+		groupID := c.Param("groupId")
+		if groupID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "群组ID不能为空"})
+			return
+		}
+
+		var req InviteUserRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		err := service.InviteUser(c.Request.Context(), groupID, req.UserID, userID.(string))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "邀请成功"})
+	}
+}
+
+// ExitGroup 退出群组
+func ExitGroup(service *GroupService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+			return
+		}
+
+		groupID := c.Param("groupId")
+		if groupID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "群组ID不能为空"})
+			return
+		}
+
+		err := service.ExitGroup(c.Request.Context(), groupID, userID.(string))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "退出群组成功"})
+	}
+}
+
+// GetGroupMembers 获取群成员列表
+func GetGroupMembers(c *gin.Context) {
+	groupID := c.Param("groupId")
+	if groupID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "群组ID不能为空"})
 		return
 	}
 
-	var req CreateGroupRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	service := NewGroupService()
+	members, err := service.GetGroupMembers(c.Request.Context(), groupID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	roles, err := service.GetMemberRoles(c.Request.Context(), groupID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	muted, err := service.GetMutedMembers(c.Request.Context(), groupID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"members": members,
+		"roles":   roles,
+		"muted":   muted,
+	})
+}
+
+// GetUserGroups 获取用户所在的群组列表
+func GetUserGroups(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
 		return
 	}
 
 	service := NewGroupService()
-	group, err := service.CreateGroup(c.Request.Context(), userID.(string), req.Name)
+	groups, err := service.GetUserGroups(c.Request.Context(), userID.(string))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "群组创建成功",
-		"group":   group,
+		"groups": groups,
 	})
 }
 
-// InviteUser 邀请用户入群
-func InviteUser(c *gin.Context) {
+// UpdateGroupName 更新群名称
+func UpdateGroupName(service *GroupService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+			return
+		}
+
+		groupID := c.Param("groupId")
+		if groupID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "群组ID不能为空"})
+			return
+		}
+
+		var req UpdateGroupNameRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		err := service.UpdateGroupName(c.Request.Context(), groupID, userID.(string), req.Name)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "群名称更新成功"})
+	}
+}
+
+// DeleteGroup 解散群组
+func DeleteGroup(service *GroupService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+			return
+		}
+
+		groupID := c.Param("groupId")
+		if groupID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "群组ID不能为空"})
+			return
+		}
+
+		err := service.DeleteGroup(c.Request.Context(), groupID, userID.(string))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "群组解散成功"})
+	}
+}
+
+// GetGroupInfo 获取群组信息，支持 Accept: application/x-protobuf 返回 GroupInfo
+func GetGroupInfo(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
@@ -62,24 +267,54 @@ func InviteUser(c *gin.Context) {
 		return
 	}
 
-	var req InviteUserRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	service := NewGroupService()
+	info, err := service.GetGroupInfo(c.Request.Context(), groupID, userID.(string))
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	service := NewGroupService()
-	err := service.InviteUser(c.Request.Context(), groupID, req.UserID, userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if c.NegotiateFormat(binding.MIMEJSON, binding.MIMEPROTOBUF) == binding.MIMEPROTOBUF {
+		c.ProtoBuf(http.StatusOK, info)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "邀请成功"})
+	c.JSON(http.StatusOK, gin.H{"group": info})
 }
 
-// ExitGroup 退出群组
-func ExitGroup(c *gin.Context) {
+// UpdateGroupDescription 更新群简介
+func UpdateGroupDescription(service *GroupService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+			return
+		}
+
+		groupID := c.Param("groupId")
+		if groupID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "群组ID不能为空"})
+			return
+		}
+
+		var req UpdateGroupDescriptionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		err := service.UpdateGroupDescription(c.Request.Context(), groupID, userID.(string), req.Description)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "群简介更新成功"})
+	}
+}
+
+// ListAnnouncements 获取群公告列表
+func ListAnnouncements(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
@@ -93,17 +328,387 @@ func ExitGroup(c *gin.Context) {
 	}
 
 	service := NewGroupService()
-	err := service.ExitGroup(c.Request.Context(), groupID, userID.(string))
+	announcements, err := service.ListAnnouncements(c.Request.Context(), groupID, userID.(string))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "退出群组成功"})
+	c.JSON(http.StatusOK, gin.H{"announcements": announcements})
 }
 
-// GetGroupMembers 获取群成员列表
-func GetGroupMembers(c *gin.Context) {
+// CreateAnnouncement 发布群公告
+// 需要使用已设置连接管理器的群组服务，以便推送公告事件
+func CreateAnnouncement(service *GroupService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+			return
+		}
+
+		groupID := c.Param("groupId")
+		if groupID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "群组ID不能为空"})
+			return
+		}
+
+		var req AnnouncementRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		announcement, err := service.CreateAnnouncement(c.Request.Context(), groupID, userID.(string), req.Content)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":      "公告发布成功",
+			"announcement": announcement,
+		})
+	}
+}
+
+// UpdateAnnouncement 修改群公告
+func UpdateAnnouncement(service *GroupService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+			return
+		}
+
+		groupID := c.Param("groupId")
+		announcementID := c.Param("announcementId")
+		if groupID == "" || announcementID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "群组ID和公告ID不能为空"})
+			return
+		}
+
+		var req AnnouncementRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		announcement, err := service.UpdateAnnouncement(c.Request.Context(), groupID, announcementID, userID.(string), req.Content)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":      "公告修改成功",
+			"announcement": announcement,
+		})
+	}
+}
+
+// DeleteAnnouncement 删除群公告
+func DeleteAnnouncement(service *GroupService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+			return
+		}
+
+		groupID := c.Param("groupId")
+		announcementID := c.Param("announcementId")
+		if groupID == "" || announcementID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "群组ID和公告ID不能为空"})
+			return
+		}
+
+		err := service.DeleteAnnouncement(c.Request.Context(), groupID, announcementID, userID.(string))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "公告删除成功"})
+	}
+}
+
+// PromoteAdmin 设置群管理员
+// 需要使用已设置系统消息发送者的群组服务，以便在群内记录角色变更
+func PromoteAdmin(service *GroupService) gin.HandlerFunc {
+	return setAdminHandler(service, true)
+}
+
+// DemoteAdmin 取消群管理员
+func DemoteAdmin(service *GroupService) gin.HandlerFunc {
+	return setAdminHandler(service, false)
+}
+
+func setAdminHandler(service *GroupService, admin bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+			return
+		}
+
+		groupID := c.Param("groupId")
+		targetID := c.Param("userId")
+		if groupID == "" || targetID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "群组ID和用户ID不能为空"})
+			return
+		}
+
+		if err := service.SetAdmin(c.Request.Context(), groupID, userID.(string), targetID, admin); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		message := "已设为管理员"
+		if !admin {
+			message = "已取消管理员"
+		}
+		c.JSON(http.StatusOK, gin.H{"message": message})
+	}
+}
+
+// TransferOwnership 转让群主
+func TransferOwnership(service *GroupService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+			return
+		}
+
+		groupID := c.Param("groupId")
+		if groupID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "群组ID不能为空"})
+			return
+		}
+
+		var req TransferOwnershipRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := service.TransferOwnership(c.Request.Context(), groupID, userID.(string), req.UserID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "群主转让成功"})
+	}
+}
+
+// RemoveMember 将成员移出群组
+func RemoveMember(service *GroupService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+			return
+		}
+
+		groupID := c.Param("groupId")
+		targetID := c.Param("userId")
+		if groupID == "" || targetID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "群组ID和用户ID不能为空"})
+			return
+		}
+
+		if err := service.RemoveMember(c.Request.Context(), groupID, userID.(string), targetID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "已移出群组"})
+	}
+}
+
+// MuteMember 禁言群成员
+func MuteMember(service *GroupService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+			return
+		}
+
+		groupID := c.Param("groupId")
+		targetID := c.Param("userId")
+		if groupID == "" || targetID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "群组ID和用户ID不能为空"})
+			return
+		}
+
+		var req MuteRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		duration := time.Duration(req.Duration) * time.Second
+		if err := service.MuteMember(c.Request.Context(), groupID, userID.(string), targetID, duration); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "禁言成功"})
+	}
+}
+
+// UnmuteMember 解除群成员的禁言
+func UnmuteMember(service *GroupService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+			return
+		}
+
+		groupID := c.Param("groupId")
+		targetID := c.Param("userId")
+		if groupID == "" || targetID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "群组ID和用户ID不能为空"})
+			return
+		}
+
+		if err := service.UnmuteMember(c.Request.Context(), groupID, userID.(string), targetID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "已解除禁言"})
+	}
+}
+
+// MuteAll 开启全员禁言，请求体可选
+func MuteAll(service *GroupService) gin.HandlerFunc {
+	return muteAllHandler(service, true)
+}
+
+// UnmuteAll 关闭全员禁言
+func UnmuteAll(service *GroupService) gin.HandlerFunc {
+	return muteAllHandler(service, false)
+}
+
+func muteAllHandler(service *GroupService, mute bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+			return
+		}
+
+		groupID := c.Param("groupId")
+		if groupID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "群组ID不能为空"})
+			return
+		}
+
+		var req MuteRequest
+		if mute && c.Request.ContentLength > 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		duration := time.Duration(req.Duration) * time.Second
+		if err := service.SetMuteAll(c.Request.Context(), groupID, userID.(string), mute, duration); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		message := "已开启全员禁言"
+		if !mute {
+			message = "已关闭全员禁言"
+		}
+		c.JSON(http.StatusOK, gin.H{"message": message})
+	}
+}
+
+// SetJoinPolicy 修改入群方式
+func SetJoinPolicy(service *GroupService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+			return
+		}
+
+		groupID := c.Param("groupId")
+		if groupID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "群组ID不能为空"})
+			return
+		}
+
+		var req JoinPolicyRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := service.SetJoinPolicy(c.Request.Context(), groupID, userID.(string), req.Policy); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "入群方式已更新"})
+	}
+}
+
+// JoinGroup 申请加入群组，允许任何人加入的群组直接加入
+func JoinGroup(service *GroupService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+			return
+		}
+
+		groupID := c.Param("groupId")
+		if groupID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "群组ID不能为空"})
+			return
+		}
+
+		var req JoinGroupRequest
+		if c.Request.ContentLength > 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		request, err := service.JoinGroup(c.Request.Context(), groupID, userID.(string), req.Message)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if request == nil {
+			c.JSON(http.StatusOK, gin.H{"message": "已加入群组", "joined": true})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"message": "入群申请已提交，请等待审批",
+			"joined":  false,
+			"request": request,
+		})
+	}
+}
+
+// ListJoinRequests 获取待审批的入群申请
+func ListJoinRequests(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
 	groupID := c.Param("groupId")
 	if groupID == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "群组ID不能为空"})
@@ -111,39 +716,88 @@ func GetGroupMembers(c *gin.Context) {
 	}
 
 	service := NewGroupService()
-	members, err := service.GetGroupMembers(c.Request.Context(), groupID)
+	requests, err := service.ListJoinRequests(c.Request.Context(), groupID, userID.(string))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"members": members,
-	})
+	c.JSON(http.StatusOK, gin.H{"requests": requests})
 }
 
-// GetUserGroups 获取用户所在的群组列表
-func GetUserGroups(c *gin.Context) {
+// ApproveJoinRequest 通过入群申请
+func ApproveJoinRequest(service *GroupService) gin.HandlerFunc {
+	return joinRequestHandler(service, true)
+}
+
+// RejectJoinRequest 拒绝入群申请
+func RejectJoinRequest(service *GroupService) gin.HandlerFunc {
+	return joinRequestHandler(service, false)
+}
+
+func joinRequestHandler(service *GroupService, approve bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+			return
+		}
+
+		groupID := c.Param("groupId")
+		requestID := c.Param("requestId")
+		if groupID == "" || requestID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "群组ID和申请ID不能为空"})
+			return
+		}
+
+		request, err := service.HandleJoinRequest(c.Request.Context(), groupID, requestID, userID.(string), approve)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		message := "已通过入群申请"
+		if !approve {
+			message = "已拒绝入群申请"
+		}
+		c.JSON(http.StatusOK, gin.H{"message": message, "request": request})
+	}
+}
+
+// CreateInvite 创建邀请链接
+func CreateInvite(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
 		return
 	}
 
+	groupID := c.Param("groupId")
+	if groupID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "群组ID不能为空"})
+		return
+	}
+
+	var req CreateInviteRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
 	service := NewGroupService()
-	groups, err := service.GetUserGroups(c.Request.Context(), userID.(string))
+	invite, err := service.CreateInvite(c.Request.Context(), groupID, userID.(string), time.Duration(req.ExpiresIn)*time.Second, req.MaxUses)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"groups": groups,
-	})
+	c.JSON(http.StatusOK, gin.H{"invite": invite})
 }
 
-// UpdateGroupName 更新群名称
-func UpdateGroupName(c *gin.Context) {
+// ListInvites 获取有效的邀请链接
+func ListInvites(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
@@ -156,24 +810,18 @@ func UpdateGroupName(c *gin.Context) {
 		return
 	}
 
-	var req UpdateGroupNameRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
 	service := NewGroupService()
-	err := service.UpdateGroupName(c.Request.Context(), groupID, userID.(string), req.Name)
+	invites, err := service.ListInvites(c.Request.Context(), groupID, userID.(string))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "群名称更新成功"})
+	c.JSON(http.StatusOK, gin.H{"invites": invites})
 }
 
-// DeleteGroup 解散群组
-func DeleteGroup(c *gin.Context) {
+// RevokeInvite 撤销邀请链接
+func RevokeInvite(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
@@ -181,17 +829,60 @@ func DeleteGroup(c *gin.Context) {
 	}
 
 	groupID := c.Param("groupId")
-	if groupID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "群组ID不能为空"})
+	inviteID := c.Param("inviteId")
+	if groupID == "" || inviteID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "群组ID和邀请链接ID不能为空"})
+		return
+	}
+
+	service := NewGroupService()
+	if err := service.RevokeInvite(c.Request.Context(), groupID, inviteID, userID.(string)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "邀请链接已撤销"})
+}
+
+// PreviewInvite 查看邀请链接对应的群组
+func PreviewInvite(c *gin.Context) {
+	token := c.Param("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "邀请码不能为空"})
 		return
 	}
 
 	service := NewGroupService()
-	err := service.DeleteGroup(c.Request.Context(), groupID, userID.(string))
+	preview, err := service.PreviewInvite(c.Request.Context(), token)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "群组解散成功"})
+	c.JSON(http.StatusOK, gin.H{"group": preview})
+}
+
+// JoinByInvite 通过邀请链接加入群组
+func JoinByInvite(service *GroupService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+			return
+		}
+
+		token := c.Param("token")
+		if token == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "邀请码不能为空"})
+			return
+		}
+
+		groupID, err := service.JoinByInvite(c.Request.Context(), token, userID.(string))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "已加入群组", "group_id": groupID})
+	}
 }
@@ -0,0 +1,406 @@
+package group
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"cursorIM/internal/constants"
+	"cursorIM/internal/model"
+	"cursorIM/internal/protocol"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 入群申请和邀请链接的限制
+const (
+	maxJoinMessageLength = 200
+	defaultInviteTTL     = 7 * 24 * time.Hour
+	maxInviteTTL         = 30 * 24 * time.Hour
+)
+
+// joinPolicyNames 入群方式在系统消息中的名称
+var joinPolicyNames = map[string]string{
+	constants.GroupJoinOpen:       "允许任何人加入",
+	constants.GroupJoinApproval:   "需要审批",
+	constants.GroupJoinInviteOnly: "仅限邀请",
+}
+
+// InvitePreview 邀请链接对应的群组概要，非群成员打开链接时展示
+type InvitePreview struct {
+	GroupID     string `json:"group_id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	MemberCount int64  `json:"member_count"`
+	ExpiresAt   int64  `json:"expires_at"`
+}
+
+// SetJoinPolicy 修改入群方式（只有群主和管理员可以修改），修改后在群内发送系统消息
+func (s *GroupService) SetJoinPolicy(ctx context.Context, groupID, operatorID, policy string) error {
+	name, ok := joinPolicyNames[policy]
+	if !ok {
+		return errors.New("入群方式只能是 open、approval 或 invite_only")
+	}
+
+	if err := s.checkAdmin(groupID, operatorID); err != nil {
+		return err
+	}
+
+	result := s.db.Model(&model.Group{}).
+		Where("id = ? AND join_policy <> ?", groupID, policy).
+		Updates(map[string]interface{}{
+			"join_policy": policy,
+			"updated_at":  time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+
+	log.Printf("用户 %s 将群组 %s 的入群方式改为 %s", operatorID, groupID, policy)
+	s.sendSystemMessage(ctx, groupID, fmt.Sprintf("%s 将入群方式修改为「%s」", s.displayName(operatorID), name))
+	return nil
+}
+
+// JoinGroup 主动加入群组
+// 允许任何人加入的群组直接加入，返回的申请为 nil；需要审批的群组提交入群申请并实时推送给群主和管理员，
+// 已有待审批的申请时直接返回该申请；仅限邀请的群组不能主动加入
+func (s *GroupService) JoinGroup(ctx context.Context, groupID, userID, message string) (*model.GroupJoinRequest, error) {
+	message = strings.TrimSpace(message)
+	if utf8.RuneCountInString(message) > maxJoinMessageLength {
+		return nil, fmt.Errorf("申请附言不能超过%d个字符", maxJoinMessageLength)
+	}
+
+	var group model.Group
+	if err := s.db.First(&group, "id = ?", groupID).Error; err != nil {
+		return nil, errors.New("群组不存在")
+	}
+	if s.checkMember(groupID, userID) == nil {
+		return nil, errors.New("您已经是群成员")
+	}
+
+	switch group.JoinPolicy {
+	case constants.GroupJoinOpen:
+		if err := s.db.Transaction(func(tx *gorm.DB) error {
+			return addMember(tx, groupID, userID, userID)
+		}); err != nil {
+			return nil, err
+		}
+		s.invalidateMemberCache(groupID)
+
+		log.Printf("用户 %s 加入了群组 %s", userID, groupID)
+		s.publishEvent(ctx, groupID, fmt.Sprintf("%s 加入了群聊", s.displayName(userID)), memberJoinedEvent(userID, userID))
+		return nil, nil
+
+	case constants.GroupJoinApproval:
+		var pending model.GroupJoinRequest
+		if err := s.db.Where("group_id = ? AND user_id = ? AND status = ?", groupID, userID, constants.JoinRequestPending).
+			Limit(1).Find(&pending).Error; err != nil {
+			return nil, err
+		}
+		if pending.ID != "" {
+			return &pending, nil
+		}
+
+		request := &model.GroupJoinRequest{
+			ID:        uuid.New().String(),
+			GroupID:   groupID,
+			UserID:    userID,
+			Message:   message,
+			Status:    constants.JoinRequestPending,
+			CreatedAt: time.Now(),
+		}
+		if err := s.db.Create(request).Error; err != nil {
+			return nil, err
+		}
+
+		log.Printf("用户 %s 申请加入群组 %s", userID, groupID)
+		s.notifyAdmins(groupID, joinRequestNotice(request))
+		return request, nil
+
+	default:
+		return nil, errors.New("该群组仅支持邀请加入")
+	}
+}
+
+// ListJoinRequests 获取待审批的入群申请（只有群主和管理员可以查看），最早提交的在前
+func (s *GroupService) ListJoinRequests(ctx context.Context, groupID, userID string) ([]model.GroupJoinRequest, error) {
+	if err := s.checkAdmin(groupID, userID); err != nil {
+		return nil, err
+	}
+
+	var requests []model.GroupJoinRequest
+	err := s.db.Where("group_id = ? AND status = ?", groupID, constants.JoinRequestPending).
+		Order("created_at asc").
+		Find(&requests).Error
+	return requests, err
+}
+
+// HandleJoinRequest 通过或拒绝入群申请（只有群主和管理员可以审批）
+// 申请在事务中加锁审批，多个管理员同时处理时只有一个生效；结果推送给申请人和所有群主/管理员
+func (s *GroupService) HandleJoinRequest(ctx context.Context, groupID, requestID, operatorID string, approve bool) (*model.GroupJoinRequest, error) {
+	if err := s.checkAdmin(groupID, operatorID); err != nil {
+		return nil, err
+	}
+
+	status := constants.JoinRequestRejected
+	if approve {
+		status = constants.JoinRequestApproved
+	}
+
+	var request model.GroupJoinRequest
+	joined := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND group_id = ?", requestID, groupID).
+			Take(&request).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("入群申请不存在")
+			}
+			return err
+		}
+		if request.Status != constants.JoinRequestPending {
+			return errors.New("该申请已被处理")
+		}
+
+		if approve {
+			if _, err := findMember(tx, groupID, request.UserID); err != nil {
+				if err := addMember(tx, groupID, request.UserID, operatorID); err != nil {
+					return err
+				}
+				joined = true
+			}
+		}
+
+		now := time.Now()
+		request.Status = status
+		request.HandledBy = operatorID
+		request.HandledAt = &now
+		return tx.Model(&model.GroupJoinRequest{}).Where("id = ?", request.ID).Updates(map[string]interface{}{
+			"status":     request.Status,
+			"handled_by": request.HandledBy,
+			"handled_at": request.HandledAt,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("用户 %s 处理了 %s 加入群组 %s 的申请: %s", operatorID, request.UserID, groupID, status)
+	notice := joinRequestNotice(&request)
+	s.notifyAdmins(groupID, notice)
+	s.notifyUsers(notice, []string{request.UserID})
+
+	if joined {
+		s.invalidateMemberCache(groupID)
+		s.publishEvent(ctx, groupID, fmt.Sprintf("%s 同意 %s 加入了群聊", s.displayName(operatorID), s.displayName(request.UserID)),
+			memberJoinedEvent(operatorID, request.UserID))
+	}
+	return &request, nil
+}
+
+// CreateInvite 创建邀请链接（只有群主和管理员可以创建）
+// ttl 为 0 时默认 7 天有效，最长 30 天；maxUses 为 0 表示不限使用次数
+func (s *GroupService) CreateInvite(ctx context.Context, groupID, userID string, ttl time.Duration, maxUses int) (*model.GroupInvite, error) {
+	if ttl == 0 {
+		ttl = defaultInviteTTL
+	}
+	if ttl < time.Minute || ttl > maxInviteTTL {
+		return nil, errors.New("邀请链接有效期必须在1分钟到30天之间")
+	}
+	if maxUses < 0 {
+		return nil, errors.New("使用次数不能为负数")
+	}
+
+	if err := s.checkAdmin(groupID, userID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	invite := &model.GroupInvite{
+		ID:        uuid.New().String(),
+		GroupID:   groupID,
+		Token:     strings.ReplaceAll(uuid.New().String(), "-", ""),
+		CreatedBy: userID,
+		MaxUses:   maxUses,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}
+	if err := s.db.Create(invite).Error; err != nil {
+		return nil, err
+	}
+
+	log.Printf("用户 %s 创建了群组 %s 的邀请链接 %s", userID, groupID, invite.ID)
+	return invite, nil
+}
+
+// ListInvites 获取仍然有效的邀请链接（只有群主和管理员可以查看）
+func (s *GroupService) ListInvites(ctx context.Context, groupID, userID string) ([]model.GroupInvite, error) {
+	if err := s.checkAdmin(groupID, userID); err != nil {
+		return nil, err
+	}
+
+	var invites []model.GroupInvite
+	err := s.db.Where("group_id = ? AND expires_at > ?", groupID, time.Now()).
+		Where("max_uses = 0 OR use_count < max_uses").
+		Order("created_at desc").
+		Find(&invites).Error
+	return invites, err
+}
+
+// RevokeInvite 撤销邀请链接（只有群主和管理员可以撤销）
+func (s *GroupService) RevokeInvite(ctx context.Context, groupID, inviteID, userID string) error {
+	if err := s.checkAdmin(groupID, userID); err != nil {
+		return err
+	}
+
+	result := s.db.Where("id = ? AND group_id = ?", inviteID, groupID).Delete(&model.GroupInvite{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("邀请链接不存在")
+	}
+	return nil
+}
+
+// PreviewInvite 获取邀请链接对应的群组概要
+func (s *GroupService) PreviewInvite(ctx context.Context, token string) (*InvitePreview, error) {
+	var invite model.GroupInvite
+	if err := s.db.Where("token = ?", token).Take(&invite).Error; err != nil {
+		return nil, errors.New("邀请链接无效")
+	}
+	if err := checkInviteUsable(&invite); err != nil {
+		return nil, err
+	}
+
+	var group model.Group
+	if err := s.db.First(&group, "id = ?", invite.GroupID).Error; err != nil {
+		return nil, errors.New("群组不存在")
+	}
+
+	var memberCount int64
+	s.db.Model(&model.GroupMember{}).Where("group_id = ?", group.ID).Count(&memberCount)
+
+	return &InvitePreview{
+		GroupID:     group.ID,
+		Name:        group.Name,
+		Description: group.Description,
+		MemberCount: memberCount,
+		ExpiresAt:   invite.ExpiresAt.Unix(),
+	}, nil
+}
+
+// JoinByInvite 通过邀请链接加入群组，不受入群方式限制
+// 邀请链接在事务中加锁计数，并发使用时不会超过使用次数上限
+func (s *GroupService) JoinByInvite(ctx context.Context, token, userID string) (string, error) {
+	var invite model.GroupInvite
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token = ?", token).
+			Take(&invite).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("邀请链接无效")
+			}
+			return err
+		}
+		if err := checkInviteUsable(&invite); err != nil {
+			return err
+		}
+		if _, err := findMember(tx, invite.GroupID, userID); err == nil {
+			return errors.New("您已经是群成员")
+		}
+
+		if err := addMember(tx, invite.GroupID, userID, invite.CreatedBy); err != nil {
+			return err
+		}
+		return tx.Model(&model.GroupInvite{}).Where("id = ?", invite.ID).
+			Update("use_count", gorm.Expr("use_count + 1")).Error
+	})
+	if err != nil {
+		return "", err
+	}
+	s.invalidateMemberCache(invite.GroupID)
+
+	log.Printf("用户 %s 通过邀请链接 %s 加入了群组 %s", userID, invite.ID, invite.GroupID)
+	s.publishEvent(ctx, invite.GroupID, fmt.Sprintf("%s 通过 %s 分享的邀请链接加入了群聊", s.displayName(userID), s.displayName(invite.CreatedBy)),
+		memberJoinedEvent(userID, userID))
+	return invite.GroupID, nil
+}
+
+// checkInviteUsable 校验邀请链接未过期且未用完
+func checkInviteUsable(invite *model.GroupInvite) error {
+	if !invite.ExpiresAt.After(time.Now()) {
+		return errors.New("邀请链接已过期")
+	}
+	if invite.MaxUses > 0 && invite.UseCount >= invite.MaxUses {
+		return errors.New("邀请链接已达到使用次数上限")
+	}
+	return nil
+}
+
+// addMember 添加普通成员并加入群聊会话，同时把该用户待审批的入群申请一并标记为已通过
+// 调用方负责校验用户还不是群成员，并在事务提交后清除成员缓存
+func addMember(tx *gorm.DB, groupID, userID, operatorID string) error {
+	now := time.Now()
+	member := &model.GroupMember{
+		ID:       uuid.New().String(),
+		GroupID:  groupID,
+		UserID:   userID,
+		Role:     constants.GroupRoleMember,
+		JoinedAt: now,
+	}
+	if err := tx.Create(member).Error; err != nil {
+		return err
+	}
+	if err := addParticipant(tx, groupID, userID); err != nil {
+		return err
+	}
+
+	return tx.Model(&model.GroupJoinRequest{}).
+		Where("group_id = ? AND user_id = ? AND status = ?", groupID, userID, constants.JoinRequestPending).
+		Updates(map[string]interface{}{
+			"status":     constants.JoinRequestApproved,
+			"handled_by": operatorID,
+			"handled_at": now,
+		}).Error
+}
+
+// joinRequestNotice 构造入群申请事件，sender_id 为申请人，target_id 为申请ID，status 为申请状态
+func joinRequestNotice(request *model.GroupJoinRequest) *protocol.Message {
+	notice := &protocol.Message{
+		Type:           constants.MessageTypeJoinRequest,
+		SenderID:       request.UserID,
+		TargetID:       request.ID,
+		ConversationID: request.GroupID,
+		GroupID:        request.GroupID,
+		IsGroup:        true,
+		Content:        request.Message,
+		Status:         request.Status,
+		Timestamp:      time.Now().Unix(),
+	}
+	if request.HandledBy != "" {
+		notice.Metadata = map[string]string{"handled_by": request.HandledBy}
+	}
+	return notice
+}
+
+// notifyAdmins 向群主和所有管理员推送通知
+func (s *GroupService) notifyAdmins(groupID string, notice *protocol.Message) {
+	var adminIDs []string
+	if err := s.db.Model(&model.GroupMember{}).
+		Where("group_id = ? AND role IN ?", groupID, []int{constants.GroupRoleAdmin, constants.GroupRoleOwner}).
+		Pluck("user_id", &adminIDs).Error; err != nil {
+		log.Printf("获取群组 %s 的管理员失败: %v", groupID, err)
+		return
+	}
+	s.notifyUsers(notice, adminIDs)
+}
@@ -0,0 +1,285 @@
+package group
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"cursorIM/internal/constants"
+	"cursorIM/internal/model"
+	"cursorIM/internal/protocol"
+
+	"gorm.io/gorm"
+)
+
+// 禁言时长限制和到期检查间隔
+const (
+	minMuteDuration    = time.Minute
+	maxMuteDuration    = 30 * 24 * time.Hour
+	muteExpiryInterval = 10 * time.Second
+)
+
+// RemoveMember 将成员移出群组（群主可以移除管理员和成员，管理员只能移除成员）
+// 移除后在群内发送系统消息和群组事件，被移除的用户也会单独收到
+func (s *GroupService) RemoveMember(ctx context.Context, groupID, operatorID, targetID string) error {
+	if _, err := s.moderationTarget(groupID, operatorID, targetID); err != nil {
+		return err
+	}
+
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("group_id = ? AND user_id = ?", groupID, targetID).Delete(&model.GroupMember{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("该用户不是群成员")
+		}
+		return removeParticipant(tx, groupID, targetID)
+	}); err != nil {
+		return err
+	}
+	s.invalidateMemberCache(groupID)
+
+	log.Printf("用户 %s 将 %s 移出群组 %s", operatorID, targetID, groupID)
+	s.publishEvent(ctx, groupID, fmt.Sprintf("%s 将 %s 移出了群聊", s.displayName(operatorID), s.displayName(targetID)), &protocol.GroupEvent{
+		Event:      constants.GroupEventMemberRemoved,
+		OperatorID: operatorID,
+		MemberIDs:  []string{targetID},
+	}, targetID)
+	return nil
+}
+
+// MuteMember 禁言群成员一段时间（权限规则与移出成员相同），重复禁言以最后一次的时长为准
+func (s *GroupService) MuteMember(ctx context.Context, groupID, operatorID, targetID string, duration time.Duration) error {
+	if err := validateMuteDuration(duration); err != nil {
+		return err
+	}
+
+	target, err := s.moderationTarget(groupID, operatorID, targetID)
+	if err != nil {
+		return err
+	}
+
+	until := time.Now().Add(duration)
+	if err := s.db.Model(&model.GroupMember{}).Where("id = ?", target.ID).
+		Update("muted_until", until).Error; err != nil {
+		return err
+	}
+
+	log.Printf("用户 %s 将群组 %s 的成员 %s 禁言至 %s", operatorID, groupID, targetID, until.Format(time.RFC3339))
+	s.sendSystemMessage(ctx, groupID, fmt.Sprintf("%s 将 %s 禁言%s", s.displayName(operatorID), s.displayName(targetID), formatMuteDuration(duration)))
+	return nil
+}
+
+// UnmuteMember 解除群成员的禁言，成员未被禁言时直接返回成功
+func (s *GroupService) UnmuteMember(ctx context.Context, groupID, operatorID, targetID string) error {
+	target, err := s.moderationTarget(groupID, operatorID, targetID)
+	if err != nil {
+		return err
+	}
+
+	result := s.db.Model(&model.GroupMember{}).
+		Where("id = ? AND muted_until > ?", target.ID, time.Now()).
+		Update("muted_until", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+
+	log.Printf("用户 %s 解除了群组 %s 的成员 %s 的禁言", operatorID, groupID, targetID)
+	s.sendSystemMessage(ctx, groupID, fmt.Sprintf("%s 解除了 %s 的禁言", s.displayName(operatorID), s.displayName(targetID)))
+	return nil
+}
+
+// SetMuteAll 开启或关闭全员禁言（只有群主和管理员可以操作），开启后只有群主和管理员可以发言
+// duration 为 0 表示直到手动关闭
+func (s *GroupService) SetMuteAll(ctx context.Context, groupID, operatorID string, mute bool, duration time.Duration) error {
+	if mute && duration != 0 {
+		if err := validateMuteDuration(duration); err != nil {
+			return err
+		}
+	}
+
+	if err := s.checkAdmin(groupID, operatorID); err != nil {
+		return err
+	}
+
+	updates := map[string]interface{}{
+		"mute_all":       mute,
+		"mute_all_until": nil,
+		"updated_at":     time.Now(),
+	}
+	if mute && duration != 0 {
+		updates["mute_all_until"] = time.Now().Add(duration)
+	}
+
+	query := s.db.Model(&model.Group{}).Where("id = ?", groupID)
+	if !mute {
+		query = query.Where("mute_all = ?", true)
+	}
+	result := query.Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+
+	operator := s.displayName(operatorID)
+	switch {
+	case !mute:
+		log.Printf("用户 %s 关闭了群组 %s 的全员禁言", operatorID, groupID)
+		s.sendSystemMessage(ctx, groupID, fmt.Sprintf("%s 关闭了全员禁言", operator))
+	case duration == 0:
+		log.Printf("用户 %s 开启了群组 %s 的全员禁言", operatorID, groupID)
+		s.sendSystemMessage(ctx, groupID, fmt.Sprintf("%s 开启了全员禁言", operator))
+	default:
+		log.Printf("用户 %s 开启了群组 %s 的全员禁言，时长 %s", operatorID, groupID, duration)
+		s.sendSystemMessage(ctx, groupID, fmt.Sprintf("%s 开启了全员禁言%s", operator, formatMuteDuration(duration)))
+	}
+	return nil
+}
+
+// GetMutedMembers 获取群内正在被禁言的成员（用户ID -> 禁言结束时间，Unix 秒）
+func (s *GroupService) GetMutedMembers(ctx context.Context, groupID string) (map[string]int64, error) {
+	var members []model.GroupMember
+	if err := s.db.Where("group_id = ? AND muted_until > ?", groupID, time.Now()).Find(&members).Error; err != nil {
+		return nil, err
+	}
+
+	muted := make(map[string]int64, len(members))
+	for _, member := range members {
+		muted[member.UserID] = member.MutedUntil.Unix()
+	}
+	return muted, nil
+}
+
+// RunMuteExpiry 定期清理到期的禁言并在群内发送解除通知，直到 ctx 取消
+// 发言权限按结束时间实时判断，这里只负责清理状态和通知群成员
+func (s *GroupService) RunMuteExpiry(ctx context.Context) {
+	ticker := time.NewTicker(muteExpiryInterval)
+	defer ticker.Stop()
+
+	log.Println("禁言到期检查已启动")
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("禁言到期检查已停止")
+			return
+		case <-ticker.C:
+			s.expireMutes(ctx)
+		}
+	}
+}
+
+// expireMutes 解除所有已到期的成员禁言和全员禁言
+// 通过带旧值的条件更新认领，多个节点同时检查时每次到期只通知一次
+func (s *GroupService) expireMutes(ctx context.Context) {
+	now := time.Now()
+
+	var members []model.GroupMember
+	if err := s.db.Where("muted_until <= ?", now).Find(&members).Error; err != nil {
+		log.Printf("查询到期的禁言失败: %v", err)
+		return
+	}
+	for _, member := range members {
+		result := s.db.Model(&model.GroupMember{}).
+			Where("id = ? AND muted_until = ?", member.ID, member.MutedUntil).
+			Update("muted_until", nil)
+		if result.Error != nil {
+			log.Printf("解除群组 %s 成员 %s 的禁言失败: %v", member.GroupID, member.UserID, result.Error)
+			continue
+		}
+		if result.RowsAffected > 0 {
+			s.sendSystemMessage(ctx, member.GroupID, fmt.Sprintf("%s 的禁言已到期解除", s.displayName(member.UserID)))
+		}
+	}
+
+	var groups []model.Group
+	if err := s.db.Where("mute_all = ? AND mute_all_until <= ?", true, now).Find(&groups).Error; err != nil {
+		log.Printf("查询到期的全员禁言失败: %v", err)
+		return
+	}
+	for _, group := range groups {
+		result := s.db.Model(&model.Group{}).
+			Where("id = ? AND mute_all = ? AND mute_all_until = ?", group.ID, true, group.MuteAllUntil).
+			Updates(map[string]interface{}{
+				"mute_all":       false,
+				"mute_all_until": nil,
+				"updated_at":     now,
+			})
+		if result.Error != nil {
+			log.Printf("解除群组 %s 的全员禁言失败: %v", group.ID, result.Error)
+			continue
+		}
+		if result.RowsAffected > 0 {
+			s.sendSystemMessage(ctx, group.ID, "全员禁言已到期解除")
+		}
+	}
+}
+
+// moderationTarget 校验操作者可以管理目标成员：操作者是群主或管理员，且角色高于目标成员
+func (s *GroupService) moderationTarget(groupID, operatorID, targetID string) (*model.GroupMember, error) {
+	if targetID == "" {
+		return nil, errors.New("用户ID不能为空")
+	}
+	if operatorID == targetID {
+		return nil, errors.New("不能对自己执行此操作")
+	}
+
+	operator, err := findMember(s.db, groupID, operatorID)
+	if err != nil {
+		return nil, errors.New("您不是群成员")
+	}
+	if operator.Role == constants.GroupRoleMember {
+		return nil, errors.New("权限不足")
+	}
+
+	target, err := findMember(s.db, groupID, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if target.Role >= operator.Role {
+		return nil, errors.New("权限不足，只能管理角色低于自己的成员")
+	}
+	return target, nil
+}
+
+// validateMuteDuration 校验禁言时长
+func validateMuteDuration(duration time.Duration) error {
+	if duration < minMuteDuration || duration > maxMuteDuration {
+		return errors.New("禁言时长必须在1分钟到30天之间")
+	}
+	return nil
+}
+
+// formatMuteDuration 将禁言时长格式化为系统消息中的文字，如“2小时”“30分钟”
+func formatMuteDuration(duration time.Duration) string {
+	switch {
+	case duration%(24*time.Hour) == 0:
+		return fmt.Sprintf("%d天", duration/(24*time.Hour))
+	case duration%time.Hour == 0:
+		return fmt.Sprintf("%d小时", duration/time.Hour)
+	default:
+		return fmt.Sprintf("%d分钟", (duration+time.Minute-1)/time.Minute)
+	}
+}
+
+// notifyUser 单独向一个用户推送通知（如被移出群组的用户收不到群内消息）
+func (s *GroupService) notifyUser(userID string, notice *protocol.Message) {
+	cm, ok := s.connManager.(interface{ SendMessage(*protocol.Message) error })
+	if !ok {
+		log.Printf("用户 %s 的 %s 通知无法发送，连接管理器未设置或不支持SendMessage", userID, notice.Type)
+		return
+	}
+
+	userNotice := *notice
+	userNotice.RecipientID = userID
+	if err := cm.SendMessage(&userNotice); err != nil {
+		log.Printf("向用户 %s 推送 %s 通知失败: %v", userID, notice.Type, err)
+	}
+}
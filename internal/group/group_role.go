@@ -0,0 +1,206 @@
+package group
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"cursorIM/internal/constants"
+	"cursorIM/internal/model"
+	"cursorIM/internal/protocol"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SystemMessenger 向群聊时间线写入系统消息，由聊天服务实现
+type SystemMessenger interface {
+	SendSystemMessage(ctx context.Context, groupID, content string) (*protocol.Message, error)
+}
+
+// SetSystemMessenger 设置系统消息发送者，用于在群内记录成员角色变更等事件
+func (s *GroupService) SetSystemMessenger(messenger SystemMessenger) {
+	s.messenger = messenger
+}
+
+// GetMemberRoles 获取群成员的角色（用户ID -> 角色）
+func (s *GroupService) GetMemberRoles(ctx context.Context, groupID string) (map[string]int, error) {
+	var members []model.GroupMember
+	if err := s.db.Where("group_id = ?", groupID).Find(&members).Error; err != nil {
+		return nil, err
+	}
+
+	roles := make(map[string]int, len(members))
+	for _, member := range members {
+		roles[member.UserID] = member.Role
+	}
+	return roles, nil
+}
+
+// SetAdmin 设置或取消群管理员（只有群主可以操作），变更后在群内发送系统消息
+// 目标用户的角色已是期望值时直接返回成功，不重复发送系统消息
+func (s *GroupService) SetAdmin(ctx context.Context, groupID, operatorID, targetID string, admin bool) error {
+	if operatorID == targetID {
+		return errors.New("不能修改自己的角色")
+	}
+
+	role := constants.GroupRoleMember
+	if admin {
+		role = constants.GroupRoleAdmin
+	}
+
+	changed := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockGroupAsOwner(tx, groupID, operatorID); err != nil {
+			return err
+		}
+
+		target, err := findMember(tx, groupID, targetID)
+		if err != nil {
+			return err
+		}
+		if target.Role == constants.GroupRoleOwner {
+			return errors.New("不能修改群主的角色")
+		}
+		if target.Role == role {
+			return nil
+		}
+
+		changed = true
+		return tx.Model(&model.GroupMember{}).Where("id = ?", target.ID).Update("role", role).Error
+	})
+	if err != nil || !changed {
+		return err
+	}
+
+	content := fmt.Sprintf("%s 将 %s 设为管理员", s.displayName(operatorID), s.displayName(targetID))
+	if !admin {
+		content = fmt.Sprintf("%s 取消了 %s 的管理员身份", s.displayName(operatorID), s.displayName(targetID))
+	}
+	log.Printf("用户 %s 将群组 %s 的成员 %s 的角色改为 %d", operatorID, groupID, targetID, role)
+	s.publishEvent(ctx, groupID, content, &protocol.GroupEvent{
+		Event:      constants.GroupEventRoleChanged,
+		OperatorID: operatorID,
+		MemberIDs:  []string{targetID},
+		Role:       role,
+	})
+	return nil
+}
+
+// TransferOwnership 转让群主（只有群主可以操作）
+// 在同一事务中更新群组的群主、新群主的角色，原群主降为管理员，变更后在群内发送系统消息
+func (s *GroupService) TransferOwnership(ctx context.Context, groupID, ownerID, newOwnerID string) error {
+	if newOwnerID == "" {
+		return errors.New("新群主ID不能为空")
+	}
+	if ownerID == newOwnerID {
+		return errors.New("您已经是群主")
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		owner, err := lockGroupAsOwner(tx, groupID, ownerID)
+		if err != nil {
+			return err
+		}
+
+		target, err := findMember(tx, groupID, newOwnerID)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Model(&model.Group{}).Where("id = ?", groupID).Updates(map[string]interface{}{
+			"owner_id":   newOwnerID,
+			"updated_at": time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.GroupMember{}).Where("id = ?", target.ID).
+			Update("role", constants.GroupRoleOwner).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.GroupMember{}).Where("id = ?", owner.ID).
+			Update("role", constants.GroupRoleAdmin).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("用户 %s 将群组 %s 的群主转让给 %s", ownerID, groupID, newOwnerID)
+	s.publishEvent(ctx, groupID, fmt.Sprintf("%s 将群主转让给了 %s", s.displayName(ownerID), s.displayName(newOwnerID)), &protocol.GroupEvent{
+		Event:      constants.GroupEventOwnerChanged,
+		OperatorID: ownerID,
+		MemberIDs:  []string{newOwnerID},
+		Role:       constants.GroupRoleOwner,
+	})
+	return nil
+}
+
+// lockGroupAsOwner 锁定群组记录并校验操作者是群主，返回群主的成员记录
+// 群主变更和角色变更都先锁定群组，避免与转让群主并发执行
+func lockGroupAsOwner(tx *gorm.DB, groupID, userID string) (*model.GroupMember, error) {
+	var group model.Group
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&group, "id = ?", groupID).Error; err != nil {
+		return nil, errors.New("群组不存在")
+	}
+
+	member, err := findMember(tx, groupID, userID)
+	if err != nil {
+		return nil, errors.New("您不是群成员")
+	}
+	if group.OwnerID != userID {
+		return nil, errors.New("只有群主可以执行此操作")
+	}
+	return member, nil
+}
+
+// findMember 查找群成员记录
+func findMember(db *gorm.DB, groupID, userID string) (*model.GroupMember, error) {
+	var member model.GroupMember
+	if err := db.Where("group_id = ? AND user_id = ?", groupID, userID).Take(&member).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("该用户不是群成员")
+		}
+		return nil, err
+	}
+	return &member, nil
+}
+
+// checkOwner 校验用户是群主
+func (s *GroupService) checkOwner(groupID, userID string) (*model.Group, error) {
+	var group model.Group
+	if err := s.db.First(&group, "id = ?", groupID).Error; err != nil {
+		return nil, errors.New("群组不存在")
+	}
+	if group.OwnerID != userID {
+		return nil, errors.New("只有群主可以执行此操作")
+	}
+	return &group, nil
+}
+
+// displayName 获取用户在系统消息中显示的名称，优先使用昵称
+func (s *GroupService) displayName(userID string) string {
+	var user model.User
+	if err := s.db.Select("id, username, nickname").First(&user, "id = ?", userID).Error; err != nil {
+		return userID
+	}
+	if user.Nickname != "" {
+		return user.Nickname
+	}
+	return user.Username
+}
+
+// sendSystemMessage 在群内发送系统消息，发送失败只记录日志并返回 nil
+func (s *GroupService) sendSystemMessage(ctx context.Context, groupID, content string) *protocol.Message {
+	if s.messenger == nil {
+		log.Printf("群组 %s 的系统消息无法发送，系统消息发送者未设置: %s", groupID, content)
+		return nil
+	}
+	message, err := s.messenger.SendSystemMessage(ctx, groupID, content)
+	if err != nil {
+		log.Printf("发送群组 %s 的系统消息失败: %v", groupID, err)
+		return nil
+	}
+	return message
+}
@@ -2,9 +2,15 @@ package group
 
 import (
 	"context"
+	"cursorIM/internal/constants"
 	"cursorIM/internal/database"
 	"cursorIM/internal/model"
+	"cursorIM/internal/protocol"
+	"cursorIM/internal/redisclient"
 	"errors"
+	"fmt"
+	"log"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,7 +18,9 @@ import (
 )
 
 type GroupService struct {
-	db *gorm.DB
+	db          *gorm.DB
+	connManager interface{}
+	messenger   SystemMessenger
 }
 
 func NewGroupService() *GroupService {
@@ -21,14 +29,20 @@ func NewGroupService() *GroupService {
 	}
 }
 
-// CreateGroup 创建群组
+// CreateGroup 创建群组，创建后在群内发送系统消息和群组事件
 func (s *GroupService) CreateGroup(ctx context.Context, ownerID, name string) (*model.Group, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("群名称不能为空")
+	}
+
 	group := &model.Group{
-		ID:        uuid.New().String(),
-		Name:      name,
-		OwnerID:   ownerID,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		ID:         uuid.New().String(),
+		Name:       name,
+		OwnerID:    ownerID,
+		JoinPolicy: constants.GroupJoinInviteOnly,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
 	}
 
 	tx := s.db.Begin()
@@ -47,7 +61,7 @@ func (s *GroupService) CreateGroup(ctx context.Context, ownerID, name string) (*
 		ID:       uuid.New().String(),
 		GroupID:  group.ID,
 		UserID:   ownerID,
-		Role:     1, // 管理员角色
+		Role:     constants.GroupRoleOwner,
 		JoinedAt: time.Now(),
 	}
 
@@ -56,11 +70,30 @@ func (s *GroupService) CreateGroup(ctx context.Context, ownerID, name string) (*
 		return nil, err
 	}
 
-	tx.Commit()
+	// 创建群聊会话，群主为第一个参与者
+	if err := createGroupConversation(tx, group); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := addParticipant(tx, group.ID, ownerID); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	s.publishEvent(ctx, group.ID, fmt.Sprintf("%s 创建了群聊「%s」", s.displayName(ownerID), name), &protocol.GroupEvent{
+		Event:      constants.GroupEventCreated,
+		OperatorID: ownerID,
+		MemberIDs:  []string{ownerID},
+		Name:       name,
+	})
 	return group, nil
 }
 
-// InviteUser 邀请用户入群
+// InviteUser 邀请用户入群，入群后在群内发送系统消息和群组事件
 func (s *GroupService) InviteUser(ctx context.Context, groupID, userID, inviterID string) error {
 	// 检查群组是否存在
 	var group model.Group
@@ -69,13 +102,8 @@ func (s *GroupService) InviteUser(ctx context.Context, groupID, userID, inviterI
 	}
 
 	// 检查邀请者权限（只有群主和管理员可以邀请）
-	var inviterMember model.GroupMember
-	if err := s.db.First(&inviterMember, "group_id = ? AND user_id = ?", groupID, inviterID).Error; err != nil {
-		return errors.New("您不是群成员")
-	}
-
-	if inviterMember.Role == 0 {
-		return errors.New("权限不足")
+	if err := s.checkAdmin(groupID, inviterID); err != nil {
+		return err
 	}
 
 	// 检查用户是否已经是群成员
@@ -85,18 +113,19 @@ func (s *GroupService) InviteUser(ctx context.Context, groupID, userID, inviterI
 	}
 
 	// 添加新成员
-	member := &model.GroupMember{
-		ID:       uuid.New().String(),
-		GroupID:  groupID,
-		UserID:   userID,
-		Role:     0, // 普通成员
-		JoinedAt: time.Now(),
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		return addMember(tx, groupID, userID, inviterID)
+	}); err != nil {
+		return err
 	}
 
-	return s.db.Create(member).Error
+	s.invalidateMemberCache(groupID)
+	s.publishEvent(ctx, groupID, fmt.Sprintf("%s 邀请 %s 加入了群聊", s.displayName(inviterID), s.displayName(userID)),
+		memberJoinedEvent(inviterID, userID))
+	return nil
 }
 
-// ExitGroup 退出群组
+// ExitGroup 退出群组，退出后在群内发送系统消息和群组事件，退出的用户也会单独收到
 func (s *GroupService) ExitGroup(ctx context.Context, groupID, userID string) error {
 	// 检查是否是群成员
 	var member model.GroupMember
@@ -114,8 +143,23 @@ func (s *GroupService) ExitGroup(ctx context.Context, groupID, userID string) er
 		return errors.New("群主不能退出群组，请先转让群主身份")
 	}
 
-	// 删除群成员记录
-	return s.db.Delete(&member).Error
+	// 删除群成员记录，同时移出群聊会话
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&member).Error; err != nil {
+			return err
+		}
+		return removeParticipant(tx, groupID, userID)
+	}); err != nil {
+		return err
+	}
+
+	s.invalidateMemberCache(groupID)
+	s.publishEvent(ctx, groupID, fmt.Sprintf("%s 退出了群聊", s.displayName(userID)), &protocol.GroupEvent{
+		Event:      constants.GroupEventMemberLeft,
+		OperatorID: userID,
+		MemberIDs:  []string{userID},
+	}, userID)
+	return nil
 }
 
 // GetGroupMembers 获取群成员列表
@@ -140,33 +184,55 @@ func (s *GroupService) GetUserGroups(ctx context.Context, userID string) ([]mode
 	return groups, err
 }
 
-// UpdateGroupName 更新群名称
+// UpdateGroupName 更新群名称，修改后在群内发送系统消息和群组事件，名称未变化时直接返回成功
 func (s *GroupService) UpdateGroupName(ctx context.Context, groupID, userID, newName string) error {
+	newName = strings.TrimSpace(newName)
+	if newName == "" {
+		return errors.New("群名称不能为空")
+	}
+
 	// 检查权限（只有群主和管理员可以修改）
-	var member model.GroupMember
-	if err := s.db.First(&member, "group_id = ? AND user_id = ?", groupID, userID).Error; err != nil {
-		return errors.New("您不是群成员")
+	if err := s.checkAdmin(groupID, userID); err != nil {
+		return err
 	}
 
-	if member.Role == 0 {
-		return errors.New("权限不足")
+	changed := false
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.Group{}).
+			Where("id = ? AND name <> ?", groupID, newName).
+			Updates(map[string]interface{}{
+				"name":       newName,
+				"updated_at": time.Now(),
+			})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		changed = true
+		return renameGroupConversation(tx, groupID, newName)
+	}); err != nil || !changed {
+		return err
 	}
 
-	return s.db.Model(&model.Group{}).
-		Where("id = ?", groupID).
-		Update("name", newName).Error
+	s.publishEvent(ctx, groupID, fmt.Sprintf("%s 将群名称修改为「%s」", s.displayName(userID), newName), &protocol.GroupEvent{
+		Event:      constants.GroupEventRenamed,
+		OperatorID: userID,
+		Name:       newName,
+	})
+	return nil
 }
 
-// DeleteGroup 解散群组（仅群主可操作）
+// DeleteGroup 解散群组（仅群主可操作），解散后向原群成员发送系统消息和群组事件
 func (s *GroupService) DeleteGroup(ctx context.Context, groupID, userID string) error {
 	// 检查是否是群主
-	var group model.Group
-	if err := s.db.First(&group, "id = ?", groupID).Error; err != nil {
-		return errors.New("群组不存在")
+	group, err := s.checkOwner(groupID, userID)
+	if err != nil {
+		return err
 	}
 
-	if group.OwnerID != userID {
-		return errors.New("只有群主可以解散群组")
+	// 记录解散前的成员，解散后群内已没有成员可以收到通知
+	var memberIDs []string
+	if err := s.db.Model(&model.GroupMember{}).Where("group_id = ?", groupID).Pluck("user_id", &memberIDs).Error; err != nil {
+		return err
 	}
 
 	tx := s.db.Begin()
@@ -180,12 +246,58 @@ func (s *GroupService) DeleteGroup(ctx context.Context, groupID, userID string)
 		return err
 	}
 
+	// 删除群公告和置顶消息
+	if err := tx.Delete(&model.GroupAnnouncement{}, "group_id = ?", groupID).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Delete(&model.PinnedMessage{}, "conversation_id = ?", groupID).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// 删除入群申请和邀请链接
+	if err := tx.Delete(&model.GroupJoinRequest{}, "group_id = ?", groupID).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Delete(&model.GroupInvite{}, "group_id = ?", groupID).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// 删除群聊会话及参与者
+	if err := deleteGroupConversation(tx, groupID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	// 删除群组
-	if err := tx.Delete(&group).Error; err != nil {
+	if err := tx.Delete(group).Error; err != nil {
 		tx.Rollback()
 		return err
 	}
 
-	tx.Commit()
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+	s.invalidateMemberCache(groupID)
+
+	s.publishEvent(ctx, groupID, fmt.Sprintf("%s 解散了群聊", s.displayName(userID)), &protocol.GroupEvent{
+		Event:      constants.GroupEventDissolved,
+		OperatorID: userID,
+	}, memberIDs...)
 	return nil
 }
+
+// invalidateMemberCache 成员变更后清除群成员缓存，消息扇出时会重新加载
+func (s *GroupService) invalidateMemberCache(groupID string) {
+	if !redisclient.IsRedisEnabled() {
+		return
+	}
+
+	key := fmt.Sprintf(constants.RedisKeyGroupMembers, groupID)
+	if err := redisclient.GetRedisClient().Del(context.Background(), key).Err(); err != nil {
+		log.Printf("清除群组 %s 的成员缓存失败: %v", groupID, err)
+	}
+}
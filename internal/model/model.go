@@ -1,9 +1,16 @@
 package model
 
 import (
+	"errors"
+	"log"
 	"time"
 
+	"cursorIM/internal/constants"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // User 用户模型
@@ -30,20 +37,49 @@ type Friendship struct {
 
 // Group 群组表
 type Group struct {
-	ID        string    `gorm:"primaryKey;type:varchar(36)" json:"id"`
-	Name      string    `gorm:"type:varchar(50);not null" json:"name"`
-	OwnerID   string    `gorm:"type:varchar(36);not null" json:"owner_id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID           string     `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	Name         string     `gorm:"type:varchar(50);not null" json:"name"`
+	Description  string     `gorm:"type:varchar(500)" json:"description"` // 群简介
+	OwnerID      string     `gorm:"type:varchar(36);not null" json:"owner_id"`
+	MuteAll      bool       `gorm:"default:false" json:"mute_all"`                             // 全员禁言（群主和管理员除外）
+	MuteAllUntil *time.Time `gorm:"index" json:"mute_all_until,omitempty"`                     // 全员禁言结束时间，为空表示直到解除
+	JoinPolicy   string     `gorm:"type:varchar(20);default:'invite_only'" json:"join_policy"` // 入群方式：open/approval/invite_only
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
 }
 
 // GroupMember 群成员表
 type GroupMember struct {
-	ID       string    `gorm:"primaryKey;type:varchar(36)" json:"id"`
-	GroupID  string    `gorm:"type:varchar(36);index:idx_group_member" json:"group_id"`
-	UserID   string    `gorm:"type:varchar(36);index:idx_group_member" json:"user_id"`
-	Role     int       `gorm:"default:0" json:"role"` // 0-成员，1-管理员
-	JoinedAt time.Time `json:"joined_at"`
+	ID         string     `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	GroupID    string     `gorm:"type:varchar(36);index:idx_group_member" json:"group_id"`
+	UserID     string     `gorm:"type:varchar(36);index:idx_group_member" json:"user_id"`
+	Role       int        `gorm:"default:0" json:"role"` // 0-成员，1-管理员，2-群主
+	JoinedAt   time.Time  `json:"joined_at"`
+	MutedUntil *time.Time `gorm:"index" json:"muted_until,omitempty"` // 禁言结束时间，为空表示未被禁言
+}
+
+// GroupJoinRequest 入群申请
+type GroupJoinRequest struct {
+	ID        string     `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	GroupID   string     `gorm:"type:varchar(36);index:idx_group_join_request" json:"group_id"`
+	UserID    string     `gorm:"type:varchar(36);index:idx_group_join_request" json:"user_id"`
+	Message   string     `gorm:"type:varchar(200)" json:"message"`             // 申请附言
+	Status    string     `gorm:"type:varchar(20);index" json:"status"`         // pending/approved/rejected
+	HandledBy string     `gorm:"type:varchar(36)" json:"handled_by,omitempty"` // 审批人
+	HandledAt *time.Time `json:"handled_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// GroupInvite 群邀请链接
+type GroupInvite struct {
+	ID        string    `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	GroupID   string    `gorm:"type:varchar(36);index" json:"group_id"`
+	Token     string    `gorm:"type:varchar(64);uniqueIndex" json:"token"`
+	CreatedBy string    `gorm:"type:varchar(36)" json:"created_by"`
+	MaxUses   int       `gorm:"default:0" json:"max_uses"` // 最多使用次数，0 表示不限
+	UseCount  int       `gorm:"default:0" json:"use_count"`
+	ExpiresAt time.Time `gorm:"index" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 // Conversation 会话
@@ -59,12 +95,29 @@ type Conversation struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// conversationNamespace 生成确定性会话ID和参与者ID的命名空间
+var conversationNamespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte("cursorim:conversation"))
+
+// PrivateConversationID 根据两个用户ID生成唯一确定的单聊会话ID（与参数顺序无关）
+func PrivateConversationID(userA, userB string) string {
+	if userA > userB {
+		userA, userB = userB, userA
+	}
+	return uuid.NewSHA1(conversationNamespace, []byte("private:"+userA+":"+userB)).String()
+}
+
+// ParticipantID 根据会话ID和用户ID生成唯一确定的参与者ID
+func ParticipantID(conversationID, userID string) string {
+	return uuid.NewSHA1(conversationNamespace, []byte("participant:"+conversationID+":"+userID)).String()
+}
+
 // Participant 会话参与者
 type Participant struct {
 	ID             string `gorm:"primaryKey;type:varchar(36)"`
 	UserID         string `gorm:"type:varchar(36);index:idx_conv_user"`
 	ConversationID string `gorm:"type:varchar(36);index:idx_conv_user"`
 	LastReadAt     time.Time
+	Muted          bool `gorm:"default:false"` // 会话免打扰，被@时仍然提醒
 	JoinedAt       time.Time
 	CreatedAt      time.Time
 	UpdatedAt      time.Time
@@ -72,41 +125,218 @@ type Participant struct {
 
 // PrivateMessage 单聊消息表
 type PrivateMessage struct {
-	ID         string    `gorm:"primaryKey;type:varchar(36)" json:"id"`
-	SenderID   string    `gorm:"type:varchar(36);index" json:"sender_id"`
-	ReceiverID string    `gorm:"type:varchar(36);index" json:"receiver_id"`
-	Type       string    `gorm:"type:varchar(10);default:'text'" json:"type"` // text/image/file
-	Content    string    `gorm:"type:text" json:"content"`
-	SentAt     time.Time `json:"sent_at"`
-	Read       bool      `gorm:"default:false" json:"read"`
+	ID         string     `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	SenderID   string     `gorm:"type:varchar(36);index" json:"sender_id"`
+	ReceiverID string     `gorm:"type:varchar(36);index" json:"receiver_id"`
+	Type       string     `gorm:"type:varchar(10);default:'text'" json:"type"` // text/image/file
+	Content    string     `gorm:"type:text" json:"content"`
+	SentAt     time.Time  `json:"sent_at"`
+	Read       bool       `gorm:"default:false" json:"read"`
+	Recalled   bool       `gorm:"default:false" json:"recalled"`                     // 是否已撤回
+	EditedAt   *time.Time `json:"edited_at,omitempty"`                               // 最后编辑时间
+	ReplyToID  string     `gorm:"type:varchar(36)" json:"reply_to_id,omitempty"`     // 回复的父消息ID
+	ThreadID   string     `gorm:"type:varchar(36);index" json:"thread_id,omitempty"` // 所属话题的根消息ID
 }
 
 // GroupMessage 群聊消息表
 type GroupMessage struct {
-	ID       string    `gorm:"primaryKey;type:varchar(36)" json:"id"`
-	GroupID  string    `gorm:"type:varchar(36);index" json:"group_id"`
-	SenderID string    `gorm:"type:varchar(36);index" json:"sender_id"`
-	Type     string    `gorm:"type:varchar(10);default:'text'" json:"type"` // text/image/file
-	Content  string    `gorm:"type:text" json:"content"`
-	SentAt   time.Time `json:"sent_at"`
+	ID        string     `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	GroupID   string     `gorm:"type:varchar(36);index" json:"group_id"`
+	SenderID  string     `gorm:"type:varchar(36);index" json:"sender_id"`
+	Type      string     `gorm:"type:varchar(10);default:'text'" json:"type"` // text/image/file
+	Content   string     `gorm:"type:text" json:"content"`
+	SentAt    time.Time  `json:"sent_at"`
+	Recalled  bool       `gorm:"default:false" json:"recalled"`                     // 是否已撤回
+	EditedAt  *time.Time `json:"edited_at,omitempty"`                               // 最后编辑时间
+	ReplyToID string     `gorm:"type:varchar(36)" json:"reply_to_id,omitempty"`     // 回复的父消息ID
+	ThreadID  string     `gorm:"type:varchar(36);index" json:"thread_id,omitempty"` // 所属话题的根消息ID
 }
 
 // Message 消息
 type Message struct {
+	ID                    string     `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	ConversationID        string     `gorm:"type:varchar(100);index;index:idx_conv_seq,priority:1" json:"conversation_id"` // 增加长度以支持临时会话ID
+	Seq                   int64      `gorm:"default:0;index:idx_conv_seq,priority:2" json:"seq"`                           // 会话内单调递增的序列号
+	SenderID              string     `gorm:"type:varchar(36);index;uniqueIndex:idx_sender_client_msg,priority:1" json:"sender_id"`
+	ClientMsgID           *string    `gorm:"type:varchar(64);uniqueIndex:idx_sender_client_msg,priority:2" json:"client_msg_id,omitempty"` // 客户端生成的消息ID，用于重发去重
+	Content               string     `gorm:"type:text" json:"content"`
+	ContentType           string     `gorm:"type:varchar(20);default:'text'" json:"content_type"` // text, image, file
+	Status                string     `gorm:"type:varchar(20);default:'sent'" json:"status"`       // sent, delivered, read
+	Timestamp             int64      `json:"timestamp"`
+	IsGroup               bool       `json:"is_group"`                                                   // 是否是群组消息
+	Type                  string     `json:"type"`                                                       // 文本、图片、文件等
+	RecipientID           string     `gorm:"type:varchar(36);index" json:"recipient_id"`                 // 直接接收者ID
+	TargetID              string     `gorm:"type:varchar(36)" json:"target_id,omitempty"`                // 通知类消息指向的目标消息ID
+	Recalled              bool       `gorm:"default:false" json:"recalled"`                              // 是否已撤回
+	RecalledAt            *time.Time `json:"recalled_at,omitempty"`                                      // 撤回时间
+	RecalledBy            string     `gorm:"type:varchar(36)" json:"recalled_by,omitempty"`              // 撤回操作人（发送者或群管理员）
+	EditedAt              *time.Time `json:"edited_at,omitempty"`                                        // 最后编辑时间
+	ReplyToID             string     `gorm:"type:varchar(36);index" json:"reply_to_id,omitempty"`        // 回复/引用的父消息ID
+	QuoteSenderID         string     `gorm:"type:varchar(36)" json:"quote_sender_id,omitempty"`          // 被引用消息的发送者（快照）
+	QuoteType             string     `gorm:"type:varchar(20)" json:"quote_type,omitempty"`               // 被引用消息的类型（快照）
+	QuoteContent          string     `gorm:"type:text" json:"quote_content,omitempty"`                   // 被引用消息的内容（快照）
+	ThreadID              string     `gorm:"type:varchar(36);index" json:"thread_id,omitempty"`          // 所属话题的根消息ID，主消息流中的消息为空
+	ReplyCount            int        `gorm:"default:0" json:"reply_count"`                               // 作为话题根消息时的回复数
+	Mentions              string     `gorm:"type:text" json:"mentions,omitempty"`                        // 显式提及的用户ID，逗号分隔
+	MentionAll            bool       `gorm:"default:false" json:"mention_all"`                           // @所有人
+	MentionAdmins         bool       `gorm:"default:false" json:"mention_admins"`                        // @管理员
+	TTL                   int        `gorm:"default:0" json:"ttl,omitempty"`                             // 自毁消息的存活时间（秒），0 表示不自动删除
+	ExpiresAt             *time.Time `gorm:"index" json:"expires_at,omitempty"`                          // 到期时间，阅后计时的消息在首次被阅读前为空
+	ForwardFromID         string     `gorm:"type:varchar(36)" json:"forward_from_id,omitempty"`          // 转发的原消息ID（多次转发时为最初的消息）
+	ForwardSenderID       string     `gorm:"type:varchar(36)" json:"forward_sender_id,omitempty"`        // 原消息的发送者
+	ForwardConversationID string     `gorm:"type:varchar(100)" json:"forward_conversation_id,omitempty"` // 原消息所在的会话
+	Record                string     `gorm:"type:mediumtext" json:"record,omitempty"`                    // 合并转发的聊天记录（JSON）
+	Body                  string     `gorm:"type:text" json:"body,omitempty"`                            // 位置、名片、表情包、链接卡片等结构化消息体（JSON）
+	CreatedAt             time.Time  `json:"created_at"`
+	UpdatedAt             time.Time  `json:"updated_at"`
+}
+
+// MessageRevision 消息编辑历史，保存每次编辑前的内容
+type MessageRevision struct {
+	ID        string    `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	MessageID string    `gorm:"type:varchar(36);index" json:"message_id"`
+	Revision  int       `json:"revision"` // 从 1 开始，1 为原始内容
+	Content   string    `gorm:"type:text" json:"content"`
+	EditedBy  string    `gorm:"type:varchar(36)" json:"edited_by"` // 产生下一版本的编辑者
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// MessageMention 消息提及记录，@所有人/@管理员 展开为每个被提及用户一条
+type MessageMention struct {
 	ID             string    `gorm:"primaryKey;type:varchar(36)" json:"id"`
-	ConversationID string    `gorm:"type:varchar(100);index" json:"conversation_id"` // 增加长度以支持临时会话ID
-	SenderID       string    `gorm:"type:varchar(36);index" json:"sender_id"`
-	Content        string    `gorm:"type:text" json:"content"`
-	ContentType    string    `gorm:"type:varchar(20);default:'text'" json:"content_type"` // text, image, file
-	Status         string    `gorm:"type:varchar(20);default:'sent'" json:"status"`       // sent, delivered, read
-	Timestamp      int64     `json:"timestamp"`
-	IsGroup        bool      `json:"is_group"`                                   // 是否是群组消息
-	Type           string    `json:"type"`                                       // 文本、图片、文件等
-	RecipientID    string    `gorm:"type:varchar(36);index" json:"recipient_id"` // 直接接收者ID
+	MessageID      string    `gorm:"type:varchar(36);index" json:"message_id"`
+	ConversationID string    `gorm:"type:varchar(100);index:idx_mention_conv_user" json:"conversation_id"`
+	UserID         string    `gorm:"type:varchar(36);index:idx_mention_conv_user" json:"user_id"`
 	CreatedAt      time.Time `json:"created_at"`
+}
+
+// MessageReaction 消息表情回应，每个用户对同一消息的同一表情只有一条记录
+type MessageReaction struct {
+	ID        string    `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	MessageID string    `gorm:"type:varchar(36);uniqueIndex:idx_reaction" json:"message_id"`
+	UserID    string    `gorm:"type:varchar(36);uniqueIndex:idx_reaction" json:"user_id"`
+	Emoji     string    `gorm:"type:varchar(32);uniqueIndex:idx_reaction" json:"emoji"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ThreadReadState 话题的每用户已读状态
+type ThreadReadState struct {
+	ID         string `gorm:"primaryKey;type:varchar(36)"`
+	ThreadID   string `gorm:"type:varchar(36);uniqueIndex:idx_thread_user"`
+	UserID     string `gorm:"type:varchar(36);uniqueIndex:idx_thread_user"`
+	LastReadAt time.Time
+	UpdatedAt  time.Time
+}
+
+// ConversationReadState 用户在会话（含群聊）中的已读位置
+type ConversationReadState struct {
+	ID             string `gorm:"primaryKey;type:varchar(36)"`
+	ConversationID string `gorm:"type:varchar(100);uniqueIndex:idx_conv_reader"`
+	UserID         string `gorm:"type:varchar(36);uniqueIndex:idx_conv_reader"`
+	LastReadSeq    int64  // 已读到的最大序列号
+	LastReadAt     time.Time
+	UpdatedAt      time.Time
+}
+
+// GroupOfflineMessage 群消息的离线投递记录
+// 群消息在通用消息表中只有一行，不在线的成员各记录一条，上线后按记录补发
+type GroupOfflineMessage struct {
+	ID        string `gorm:"primaryKey;type:varchar(36)"`
+	UserID    string `gorm:"type:varchar(36);uniqueIndex:idx_offline_user_msg"`
+	MessageID string `gorm:"type:varchar(36);uniqueIndex:idx_offline_user_msg"`
+	CreatedAt time.Time
+}
+
+// OfflineNotice 离线用户的通知队列（撤回、编辑、表情回应、群事件等）
+// 通知不是聊天记录，不写入通用消息表；整帧保存在 Payload 中，上线补发后即删除
+type OfflineNotice struct {
+	ID        string `gorm:"primaryKey;type:varchar(36)"`
+	UserID    string `gorm:"type:varchar(36);uniqueIndex:idx_notice_user_msg"`
+	MessageID string `gorm:"type:varchar(36);uniqueIndex:idx_notice_user_msg"`
+	TargetID  string `gorm:"type:varchar(100);index"` // 通知针对的消息，消息到期删除时一并清理
+	Payload   string `gorm:"type:mediumtext"`
+	Timestamp int64
+	CreatedAt time.Time
+}
+
+// ConversationRetention 会话的自毁消息设置（群聊以群组ID作为会话ID）
+// 设置只影响之后发送的消息，已发送的消息保留发送时的存活时间
+type ConversationRetention struct {
+	ConversationID string    `gorm:"primaryKey;type:varchar(100)" json:"conversation_id"`
+	TTL            int       `gorm:"default:0" json:"ttl"`                        // 消息存活时间（秒），0 表示关闭
+	Mode           string    `gorm:"type:varchar(10);default:'send'" json:"mode"` // send: 从发送时计时；read: 从首次被阅读时计时
+	MinTTL         int       `gorm:"default:0" json:"min_ttl"`                    // 群管理员要求的最短保留时间（秒），开启时 TTL 不能低于该值
+	UpdatedBy      string    `gorm:"type:varchar(36)" json:"updated_by"`
 	UpdatedAt      time.Time `json:"updated_at"`
 }
 
+// ScheduledMessage 定时消息，到期后由调度器按正常发送流程投递
+type ScheduledMessage struct {
+	ID          string     `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	SenderID    string     `gorm:"type:varchar(36);index" json:"sender_id"`
+	RecipientID string     `gorm:"type:varchar(36)" json:"recipient_id"` // 接收者ID或群组ID
+	IsGroup     bool       `gorm:"default:false" json:"is_group"`
+	ContentType string     `gorm:"type:varchar(20);default:'text'" json:"content_type"`
+	Content     string     `gorm:"type:text" json:"content"`
+	Payload     string     `gorm:"type:text" json:"-"`                                                // 完整的协议消息（JSON），发送时还原
+	SendAt      time.Time  `gorm:"index:idx_scheduled_due,priority:2" json:"send_at"`                 // 计划发送时间
+	Status      string     `gorm:"type:varchar(16);index:idx_scheduled_due,priority:1" json:"status"` // pending, sending, sent, canceled, failed
+	MessageID   string     `gorm:"type:varchar(36)" json:"message_id,omitempty"`                      // 发送后生成的消息ID
+	Error       string     `gorm:"type:varchar(255)" json:"error,omitempty"`                          // 发送失败原因
+	SentAt      *time.Time `json:"sent_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// GroupAnnouncement 群公告，由群主和群管理员发布
+type GroupAnnouncement struct {
+	ID        string    `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	GroupID   string    `gorm:"type:varchar(36);index" json:"group_id"`
+	Content   string    `gorm:"type:text" json:"content"`
+	CreatedBy string    `gorm:"type:varchar(36)" json:"created_by"`
+	UpdatedBy string    `gorm:"type:varchar(36)" json:"updated_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PinnedMessage 会话中置顶的消息（群聊以群组ID作为会话ID）
+type PinnedMessage struct {
+	ID             string    `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	ConversationID string    `gorm:"type:varchar(100);uniqueIndex:idx_pinned_message,priority:1" json:"conversation_id"`
+	MessageID      string    `gorm:"type:varchar(36);uniqueIndex:idx_pinned_message,priority:2" json:"message_id"`
+	PinnedBy       string    `gorm:"type:varchar(36)" json:"pinned_by"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// Poll 群投票的状态，题目和选项保存在投票消息的消息体中
+type Poll struct {
+	ID             string     `gorm:"primaryKey;type:varchar(36)" json:"id"` // 投票消息ID
+	ConversationID string     `gorm:"type:varchar(100);index" json:"conversation_id"`
+	CreatorID      string     `gorm:"type:varchar(36)" json:"creator_id"`
+	Multiple       bool       `gorm:"default:false" json:"multiple"`
+	Anonymous      bool       `gorm:"default:false" json:"anonymous"`
+	CloseAt        *time.Time `gorm:"index" json:"close_at,omitempty"` // 自动结束时间
+	ClosedAt       *time.Time `json:"closed_at,omitempty"`
+	ClosedBy       string     `gorm:"type:varchar(36)" json:"closed_by,omitempty"` // 到期自动结束时为空
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// PollVote 投票记录，多选投票中每个选项一行
+type PollVote struct {
+	ID        string    `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	PollID    string    `gorm:"type:varchar(36);uniqueIndex:idx_poll_vote,priority:1" json:"poll_id"`
+	UserID    string    `gorm:"type:varchar(36);uniqueIndex:idx_poll_vote,priority:2" json:"user_id"`
+	OptionID  string    `gorm:"type:varchar(10);uniqueIndex:idx_poll_vote,priority:3" json:"option_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ConversationSequence 会话序列号（Redis 不可用时的分配来源）
+type ConversationSequence struct {
+	ConversationID string `gorm:"primaryKey;type:varchar(100)"`
+	LastSeq        int64  `gorm:"default:0"`
+	UpdatedAt      time.Time
+}
+
 // SetupDatabase 初始化数据库表结构
 func SetupDatabase(db *gorm.DB) error {
 	// 自动迁移表结构
@@ -120,5 +350,198 @@ func SetupDatabase(db *gorm.DB) error {
 		&PrivateMessage{},
 		&GroupMessage{},
 		&Message{},
+		&ConversationSequence{},
+		&MessageRevision{},
+		&ThreadReadState{},
+		&MessageReaction{},
+		&MessageMention{},
+		&ConversationReadState{},
+		&GroupOfflineMessage{},
+		&OfflineNotice{},
+		&ScheduledMessage{},
+		&ConversationRetention{},
+		&GroupAnnouncement{},
+		&PinnedMessage{},
+		&Poll{},
+		&PollVote{},
+		&GroupJoinRequest{},
+		&GroupInvite{},
+		&SchemaMigration{},
 	)
 }
+
+// SchemaMigration 一次性数据迁移的标记，AppliedAt 为空表示尚未执行成功
+type SchemaMigration struct {
+	Name      string `gorm:"primaryKey;type:varchar(100)"`
+	AppliedAt *time.Time
+}
+
+// RunMigrationOnce 执行一次性数据迁移，之后启动时跳过
+// 执行期间在事务中锁住迁移标记行，其他同时启动的节点阻塞到迁移完成后才继续启动；
+// 迁移成功后才写入完成时间，执行失败或进程中途退出时事务回滚，下次启动重试
+func RunMigrationOnce(db *gorm.DB, name string, migrate func() error) error {
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&SchemaMigration{Name: name}).Error; err != nil {
+		return err
+	}
+
+	for {
+		var migrateErr error
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var marker SchemaMigration
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("name = ?", name).Take(&marker).Error; err != nil {
+				return err
+			}
+			if marker.AppliedAt != nil {
+				return nil
+			}
+
+			if migrateErr = migrate(); migrateErr != nil {
+				return migrateErr
+			}
+			return tx.Model(&SchemaMigration{}).Where("name = ?", name).Update("applied_at", time.Now()).Error
+		})
+		if migrateErr != nil || !isLockWaitTimeout(err) {
+			return err
+		}
+		// 其他节点仍在执行该迁移，继续等待
+		log.Printf("等待其他节点完成数据迁移 %s", name)
+	}
+}
+
+// isLockWaitTimeout 判断错误是否为 MySQL 行锁等待超时
+func isLockWaitTimeout(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1205
+}
+
+// legacyControlTypes 旧版本离线存储时写入通用消息表的通知和控制帧类型
+var legacyControlTypes = []string{
+	constants.MessageTypePing,
+	constants.MessageTypePong,
+	constants.MessageTypeStatus,
+	constants.MessageTypeAck,
+	constants.MessageTypeError,
+	constants.MessageTypeSync,
+	constants.MessageTypeResponse,
+	constants.MessageTypeRecall,
+	constants.MessageTypeEdit,
+	constants.MessageTypeReaction,
+	constants.MessageTypeMention,
+	constants.MessageTypeRead,
+	constants.MessageTypePurge,
+	constants.MessageTypePin,
+	constants.MessageTypeAnnouncement,
+	constants.MessageTypeVote,
+	constants.MessageTypePollClose,
+	constants.MessageTypeJoinRequest,
+	constants.MessageTypeGroupEvent,
+}
+
+// PurgeLegacyControlMessages 删除旧版本写入通用消息表的通知和控制帧
+// 通知现在保存在离线通知队列（OfflineNotice）中；尚未补发的旧通知随之丢弃，
+// 客户端上线后通过增量同步拿到消息的最新状态
+func PurgeLegacyControlMessages(db *gorm.DB) error {
+	result := db.Where("content_type IN ?", legacyControlTypes).Delete(&Message{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("已从消息表删除 %d 条旧的通知和控制消息", result.RowsAffected)
+	}
+	return nil
+}
+
+// BackfillMessageSequences 为升级前没有序列号的会话按时间顺序补齐序列号
+// 只处理所有消息都没有序列号的会话，避免打乱已分配的顺序
+func BackfillMessageSequences(db *gorm.DB) error {
+	var conversationIDs []string
+	err := db.Model(&Message{}).
+		Select("conversation_id").
+		Where("conversation_id <> ''").
+		Group("conversation_id").
+		Having("MAX(seq) = 0").
+		Pluck("conversation_id", &conversationIDs).Error
+	if err != nil {
+		return err
+	}
+
+	for _, conversationID := range conversationIDs {
+		var ids []string
+		if err := db.Model(&Message{}).
+			Where("conversation_id = ?", conversationID).
+			Order("timestamp asc, created_at asc, id asc").
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			for i, id := range ids {
+				if err := tx.Model(&Message{}).Where("id = ?", id).Update("seq", i+1).Error; err != nil {
+					return err
+				}
+			}
+			return tx.Save(&ConversationSequence{
+				ConversationID: conversationID,
+				LastSeq:        int64(len(ids)),
+				UpdatedAt:      time.Now(),
+			}).Error
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// BackfillGroupOwners 将升级前以管理员角色保存的群主改为群主角色
+func BackfillGroupOwners(db *gorm.DB) error {
+	return db.Model(&GroupMember{}).
+		Where("role <> ? AND (group_id, user_id) IN (?)", constants.GroupRoleOwner, db.Model(&Group{}).Select("id, owner_id")).
+		Update("role", constants.GroupRoleOwner).Error
+}
+
+// BackfillGroupConversations 为升级前创建的群组补齐群聊会话和参与者
+// 群聊会话以群组ID作为会话ID；补齐的参与者从当前时间开始计算未读，避免历史消息全部变成未读
+func BackfillGroupConversations(db *gorm.DB) error {
+	var groups []Group
+	if err := db.Where("id NOT IN (?)", db.Model(&Conversation{}).Select("id")).Find(&groups).Error; err != nil {
+		return err
+	}
+	for _, group := range groups {
+		if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&Conversation{
+			ID:        group.ID,
+			Name:      group.Name,
+			Type:      constants.ConversationTypeGroup,
+			IsGroup:   true,
+			LastTime:  group.CreatedAt,
+			CreatedAt: group.CreatedAt,
+			UpdatedAt: time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+	}
+
+	var members []GroupMember
+	if err := db.Where("NOT EXISTS (?)", db.Model(&Participant{}).Select("1").
+		Where("participants.conversation_id = group_members.group_id AND participants.user_id = group_members.user_id")).
+		Find(&members).Error; err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, member := range members {
+		if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&Participant{
+			ID:             ParticipantID(member.GroupID, member.UserID),
+			ConversationID: member.GroupID,
+			UserID:         member.UserID,
+			JoinedAt:       member.JoinedAt,
+			LastReadAt:     now,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -49,6 +49,25 @@ func (a *MessageAdapter) JSONToProtobuf(jsonMsg *Message) (*pb.Message, error) {
 		GroupId:        jsonMsg.GroupID,
 		Status:         a.stringToMessageStatus(jsonMsg.Status),
 		HandledByLocal: jsonMsg.HandledByLocal,
+		Seq:            jsonMsg.Seq,
+		TargetId:       jsonMsg.TargetID,
+		Recalled:       jsonMsg.Recalled,
+		EditedAt:       jsonMsg.EditedAt,
+		ReplyToId:      jsonMsg.ReplyToID,
+		ThreadId:       jsonMsg.ThreadID,
+		ReplyCount:     int32(jsonMsg.ReplyCount),
+		Emoji:          jsonMsg.Emoji,
+		Remove:         jsonMsg.Remove,
+		Mentions:       jsonMsg.Mentions,
+		MentionAll:     jsonMsg.MentionAll,
+		MentionAdmins:  jsonMsg.MentionAdmins,
+		ReadCount:      int32(jsonMsg.ReadCount),
+		MemberCount:    int32(jsonMsg.MemberCount),
+		Active:         jsonMsg.Active,
+		ClientMsgId:    jsonMsg.ClientMsgID,
+		Ttl:            int32(jsonMsg.TTL),
+		ExpiresAt:      jsonMsg.ExpiresAt,
+		TargetIds:      jsonMsg.TargetIDs,
 	}
 
 	// 转换错误信息
@@ -71,9 +90,139 @@ func (a *MessageAdapter) JSONToProtobuf(jsonMsg *Message) (*pb.Message, error) {
 		pbMsg.Metadata = jsonMsg.Metadata
 	}
 
+	// 转换引用快照
+	if jsonMsg.Quote != nil {
+		pbMsg.Quote = &pb.QuotedMessage{
+			Id:       jsonMsg.Quote.ID,
+			SenderId: jsonMsg.Quote.SenderID,
+			Type:     jsonMsg.Quote.Type,
+			Content:  jsonMsg.Quote.Content,
+		}
+	}
+
+	// 转换表情回应汇总
+	for _, reaction := range jsonMsg.Reactions {
+		pbMsg.Reactions = append(pbMsg.Reactions, &pb.ReactionSummary{
+			Emoji:   reaction.Emoji,
+			Count:   int32(reaction.Count),
+			UserIds: reaction.UserIDs,
+		})
+	}
+
+	// 转换转发来源
+	if jsonMsg.Forward != nil {
+		pbMsg.Forward = &pb.ForwardInfo{
+			MessageId:      jsonMsg.Forward.MessageID,
+			SenderId:       jsonMsg.Forward.SenderID,
+			ConversationId: jsonMsg.Forward.ConversationID,
+		}
+	}
+
+	// 转换聊天记录
+	if jsonMsg.Record != nil {
+		pbMsg.Record = &pb.ChatRecord{Title: jsonMsg.Record.Title}
+		for _, item := range jsonMsg.Record.Items {
+			pbMsg.Record.Items = append(pbMsg.Record.Items, &pb.ChatRecordItem{
+				Id:        item.ID,
+				SenderId:  item.SenderID,
+				Type:      item.Type,
+				Content:   item.Content,
+				Timestamp: item.Timestamp,
+			})
+		}
+	}
+
+	// 转换结构化消息体
+	switch {
+	case jsonMsg.Location != nil:
+		pbMsg.Body = &pb.Message_Location{Location: &pb.Location{
+			Latitude:  jsonMsg.Location.Latitude,
+			Longitude: jsonMsg.Location.Longitude,
+			Name:      jsonMsg.Location.Name,
+			Address:   jsonMsg.Location.Address,
+		}}
+	case jsonMsg.Contact != nil:
+		pbMsg.Body = &pb.Message_Contact{Contact: &pb.ContactCard{
+			UserId:    jsonMsg.Contact.UserID,
+			Nickname:  jsonMsg.Contact.Nickname,
+			AvatarUrl: jsonMsg.Contact.AvatarURL,
+		}}
+	case jsonMsg.Sticker != nil:
+		pbMsg.Body = &pb.Message_Sticker{Sticker: &pb.Sticker{
+			StickerId: jsonMsg.Sticker.StickerID,
+			PackId:    jsonMsg.Sticker.PackID,
+			Url:       jsonMsg.Sticker.URL,
+			Width:     int32(jsonMsg.Sticker.Width),
+			Height:    int32(jsonMsg.Sticker.Height),
+			Emoji:     jsonMsg.Sticker.Emoji,
+		}}
+	case jsonMsg.Link != nil:
+		pbMsg.Body = &pb.Message_Link{Link: &pb.LinkPreview{
+			Url:         jsonMsg.Link.URL,
+			Title:       jsonMsg.Link.Title,
+			Description: jsonMsg.Link.Description,
+			ImageUrl:    jsonMsg.Link.ImageURL,
+			SiteName:    jsonMsg.Link.SiteName,
+		}}
+	case jsonMsg.Poll != nil:
+		pbPoll := &pb.Poll{
+			Question:   jsonMsg.Poll.Question,
+			Multiple:   jsonMsg.Poll.Multiple,
+			Anonymous:  jsonMsg.Poll.Anonymous,
+			CloseAt:    jsonMsg.Poll.CloseAt,
+			Closed:     jsonMsg.Poll.Closed,
+			ClosedAt:   jsonMsg.Poll.ClosedAt,
+			VoterCount: int32(jsonMsg.Poll.VoterCount),
+		}
+		for _, option := range jsonMsg.Poll.Options {
+			pbPoll.Options = append(pbPoll.Options, &pb.PollOption{
+				Id:       option.ID,
+				Text:     option.Text,
+				Count:    int32(option.Count),
+				VoterIds: option.VoterIDs,
+			})
+		}
+		pbMsg.Body = &pb.Message_Poll{Poll: pbPoll}
+	case jsonMsg.GroupEvent != nil:
+		pbMsg.Body = &pb.Message_GroupEvent{GroupEvent: &pb.GroupEvent{
+			Event:       jsonMsg.GroupEvent.Event,
+			OperatorId:  jsonMsg.GroupEvent.OperatorID,
+			MemberIds:   jsonMsg.GroupEvent.MemberIDs,
+			Name:        jsonMsg.GroupEvent.Name,
+			Role:        int32(jsonMsg.GroupEvent.Role),
+			Description: jsonMsg.GroupEvent.Description,
+		}}
+	}
+	pbMsg.OptionIds = jsonMsg.OptionIDs
+
+	// 转换批量消息
+	if jsonMsg.Batch != nil {
+		batch, err := a.BatchToProtobuf(jsonMsg.Batch)
+		if err != nil {
+			return nil, err
+		}
+		pbMsg.Batch = batch
+	}
+
 	return pbMsg, nil
 }
 
+// BatchToProtobuf 将批量消息转换为 Protobuf 格式（历史消息分页等 HTTP 接口也使用）
+func (a *MessageAdapter) BatchToProtobuf(batch *MessageBatch) (*pb.MessageBatch, error) {
+	pbBatch := &pb.MessageBatch{
+		TotalCount: int32(batch.TotalCount),
+		HasMore:    batch.HasMore,
+	}
+	for _, item := range batch.Messages {
+		pbItem, err := a.JSONToProtobuf(item)
+		if err != nil {
+			return nil, err
+		}
+		pbBatch.Messages = append(pbBatch.Messages, pbItem)
+	}
+	return pbBatch, nil
+}
+
 // ProtobufToJSON 将 Protobuf 消息转换为 JSON 消息
 func (a *MessageAdapter) ProtobufToJSON(pbMsg *pb.Message) (*Message, error) {
 	if pbMsg == nil {
@@ -96,6 +245,25 @@ func (a *MessageAdapter) ProtobufToJSON(pbMsg *pb.Message) (*Message, error) {
 		GroupID:        pbMsg.GroupId,
 		Status:         a.messageStatusToString(pbMsg.Status),
 		HandledByLocal: pbMsg.HandledByLocal,
+		Seq:            pbMsg.Seq,
+		TargetID:       pbMsg.TargetId,
+		Recalled:       pbMsg.Recalled,
+		EditedAt:       pbMsg.EditedAt,
+		ReplyToID:      pbMsg.ReplyToId,
+		ThreadID:       pbMsg.ThreadId,
+		ReplyCount:     int(pbMsg.ReplyCount),
+		Emoji:          pbMsg.Emoji,
+		Remove:         pbMsg.Remove,
+		Mentions:       pbMsg.Mentions,
+		MentionAll:     pbMsg.MentionAll,
+		MentionAdmins:  pbMsg.MentionAdmins,
+		ReadCount:      int(pbMsg.ReadCount),
+		MemberCount:    int(pbMsg.MemberCount),
+		Active:         pbMsg.Active,
+		ClientMsgID:    pbMsg.ClientMsgId,
+		TTL:            int(pbMsg.Ttl),
+		ExpiresAt:      pbMsg.ExpiresAt,
+		TargetIDs:      pbMsg.TargetIds,
 		CreatedAt:      time.Unix(pbMsg.Timestamp, 0),
 		UpdatedAt:      time.Unix(pbMsg.Timestamp, 0),
 	}
@@ -113,6 +281,125 @@ func (a *MessageAdapter) ProtobufToJSON(pbMsg *pb.Message) (*Message, error) {
 		jsonMsg.Metadata = pbMsg.Metadata
 	}
 
+	// 转换引用快照
+	if pbMsg.Quote != nil {
+		jsonMsg.Quote = &QuotedMessage{
+			ID:       pbMsg.Quote.Id,
+			SenderID: pbMsg.Quote.SenderId,
+			Type:     pbMsg.Quote.Type,
+			Content:  pbMsg.Quote.Content,
+		}
+	}
+
+	// 转换表情回应汇总
+	for _, reaction := range pbMsg.Reactions {
+		jsonMsg.Reactions = append(jsonMsg.Reactions, &ReactionSummary{
+			Emoji:   reaction.Emoji,
+			Count:   int(reaction.Count),
+			UserIDs: reaction.UserIds,
+		})
+	}
+
+	// 转换转发来源
+	if pbMsg.Forward != nil {
+		jsonMsg.Forward = &ForwardInfo{
+			MessageID:      pbMsg.Forward.MessageId,
+			SenderID:       pbMsg.Forward.SenderId,
+			ConversationID: pbMsg.Forward.ConversationId,
+		}
+	}
+
+	// 转换聊天记录
+	if pbMsg.Record != nil {
+		jsonMsg.Record = &ChatRecord{Title: pbMsg.Record.Title}
+		for _, item := range pbMsg.Record.Items {
+			jsonMsg.Record.Items = append(jsonMsg.Record.Items, &ChatRecordItem{
+				ID:        item.Id,
+				SenderID:  item.SenderId,
+				Type:      item.Type,
+				Content:   item.Content,
+				Timestamp: item.Timestamp,
+			})
+		}
+	}
+
+	// 转换结构化消息体
+	switch body := pbMsg.Body.(type) {
+	case *pb.Message_Location:
+		jsonMsg.Location = &Location{
+			Latitude:  body.Location.GetLatitude(),
+			Longitude: body.Location.GetLongitude(),
+			Name:      body.Location.GetName(),
+			Address:   body.Location.GetAddress(),
+		}
+	case *pb.Message_Contact:
+		jsonMsg.Contact = &ContactCard{
+			UserID:    body.Contact.GetUserId(),
+			Nickname:  body.Contact.GetNickname(),
+			AvatarURL: body.Contact.GetAvatarUrl(),
+		}
+	case *pb.Message_Sticker:
+		jsonMsg.Sticker = &Sticker{
+			StickerID: body.Sticker.GetStickerId(),
+			PackID:    body.Sticker.GetPackId(),
+			URL:       body.Sticker.GetUrl(),
+			Width:     int(body.Sticker.GetWidth()),
+			Height:    int(body.Sticker.GetHeight()),
+			Emoji:     body.Sticker.GetEmoji(),
+		}
+	case *pb.Message_Link:
+		jsonMsg.Link = &LinkPreview{
+			URL:         body.Link.GetUrl(),
+			Title:       body.Link.GetTitle(),
+			Description: body.Link.GetDescription(),
+			ImageURL:    body.Link.GetImageUrl(),
+			SiteName:    body.Link.GetSiteName(),
+		}
+	case *pb.Message_Poll:
+		jsonMsg.Poll = &Poll{
+			Question:   body.Poll.GetQuestion(),
+			Multiple:   body.Poll.GetMultiple(),
+			Anonymous:  body.Poll.GetAnonymous(),
+			CloseAt:    body.Poll.GetCloseAt(),
+			Closed:     body.Poll.GetClosed(),
+			ClosedAt:   body.Poll.GetClosedAt(),
+			VoterCount: int(body.Poll.GetVoterCount()),
+		}
+		for _, option := range body.Poll.GetOptions() {
+			jsonMsg.Poll.Options = append(jsonMsg.Poll.Options, &PollOption{
+				ID:       option.GetId(),
+				Text:     option.GetText(),
+				Count:    int(option.GetCount()),
+				VoterIDs: option.GetVoterIds(),
+			})
+		}
+	case *pb.Message_GroupEvent:
+		jsonMsg.GroupEvent = &GroupEvent{
+			Event:       body.GroupEvent.GetEvent(),
+			OperatorID:  body.GroupEvent.GetOperatorId(),
+			MemberIDs:   body.GroupEvent.GetMemberIds(),
+			Name:        body.GroupEvent.GetName(),
+			Role:        int(body.GroupEvent.GetRole()),
+			Description: body.GroupEvent.GetDescription(),
+		}
+	}
+	jsonMsg.OptionIDs = pbMsg.OptionIds
+
+	// 转换批量消息
+	if pbMsg.Batch != nil {
+		jsonMsg.Batch = &MessageBatch{
+			TotalCount: int(pbMsg.Batch.TotalCount),
+			HasMore:    pbMsg.Batch.HasMore,
+		}
+		for _, item := range pbMsg.Batch.Messages {
+			jsonItem, err := a.ProtobufToJSON(item)
+			if err != nil {
+				return nil, err
+			}
+			jsonMsg.Batch.Messages = append(jsonMsg.Batch.Messages, jsonItem)
+		}
+	}
+
 	return jsonMsg, nil
 }
 
@@ -210,6 +497,52 @@ func (a *MessageAdapter) stringToMessageType(typeStr string) pb.MessageType {
 		return pb.MessageType_MESSAGE_TYPE_RESPONSE
 	case "error":
 		return pb.MessageType_MESSAGE_TYPE_ERROR
+	case "ack":
+		return pb.MessageType_MESSAGE_TYPE_ACK
+	case "sync":
+		return pb.MessageType_MESSAGE_TYPE_SYNC
+	case "recall":
+		return pb.MessageType_MESSAGE_TYPE_RECALL
+	case "edit":
+		return pb.MessageType_MESSAGE_TYPE_EDIT
+	case "reaction":
+		return pb.MessageType_MESSAGE_TYPE_REACTION
+	case "mention":
+		return pb.MessageType_MESSAGE_TYPE_MENTION
+	case "read":
+		return pb.MessageType_MESSAGE_TYPE_READ
+	case "typing":
+		return pb.MessageType_MESSAGE_TYPE_TYPING
+	case "recording":
+		return pb.MessageType_MESSAGE_TYPE_RECORDING
+	case "purge":
+		return pb.MessageType_MESSAGE_TYPE_PURGE
+	case "chat_record":
+		return pb.MessageType_MESSAGE_TYPE_CHAT_RECORD
+	case "pin":
+		return pb.MessageType_MESSAGE_TYPE_PIN
+	case "announcement":
+		return pb.MessageType_MESSAGE_TYPE_ANNOUNCEMENT
+	case "location":
+		return pb.MessageType_MESSAGE_TYPE_LOCATION
+	case "contact":
+		return pb.MessageType_MESSAGE_TYPE_CONTACT
+	case "sticker":
+		return pb.MessageType_MESSAGE_TYPE_STICKER
+	case "link":
+		return pb.MessageType_MESSAGE_TYPE_LINK
+	case "poll":
+		return pb.MessageType_MESSAGE_TYPE_POLL
+	case "vote":
+		return pb.MessageType_MESSAGE_TYPE_VOTE
+	case "poll_close":
+		return pb.MessageType_MESSAGE_TYPE_POLL_CLOSE
+	case "system":
+		return pb.MessageType_MESSAGE_TYPE_SYSTEM
+	case "join_request":
+		return pb.MessageType_MESSAGE_TYPE_JOIN_REQUEST
+	case "group_event":
+		return pb.MessageType_MESSAGE_TYPE_GROUP_EVENT
 	default:
 		return pb.MessageType_MESSAGE_TYPE_UNKNOWN
 	}
@@ -240,6 +573,52 @@ func (a *MessageAdapter) messageTypeToString(msgType pb.MessageType) string {
 		return "response"
 	case pb.MessageType_MESSAGE_TYPE_ERROR:
 		return "error"
+	case pb.MessageType_MESSAGE_TYPE_ACK:
+		return "ack"
+	case pb.MessageType_MESSAGE_TYPE_SYNC:
+		return "sync"
+	case pb.MessageType_MESSAGE_TYPE_RECALL:
+		return "recall"
+	case pb.MessageType_MESSAGE_TYPE_EDIT:
+		return "edit"
+	case pb.MessageType_MESSAGE_TYPE_REACTION:
+		return "reaction"
+	case pb.MessageType_MESSAGE_TYPE_MENTION:
+		return "mention"
+	case pb.MessageType_MESSAGE_TYPE_READ:
+		return "read"
+	case pb.MessageType_MESSAGE_TYPE_TYPING:
+		return "typing"
+	case pb.MessageType_MESSAGE_TYPE_RECORDING:
+		return "recording"
+	case pb.MessageType_MESSAGE_TYPE_PURGE:
+		return "purge"
+	case pb.MessageType_MESSAGE_TYPE_CHAT_RECORD:
+		return "chat_record"
+	case pb.MessageType_MESSAGE_TYPE_PIN:
+		return "pin"
+	case pb.MessageType_MESSAGE_TYPE_ANNOUNCEMENT:
+		return "announcement"
+	case pb.MessageType_MESSAGE_TYPE_LOCATION:
+		return "location"
+	case pb.MessageType_MESSAGE_TYPE_CONTACT:
+		return "contact"
+	case pb.MessageType_MESSAGE_TYPE_STICKER:
+		return "sticker"
+	case pb.MessageType_MESSAGE_TYPE_LINK:
+		return "link"
+	case pb.MessageType_MESSAGE_TYPE_POLL:
+		return "poll"
+	case pb.MessageType_MESSAGE_TYPE_VOTE:
+		return "vote"
+	case pb.MessageType_MESSAGE_TYPE_POLL_CLOSE:
+		return "poll_close"
+	case pb.MessageType_MESSAGE_TYPE_SYSTEM:
+		return "system"
+	case pb.MessageType_MESSAGE_TYPE_JOIN_REQUEST:
+		return "join_request"
+	case pb.MessageType_MESSAGE_TYPE_GROUP_EVENT:
+		return "group_event"
 	default:
 		return "unknown"
 	}
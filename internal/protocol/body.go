@@ -0,0 +1,353 @@
+package protocol
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"cursorIM/internal/constants"
+)
+
+// 结构化消息体各字段的长度限制（字符数）
+const (
+	maxBodyNameLength        = 100
+	maxBodyAddressLength     = 255
+	maxBodyTitleLength       = 200
+	maxBodyDescriptionLength = 500
+	maxBodyURLLength         = 2048
+	maxStickerSize           = 4096
+	maxPollQuestionLength    = 200
+	maxPollOptionLength      = 100
+	minPollOptions           = 2
+	maxPollOptions           = 20
+	maxPollDuration          = 365 * 24 * time.Hour
+)
+
+// Location 位置消息
+type Location struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Name      string  `json:"name,omitempty"`    // 地点名称
+	Address   string  `json:"address,omitempty"` // 详细地址
+}
+
+// ContactCard 名片消息，昵称和头像由服务器按用户资料填充
+type ContactCard struct {
+	UserID    string `json:"user_id"`
+	Nickname  string `json:"nickname,omitempty"`
+	AvatarURL string `json:"avatar_url,omitempty"`
+}
+
+// Sticker 表情包消息
+type Sticker struct {
+	StickerID string `json:"sticker_id"`
+	PackID    string `json:"pack_id,omitempty"`
+	URL       string `json:"url"`
+	Width     int    `json:"width,omitempty"`
+	Height    int    `json:"height,omitempty"`
+	Emoji     string `json:"emoji,omitempty"` // 表情对应的文字/emoji，用于摘要和搜索
+}
+
+// LinkPreview 链接卡片消息
+type LinkPreview struct {
+	URL         string `json:"url"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	ImageURL    string `json:"image_url,omitempty"`
+	SiteName    string `json:"site_name,omitempty"`
+}
+
+// Poll 群投票。发起时客户端只填写题目、选项文字和设置，选项ID、计票结果和结束状态由服务器填充
+type Poll struct {
+	Question   string        `json:"question"`
+	Options    []*PollOption `json:"options"`
+	Multiple   bool          `json:"multiple,omitempty"`  // 允许多选
+	Anonymous  bool          `json:"anonymous,omitempty"` // 匿名投票，不公开投票者
+	CloseAt    int64         `json:"close_at,omitempty"`  // 自动结束时间（Unix 秒），0 表示手动结束
+	Closed     bool          `json:"closed,omitempty"`
+	ClosedAt   int64         `json:"closed_at,omitempty"`
+	VoterCount int           `json:"voter_count"` // 参与投票的人数
+}
+
+// PollOption 投票选项及其计票结果
+type PollOption struct {
+	ID       string   `json:"id"`
+	Text     string   `json:"text"`
+	Count    int      `json:"count"`
+	VoterIDs []string `json:"voter_ids,omitempty"` // 实名投票时选择该选项的用户
+}
+
+// GroupEvent 群组变更事件，客户端据此实时更新成员列表和群资料
+type GroupEvent struct {
+	Event       string   `json:"event"`                 // 事件类型，见 constants.GroupEvent*
+	OperatorID  string   `json:"operator_id,omitempty"` // 操作者，成员自己加入或退出时为该成员
+	MemberIDs   []string `json:"member_ids,omitempty"`  // 受影响的成员
+	Name        string   `json:"name,omitempty"`        // 群名称（创建和改名时）
+	Role        int      `json:"role,omitempty"`        // 成员的新角色（角色变更时）
+	Description string   `json:"description,omitempty"` // 群简介（修改简介时，清空时省略）
+}
+
+// ValidateBody 校验消息类型与结构化消息体是否匹配，以及消息体各字段是否合法
+// 位置、名片、表情包、链接卡片和投票消息必须携带对应的消息体，其他类型不能携带消息体
+func ValidateBody(message *Message) error {
+	bodies := 0
+	for _, set := range []bool{message.Location != nil, message.Contact != nil, message.Sticker != nil, message.Link != nil, message.Poll != nil, message.GroupEvent != nil} {
+		if set {
+			bodies++
+		}
+	}
+	if bodies > 1 {
+		return errors.New("消息只能携带一种消息体")
+	}
+
+	switch message.Type {
+	case constants.MessageTypeLocation:
+		if message.Location == nil {
+			return errors.New("位置消息缺少位置信息")
+		}
+		return validateLocation(message.Location)
+	case constants.MessageTypeContact:
+		if message.Contact == nil {
+			return errors.New("名片消息缺少名片信息")
+		}
+		if message.Contact.UserID == "" {
+			return errors.New("名片缺少用户ID")
+		}
+		return nil
+	case constants.MessageTypeSticker:
+		if message.Sticker == nil {
+			return errors.New("表情包消息缺少表情信息")
+		}
+		return validateSticker(message.Sticker)
+	case constants.MessageTypeLink:
+		if message.Link == nil {
+			return errors.New("链接消息缺少链接信息")
+		}
+		return validateLink(message.Link)
+	case constants.MessageTypePoll:
+		if message.Poll == nil {
+			return errors.New("投票消息缺少投票信息")
+		}
+		if !message.IsGroup {
+			return errors.New("只能在群聊中发起投票")
+		}
+		return normalizePoll(message.Poll)
+	}
+
+	if bodies > 0 {
+		return errors.New("消息类型与消息体不匹配")
+	}
+	return nil
+}
+
+func validateLocation(location *Location) error {
+	if math.IsNaN(location.Latitude) || location.Latitude < -90 || location.Latitude > 90 {
+		return errors.New("纬度必须在 -90 到 90 之间")
+	}
+	if math.IsNaN(location.Longitude) || location.Longitude < -180 || location.Longitude > 180 {
+		return errors.New("经度必须在 -180 到 180 之间")
+	}
+	if utf8.RuneCountInString(location.Name) > maxBodyNameLength {
+		return fmt.Errorf("地点名称不能超过%d个字符", maxBodyNameLength)
+	}
+	if utf8.RuneCountInString(location.Address) > maxBodyAddressLength {
+		return fmt.Errorf("地址不能超过%d个字符", maxBodyAddressLength)
+	}
+	return nil
+}
+
+func validateSticker(sticker *Sticker) error {
+	if sticker.StickerID == "" {
+		return errors.New("表情包缺少表情ID")
+	}
+	if err := validateURL(sticker.URL, "表情地址"); err != nil {
+		return err
+	}
+	if sticker.Width < 0 || sticker.Width > maxStickerSize || sticker.Height < 0 || sticker.Height > maxStickerSize {
+		return fmt.Errorf("表情尺寸必须在 0 到 %d 之间", maxStickerSize)
+	}
+	if utf8.RuneCountInString(sticker.Emoji) > maxBodyNameLength {
+		return fmt.Errorf("表情文字不能超过%d个字符", maxBodyNameLength)
+	}
+	return nil
+}
+
+func validateLink(link *LinkPreview) error {
+	if err := validateURL(link.URL, "链接地址"); err != nil {
+		return err
+	}
+	if link.ImageURL != "" {
+		if err := validateURL(link.ImageURL, "链接图片地址"); err != nil {
+			return err
+		}
+	}
+	if utf8.RuneCountInString(link.Title) > maxBodyTitleLength {
+		return fmt.Errorf("链接标题不能超过%d个字符", maxBodyTitleLength)
+	}
+	if utf8.RuneCountInString(link.Description) > maxBodyDescriptionLength {
+		return fmt.Errorf("链接描述不能超过%d个字符", maxBodyDescriptionLength)
+	}
+	if utf8.RuneCountInString(link.SiteName) > maxBodyNameLength {
+		return fmt.Errorf("网站名称不能超过%d个字符", maxBodyNameLength)
+	}
+	return nil
+}
+
+// normalizePoll 校验新发起的投票，分配选项ID并清空客户端不能指定的结果字段
+func normalizePoll(poll *Poll) error {
+	poll.Question = strings.TrimSpace(poll.Question)
+	if poll.Question == "" {
+		return errors.New("投票题目不能为空")
+	}
+	if utf8.RuneCountInString(poll.Question) > maxPollQuestionLength {
+		return fmt.Errorf("投票题目不能超过%d个字符", maxPollQuestionLength)
+	}
+	if len(poll.Options) < minPollOptions || len(poll.Options) > maxPollOptions {
+		return fmt.Errorf("投票选项数量必须在 %d 到 %d 之间", minPollOptions, maxPollOptions)
+	}
+
+	seen := make(map[string]bool, len(poll.Options))
+	options := make([]*PollOption, 0, len(poll.Options))
+	for i, option := range poll.Options {
+		if option == nil {
+			return errors.New("投票选项不能为空")
+		}
+		text := strings.TrimSpace(option.Text)
+		if text == "" {
+			return errors.New("投票选项不能为空")
+		}
+		if utf8.RuneCountInString(text) > maxPollOptionLength {
+			return fmt.Errorf("投票选项不能超过%d个字符", maxPollOptionLength)
+		}
+		if seen[text] {
+			return errors.New("投票选项不能重复")
+		}
+		seen[text] = true
+		options = append(options, &PollOption{ID: strconv.Itoa(i + 1), Text: text})
+	}
+	poll.Options = options
+
+	if poll.CloseAt != 0 {
+		closeAt := time.Unix(poll.CloseAt, 0)
+		if !closeAt.After(time.Now()) {
+			return errors.New("投票结束时间必须晚于当前时间")
+		}
+		if closeAt.After(time.Now().Add(maxPollDuration)) {
+			return errors.New("投票结束时间不能超过一年")
+		}
+	}
+
+	poll.Closed, poll.ClosedAt, poll.VoterCount = false, 0, 0
+	return nil
+}
+
+// validateURL 只接受 http/https 的绝对地址
+func validateURL(raw, field string) error {
+	if raw == "" {
+		return fmt.Errorf("%s不能为空", field)
+	}
+	if len(raw) > maxBodyURLLength {
+		return fmt.Errorf("%s过长", field)
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("%s必须是 http 或 https 地址", field)
+	}
+	return nil
+}
+
+// BodySummary 生成结构化消息的文字摘要，作为 content 供会话列表、搜索和旧版客户端展示
+func BodySummary(message *Message) string {
+	switch {
+	case message.Location != nil:
+		if message.Location.Name != "" {
+			return "[位置] " + message.Location.Name
+		}
+		if message.Location.Address != "" {
+			return "[位置] " + message.Location.Address
+		}
+		return "[位置]"
+	case message.Contact != nil:
+		return "[名片] " + message.Contact.Nickname
+	case message.Sticker != nil:
+		if message.Sticker.Emoji != "" {
+			return "[表情] " + message.Sticker.Emoji
+		}
+		return "[表情]"
+	case message.Link != nil:
+		if message.Link.Title != "" {
+			return "[链接] " + message.Link.Title
+		}
+		return message.Link.URL
+	case message.Poll != nil:
+		return "[投票] " + message.Poll.Question
+	}
+	return ""
+}
+
+// EncodeBody 将结构化消息体序列化为 JSON（数据库中以文本保存），没有消息体时返回空字符串
+func EncodeBody(message *Message) (string, error) {
+	var body interface{}
+	switch {
+	case message.Location != nil:
+		body = message.Location
+	case message.Contact != nil:
+		body = message.Contact
+	case message.Sticker != nil:
+		body = message.Sticker
+	case message.Link != nil:
+		body = message.Link
+	case message.Poll != nil:
+		body = message.Poll
+	case message.GroupEvent != nil:
+		body = message.GroupEvent
+	default:
+		return "", nil
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// DecodeBody 按消息类型解析数据库中保存的结构化消息体，空字符串或格式错误时不做处理
+// 计票和结束投票事件的消息体为投票结果，群组事件的消息体为变更内容
+func DecodeBody(message *Message, data string) {
+	if data == "" {
+		return
+	}
+
+	var err error
+	switch message.Type {
+	case constants.MessageTypeLocation:
+		message.Location = &Location{}
+		err = json.Unmarshal([]byte(data), message.Location)
+	case constants.MessageTypeContact:
+		message.Contact = &ContactCard{}
+		err = json.Unmarshal([]byte(data), message.Contact)
+	case constants.MessageTypeSticker:
+		message.Sticker = &Sticker{}
+		err = json.Unmarshal([]byte(data), message.Sticker)
+	case constants.MessageTypeLink:
+		message.Link = &LinkPreview{}
+		err = json.Unmarshal([]byte(data), message.Link)
+	case constants.MessageTypePoll, constants.MessageTypeVote, constants.MessageTypePollClose:
+		message.Poll = &Poll{}
+		err = json.Unmarshal([]byte(data), message.Poll)
+	case constants.MessageTypeGroupEvent:
+		message.GroupEvent = &GroupEvent{}
+		err = json.Unmarshal([]byte(data), message.GroupEvent)
+	}
+	if err != nil {
+		message.Location, message.Contact, message.Sticker, message.Link, message.Poll = nil, nil, nil, nil, nil
+		message.GroupEvent = nil
+	}
+}
@@ -0,0 +1,131 @@
+package protocol
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"cursorIM/internal/constants"
+)
+
+func TestValidateBody(t *testing.T) {
+	validSticker := &Sticker{StickerID: "cat-01", URL: "https://cdn.example.com/cat-01.webp", Width: 240, Height: 240}
+	validLink := &LinkPreview{URL: "https://example.com/post/1", Title: "标题"}
+
+	tests := []struct {
+		name    string
+		message *Message
+		wantErr bool
+	}{
+		{"普通文本", &Message{Type: constants.MessageTypeText, Content: "你好"}, false},
+		{"文本携带消息体", &Message{Type: constants.MessageTypeText, Location: &Location{}}, true},
+		{"多个消息体", &Message{Type: constants.MessageTypeLocation, Location: &Location{}, Sticker: validSticker}, true},
+
+		{"位置", &Message{Type: constants.MessageTypeLocation, Location: &Location{Latitude: 39.9, Longitude: 116.4, Name: "天安门"}}, false},
+		{"位置缺少消息体", &Message{Type: constants.MessageTypeLocation}, true},
+		{"纬度越界", &Message{Type: constants.MessageTypeLocation, Location: &Location{Latitude: 91}}, true},
+		{"经度越界", &Message{Type: constants.MessageTypeLocation, Location: &Location{Longitude: -181}}, true},
+		{"纬度为 NaN", &Message{Type: constants.MessageTypeLocation, Location: &Location{Latitude: math.NaN()}}, true},
+		{"地址过长", &Message{Type: constants.MessageTypeLocation, Location: &Location{Address: strings.Repeat("路", maxBodyAddressLength+1)}}, true},
+
+		{"名片", &Message{Type: constants.MessageTypeContact, Contact: &ContactCard{UserID: "user2"}}, false},
+		{"名片缺少用户ID", &Message{Type: constants.MessageTypeContact, Contact: &ContactCard{}}, true},
+		{"名片缺少消息体", &Message{Type: constants.MessageTypeContact}, true},
+
+		{"表情包", &Message{Type: constants.MessageTypeSticker, Sticker: validSticker}, false},
+		{"表情包缺少ID", &Message{Type: constants.MessageTypeSticker, Sticker: &Sticker{URL: validSticker.URL}}, true},
+		{"表情包地址不是 http", &Message{Type: constants.MessageTypeSticker, Sticker: &Sticker{StickerID: "cat-01", URL: "javascript:alert(1)"}}, true},
+		{"表情包尺寸越界", &Message{Type: constants.MessageTypeSticker, Sticker: &Sticker{StickerID: "cat-01", URL: validSticker.URL, Width: maxStickerSize + 1}}, true},
+
+		{"链接卡片", &Message{Type: constants.MessageTypeLink, Link: validLink}, false},
+		{"链接缺少地址", &Message{Type: constants.MessageTypeLink, Link: &LinkPreview{Title: "标题"}}, true},
+		{"链接地址缺少主机", &Message{Type: constants.MessageTypeLink, Link: &LinkPreview{URL: "https://"}}, true},
+		{"链接图片地址非法", &Message{Type: constants.MessageTypeLink, Link: &LinkPreview{URL: validLink.URL, ImageURL: "ftp://example.com/a.png"}}, true},
+		{"链接标题过长", &Message{Type: constants.MessageTypeLink, Link: &LinkPreview{URL: validLink.URL, Title: strings.Repeat("字", maxBodyTitleLength+1)}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateBody(tt.message)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateBody() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidatePollBody(t *testing.T) {
+	options := func(texts ...string) []*PollOption {
+		result := make([]*PollOption, 0, len(texts))
+		for _, text := range texts {
+			result = append(result, &PollOption{Text: text})
+		}
+		return result
+	}
+	tooMany := make([]string, maxPollOptions+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("选项%d", i+1)
+	}
+
+	tests := []struct {
+		name        string
+		isGroup     bool
+		poll        *Poll
+		wantErr     bool
+		wantOptions []string // 校验通过后的选项ID
+	}{
+		{"群聊投票", true, &Poll{Question: " 周末去哪 ", Options: options("爬山", "看电影")}, false, []string{"1", "2"}},
+		{"单聊不能发起投票", false, &Poll{Question: "周末去哪", Options: options("爬山", "看电影")}, true, nil},
+		{"题目为空", true, &Poll{Question: "  ", Options: options("爬山", "看电影")}, true, nil},
+		{"选项太少", true, &Poll{Question: "周末去哪", Options: options("爬山")}, true, nil},
+		{"选项太多", true, &Poll{Question: "周末去哪", Options: options(tooMany...)}, true, nil},
+		{"选项为空", true, &Poll{Question: "周末去哪", Options: options("爬山", " ")}, true, nil},
+		{"选项重复", true, &Poll{Question: "周末去哪", Options: options("爬山", " 爬山")}, true, nil},
+		{"结束时间已过", true, &Poll{Question: "周末去哪", Options: options("爬山", "看电影"), CloseAt: 1}, true, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message := &Message{Type: constants.MessageTypePoll, IsGroup: tt.isGroup, Poll: tt.poll}
+			err := ValidateBody(message)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateBody() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if len(message.Poll.Options) != len(tt.wantOptions) {
+				t.Fatalf("选项数 = %d, want %d", len(message.Poll.Options), len(tt.wantOptions))
+			}
+			for i, option := range message.Poll.Options {
+				if option.ID != tt.wantOptions[i] {
+					t.Errorf("选项 %d 的ID = %q, want %q", i, option.ID, tt.wantOptions[i])
+				}
+			}
+		})
+	}
+}
+
+func TestValidatePollBodyResetsResults(t *testing.T) {
+	poll := &Poll{
+		Question:   "周末去哪",
+		Options:    []*PollOption{{ID: "x", Text: "爬山", Count: 9}, {Text: "看电影", VoterIDs: []string{"user1"}}},
+		Closed:     true,
+		ClosedAt:   1,
+		VoterCount: 9,
+	}
+	if err := ValidateBody(&Message{Type: constants.MessageTypePoll, IsGroup: true, Poll: poll}); err != nil {
+		t.Fatalf("ValidateBody() error = %v", err)
+	}
+
+	if poll.Question != "周末去哪" || poll.Closed || poll.ClosedAt != 0 || poll.VoterCount != 0 {
+		t.Errorf("客户端指定的结果字段未被清空: %+v", poll)
+	}
+	for _, option := range poll.Options {
+		if option.Count != 0 || len(option.VoterIDs) != 0 {
+			t.Errorf("选项 %s 的计票结果未被清空: %+v", option.ID, option)
+		}
+	}
+}
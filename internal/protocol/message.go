@@ -1,6 +1,11 @@
 package protocol
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+
+	"cursorIM/internal/constants"
+)
 
 type Message struct {
 	Version    string `json:"version"`     // 协议版本号
@@ -18,6 +23,25 @@ type Message struct {
 	IsGroup        bool      `json:"is_group,omitempty"`
 	GroupID        string    `json:"group_id,omitempty"` // 群组ID，用于群聊消息
 	Status         string    `json:"status,omitempty"`
+	Seq            int64     `json:"seq,omitempty"`            // 会话内序列号
+	TargetID       string    `json:"target_id,omitempty"`      // 指令操作的目标消息ID（撤回、编辑等）
+	Recalled       bool      `json:"recalled,omitempty"`       // 消息已撤回（内容为空）
+	EditedAt       int64     `json:"edited_at,omitempty"`      // 最后编辑时间，为 0 表示未编辑过
+	ReplyToID      string    `json:"reply_to_id,omitempty"`    // 回复/引用的父消息ID
+	ThreadID       string    `json:"thread_id,omitempty"`      // 所属话题的根消息ID
+	ReplyCount     int       `json:"reply_count,omitempty"`    // 作为话题根消息时的回复数
+	Emoji          string    `json:"emoji,omitempty"`          // 表情回应指令/事件中的表情
+	Remove         bool      `json:"remove,omitempty"`         // 表情回应指令/事件：取消回应
+	Mentions       []string  `json:"mentions,omitempty"`       // 显式提及的用户ID
+	MentionAll     bool      `json:"mention_all,omitempty"`    // @所有人（仅群主/群管理员）
+	MentionAdmins  bool      `json:"mention_admins,omitempty"` // @管理员
+	ReadCount      int       `json:"read_count,omitempty"`     // 群消息已读人数（不含发送者）
+	MemberCount    int       `json:"member_count,omitempty"`   // 群消息应读人数（不含发送者）
+	Active         bool      `json:"active,omitempty"`         // 临时信号：开始（true）或结束（false）
+	ClientMsgID    string    `json:"client_msg_id,omitempty"`  // 客户端生成的消息ID，重发时保持不变
+	TTL            int       `json:"ttl,omitempty"`            // 自毁消息的存活时间（秒）
+	ExpiresAt      int64     `json:"expires_at,omitempty"`     // 自毁消息的到期时间，阅后计时的消息在首次被阅读前为 0
+	TargetIDs      []string  `json:"target_ids,omitempty"`     // 清除通知中需要删除的消息ID
 	CreatedAt      time.Time `json:"-"`
 	UpdatedAt      time.Time `json:"-"`
 	HandledByLocal bool      `json:"handledByLocal"`
@@ -30,4 +54,147 @@ type Message struct {
 
 	// 扩展元数据
 	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// 被引用消息的快照（由服务器填充）
+	Quote *QuotedMessage `json:"quote,omitempty"`
+
+	// 表情回应汇总（由服务器填充）
+	Reactions []*ReactionSummary `json:"reactions,omitempty"`
+
+	// 转发来源（由服务器填充）
+	Forward *ForwardInfo `json:"forward,omitempty"`
+
+	// 合并转发的聊天记录（类型为 chat_record 时）
+	Record *ChatRecord `json:"record,omitempty"`
+
+	// 结构化消息体，最多设置一个，与消息类型对应（对应 Protobuf 中的 oneof body）
+	Location *Location    `json:"location,omitempty"` // 类型为 location 时
+	Contact  *ContactCard `json:"contact,omitempty"`  // 类型为 contact 时
+	Sticker  *Sticker     `json:"sticker,omitempty"`  // 类型为 sticker 时
+	Link     *LinkPreview `json:"link,omitempty"`     // 类型为 link 时
+	Poll     *Poll        `json:"poll,omitempty"`     // 类型为 poll 时；计票和结束事件中为最新结果
+
+	GroupEvent *GroupEvent `json:"group_event,omitempty"` // 类型为 group_event 时
+
+	// 投票指令选择的选项ID
+	OptionIDs []string `json:"option_ids,omitempty"`
+
+	// 批量消息（用于增量同步等）
+	Batch *MessageBatch `json:"batch,omitempty"`
+}
+
+// ReactionSummary 某个表情的回应汇总
+type ReactionSummary struct {
+	Emoji   string   `json:"emoji"`
+	Count   int      `json:"count"`
+	UserIDs []string `json:"user_ids"`
+}
+
+// ForwardInfo 转发消息的来源，多次转发时始终指向最初的消息
+type ForwardInfo struct {
+	MessageID      string `json:"message_id"`
+	SenderID       string `json:"sender_id"`
+	ConversationID string `json:"conversation_id"`
+}
+
+// ChatRecord 合并转发的聊天记录卡片
+type ChatRecord struct {
+	Title string            `json:"title"`
+	Items []*ChatRecordItem `json:"items"`
+}
+
+// ChatRecordItem 聊天记录中的一条消息，媒体消息的内容为原始地址
+type ChatRecordItem struct {
+	ID        string `json:"id"`
+	SenderID  string `json:"sender_id"`
+	Type      string `json:"type"`
+	Content   string `json:"content"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// QuotedMessage 被引用消息的快照
+type QuotedMessage struct {
+	ID       string `json:"id"`
+	SenderID string `json:"sender_id"`
+	Type     string `json:"type"`
+	Content  string `json:"content"`
+}
+
+// MessageBatch 批量消息
+type MessageBatch struct {
+	Messages   []*Message `json:"messages"`
+	TotalCount int        `json:"total_count"`
+	HasMore    bool       `json:"has_more"`
+}
+
+// EncodeChatRecord 将聊天记录序列化为 JSON（数据库中以文本保存），nil 返回空字符串
+func EncodeChatRecord(record *ChatRecord) (string, error) {
+	if record == nil {
+		return "", nil
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// DecodeChatRecord 解析数据库中保存的聊天记录，空字符串或格式错误时返回 nil
+func DecodeChatRecord(data string) *ChatRecord {
+	if data == "" {
+		return nil
+	}
+	var record ChatRecord
+	if err := json.Unmarshal([]byte(data), &record); err != nil {
+		return nil
+	}
+	return &record
+}
+
+// IsEphemeral 判断消息是否为临时信号（正在输入、正在录音等）
+// 临时信号只投递给在线的接收者，不保存到数据库，也不进入离线队列
+func IsEphemeral(msgType string) bool {
+	switch msgType {
+	case constants.MessageTypeTyping, constants.MessageTypeRecording:
+		return true
+	}
+	return false
+}
+
+// IsNotice 判断消息是否为服务器推送的通知（撤回、编辑、表情回应、提及、已读、置顶、群事件等）
+// 通知只同步其他消息或群组的变化，本身没有送达状态，客户端对通知的确认不更新消息状态
+func IsNotice(msgType string) bool {
+	switch msgType {
+	case constants.MessageTypeRecall, constants.MessageTypeEdit, constants.MessageTypeReaction,
+		constants.MessageTypeMention, constants.MessageTypeRead, constants.MessageTypePurge,
+		constants.MessageTypePin, constants.MessageTypeAnnouncement, constants.MessageTypeVote,
+		constants.MessageTypePollClose, constants.MessageTypeJoinRequest, constants.MessageTypeGroupEvent:
+		return true
+	}
+	return false
+}
+
+// IsControl 判断消息是否为连接层的控制帧（心跳、状态、确认、错误、同步和请求响应）
+// 控制帧只对当前连接有意义，接收者不在线时直接丢弃，不进入离线队列
+func IsControl(msgType string) bool {
+	switch msgType {
+	case constants.MessageTypePing, constants.MessageTypePong, constants.MessageTypeStatus,
+		constants.MessageTypeAck, constants.MessageTypeError, constants.MessageTypeSync,
+		constants.MessageTypeResponse:
+		return true
+	}
+	return false
+}
+
+// RequiresRecipient 判断客户端发来的该类型消息是否必须携带接收者ID
+// 心跳、状态以及确认、同步、撤回、编辑、表情回应、已读、投票等指令不需要接收者
+func RequiresRecipient(msgType string) bool {
+	switch msgType {
+	case constants.MessageTypePing, constants.MessageTypePong, constants.MessageTypeStatus,
+		constants.MessageTypeAck, constants.MessageTypeSync, constants.MessageTypeRecall,
+		constants.MessageTypeEdit, constants.MessageTypeReaction,
+		constants.MessageTypeRead, constants.MessageTypeVote, constants.MessageTypePollClose:
+		return false
+	}
+	return true
 }
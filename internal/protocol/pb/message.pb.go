@@ -25,18 +25,41 @@ const (
 type MessageType int32
 
 const (
-	MessageType_MESSAGE_TYPE_UNKNOWN  MessageType = 0
-	MessageType_MESSAGE_TYPE_TEXT     MessageType = 1
-	MessageType_MESSAGE_TYPE_IMAGE    MessageType = 2
-	MessageType_MESSAGE_TYPE_FILE     MessageType = 3
-	MessageType_MESSAGE_TYPE_AUDIO    MessageType = 4
-	MessageType_MESSAGE_TYPE_VIDEO    MessageType = 5
-	MessageType_MESSAGE_TYPE_PING     MessageType = 6
-	MessageType_MESSAGE_TYPE_PONG     MessageType = 7
-	MessageType_MESSAGE_TYPE_STATUS   MessageType = 8
-	MessageType_MESSAGE_TYPE_COMMAND  MessageType = 9
-	MessageType_MESSAGE_TYPE_RESPONSE MessageType = 10
-	MessageType_MESSAGE_TYPE_ERROR    MessageType = 11
+	MessageType_MESSAGE_TYPE_UNKNOWN      MessageType = 0
+	MessageType_MESSAGE_TYPE_TEXT         MessageType = 1
+	MessageType_MESSAGE_TYPE_IMAGE        MessageType = 2
+	MessageType_MESSAGE_TYPE_FILE         MessageType = 3
+	MessageType_MESSAGE_TYPE_AUDIO        MessageType = 4
+	MessageType_MESSAGE_TYPE_VIDEO        MessageType = 5
+	MessageType_MESSAGE_TYPE_PING         MessageType = 6
+	MessageType_MESSAGE_TYPE_PONG         MessageType = 7
+	MessageType_MESSAGE_TYPE_STATUS       MessageType = 8
+	MessageType_MESSAGE_TYPE_COMMAND      MessageType = 9
+	MessageType_MESSAGE_TYPE_RESPONSE     MessageType = 10
+	MessageType_MESSAGE_TYPE_ERROR        MessageType = 11
+	MessageType_MESSAGE_TYPE_ACK          MessageType = 12 // 消息送达确认
+	MessageType_MESSAGE_TYPE_SYNC         MessageType = 13 // 增量同步
+	MessageType_MESSAGE_TYPE_RECALL       MessageType = 14 // 撤回消息
+	MessageType_MESSAGE_TYPE_EDIT         MessageType = 15 // 编辑消息
+	MessageType_MESSAGE_TYPE_REACTION     MessageType = 16 // 表情回应
+	MessageType_MESSAGE_TYPE_MENTION      MessageType = 17 // 被提及提醒
+	MessageType_MESSAGE_TYPE_READ         MessageType = 18 // 已读上报 / 已读回执
+	MessageType_MESSAGE_TYPE_TYPING       MessageType = 19 // 正在输入（临时信号）
+	MessageType_MESSAGE_TYPE_RECORDING    MessageType = 20 // 正在录音（临时信号）
+	MessageType_MESSAGE_TYPE_PURGE        MessageType = 21 // 清除到期的自毁消息
+	MessageType_MESSAGE_TYPE_CHAT_RECORD  MessageType = 22 // 合并转发的聊天记录卡片
+	MessageType_MESSAGE_TYPE_PIN          MessageType = 23 // 置顶/取消置顶消息
+	MessageType_MESSAGE_TYPE_ANNOUNCEMENT MessageType = 24 // 群公告变更
+	MessageType_MESSAGE_TYPE_LOCATION     MessageType = 25 // 位置
+	MessageType_MESSAGE_TYPE_CONTACT      MessageType = 26 // 名片
+	MessageType_MESSAGE_TYPE_STICKER      MessageType = 27 // 表情包
+	MessageType_MESSAGE_TYPE_LINK         MessageType = 28 // 链接卡片
+	MessageType_MESSAGE_TYPE_POLL         MessageType = 29 // 群投票
+	MessageType_MESSAGE_TYPE_VOTE         MessageType = 30 // 投票指令 / 实时计票事件
+	MessageType_MESSAGE_TYPE_POLL_CLOSE   MessageType = 31 // 结束投票指令 / 最终结果事件
+	MessageType_MESSAGE_TYPE_SYSTEM       MessageType = 32 // 群系统消息
+	MessageType_MESSAGE_TYPE_JOIN_REQUEST MessageType = 33 // 入群申请事件
+	MessageType_MESSAGE_TYPE_GROUP_EVENT  MessageType = 34 // 群组变更事件
 )
 
 // Enum value maps for MessageType.
@@ -54,20 +77,66 @@ var (
 		9:  "MESSAGE_TYPE_COMMAND",
 		10: "MESSAGE_TYPE_RESPONSE",
 		11: "MESSAGE_TYPE_ERROR",
+		12: "MESSAGE_TYPE_ACK",
+		13: "MESSAGE_TYPE_SYNC",
+		14: "MESSAGE_TYPE_RECALL",
+		15: "MESSAGE_TYPE_EDIT",
+		16: "MESSAGE_TYPE_REACTION",
+		17: "MESSAGE_TYPE_MENTION",
+		18: "MESSAGE_TYPE_READ",
+		19: "MESSAGE_TYPE_TYPING",
+		20: "MESSAGE_TYPE_RECORDING",
+		21: "MESSAGE_TYPE_PURGE",
+		22: "MESSAGE_TYPE_CHAT_RECORD",
+		23: "MESSAGE_TYPE_PIN",
+		24: "MESSAGE_TYPE_ANNOUNCEMENT",
+		25: "MESSAGE_TYPE_LOCATION",
+		26: "MESSAGE_TYPE_CONTACT",
+		27: "MESSAGE_TYPE_STICKER",
+		28: "MESSAGE_TYPE_LINK",
+		29: "MESSAGE_TYPE_POLL",
+		30: "MESSAGE_TYPE_VOTE",
+		31: "MESSAGE_TYPE_POLL_CLOSE",
+		32: "MESSAGE_TYPE_SYSTEM",
+		33: "MESSAGE_TYPE_JOIN_REQUEST",
+		34: "MESSAGE_TYPE_GROUP_EVENT",
 	}
 	MessageType_value = map[string]int32{
-		"MESSAGE_TYPE_UNKNOWN":  0,
-		"MESSAGE_TYPE_TEXT":     1,
-		"MESSAGE_TYPE_IMAGE":    2,
-		"MESSAGE_TYPE_FILE":     3,
-		"MESSAGE_TYPE_AUDIO":    4,
-		"MESSAGE_TYPE_VIDEO":    5,
-		"MESSAGE_TYPE_PING":     6,
-		"MESSAGE_TYPE_PONG":     7,
-		"MESSAGE_TYPE_STATUS":   8,
-		"MESSAGE_TYPE_COMMAND":  9,
-		"MESSAGE_TYPE_RESPONSE": 10,
-		"MESSAGE_TYPE_ERROR":    11,
+		"MESSAGE_TYPE_UNKNOWN":      0,
+		"MESSAGE_TYPE_TEXT":         1,
+		"MESSAGE_TYPE_IMAGE":        2,
+		"MESSAGE_TYPE_FILE":         3,
+		"MESSAGE_TYPE_AUDIO":        4,
+		"MESSAGE_TYPE_VIDEO":        5,
+		"MESSAGE_TYPE_PING":         6,
+		"MESSAGE_TYPE_PONG":         7,
+		"MESSAGE_TYPE_STATUS":       8,
+		"MESSAGE_TYPE_COMMAND":      9,
+		"MESSAGE_TYPE_RESPONSE":     10,
+		"MESSAGE_TYPE_ERROR":        11,
+		"MESSAGE_TYPE_ACK":          12,
+		"MESSAGE_TYPE_SYNC":         13,
+		"MESSAGE_TYPE_RECALL":       14,
+		"MESSAGE_TYPE_EDIT":         15,
+		"MESSAGE_TYPE_REACTION":     16,
+		"MESSAGE_TYPE_MENTION":      17,
+		"MESSAGE_TYPE_READ":         18,
+		"MESSAGE_TYPE_TYPING":       19,
+		"MESSAGE_TYPE_RECORDING":    20,
+		"MESSAGE_TYPE_PURGE":        21,
+		"MESSAGE_TYPE_CHAT_RECORD":  22,
+		"MESSAGE_TYPE_PIN":          23,
+		"MESSAGE_TYPE_ANNOUNCEMENT": 24,
+		"MESSAGE_TYPE_LOCATION":     25,
+		"MESSAGE_TYPE_CONTACT":      26,
+		"MESSAGE_TYPE_STICKER":      27,
+		"MESSAGE_TYPE_LINK":         28,
+		"MESSAGE_TYPE_POLL":         29,
+		"MESSAGE_TYPE_VOTE":         30,
+		"MESSAGE_TYPE_POLL_CLOSE":   31,
+		"MESSAGE_TYPE_SYSTEM":       32,
+		"MESSAGE_TYPE_JOIN_REQUEST": 33,
+		"MESSAGE_TYPE_GROUP_EVENT":  34,
 	}
 )
 
@@ -232,7 +301,57 @@ type Message struct {
 	// 扩展元数据
 	Metadata map[string]string `protobuf:"bytes,17,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 	// 媒体文件信息（用于图片、音频、视频等）
-	MediaInfo     *MediaInfo `protobuf:"bytes,18,opt,name=media_info,json=mediaInfo,proto3" json:"media_info,omitempty"`
+	MediaInfo *MediaInfo `protobuf:"bytes,18,opt,name=media_info,json=mediaInfo,proto3" json:"media_info,omitempty"`
+	// 会话内序列号
+	Seq int64 `protobuf:"varint,19,opt,name=seq,proto3" json:"seq,omitempty"`
+	// 批量消息（用于增量同步等）
+	Batch *MessageBatch `protobuf:"bytes,20,opt,name=batch,proto3" json:"batch,omitempty"`
+	// 指令操作的目标消息ID（撤回、编辑等）
+	TargetId string `protobuf:"bytes,21,opt,name=target_id,json=targetId,proto3" json:"target_id,omitempty"`
+	// 消息已撤回（内容为空）
+	Recalled bool `protobuf:"varint,22,opt,name=recalled,proto3" json:"recalled,omitempty"`
+	// 最后编辑时间，为 0 表示未编辑过
+	EditedAt int64 `protobuf:"varint,23,opt,name=edited_at,json=editedAt,proto3" json:"edited_at,omitempty"`
+	// 回复与话题
+	ReplyToId  string         `protobuf:"bytes,24,opt,name=reply_to_id,json=replyToId,proto3" json:"reply_to_id,omitempty"`   // 回复/引用的父消息ID
+	Quote      *QuotedMessage `protobuf:"bytes,25,opt,name=quote,proto3" json:"quote,omitempty"`                              // 被引用消息的快照（由服务器填充）
+	ThreadId   string         `protobuf:"bytes,26,opt,name=thread_id,json=threadId,proto3" json:"thread_id,omitempty"`        // 所属话题的根消息ID
+	ReplyCount int32          `protobuf:"varint,27,opt,name=reply_count,json=replyCount,proto3" json:"reply_count,omitempty"` // 作为话题根消息时的回复数
+	// 表情回应
+	Emoji     string             `protobuf:"bytes,28,opt,name=emoji,proto3" json:"emoji,omitempty"`         // 表情回应指令/事件中的表情
+	Remove    bool               `protobuf:"varint,29,opt,name=remove,proto3" json:"remove,omitempty"`      // 表情回应指令/事件：取消回应
+	Reactions []*ReactionSummary `protobuf:"bytes,30,rep,name=reactions,proto3" json:"reactions,omitempty"` // 表情回应汇总（由服务器填充）
+	// 提及
+	Mentions      []string `protobuf:"bytes,31,rep,name=mentions,proto3" json:"mentions,omitempty"`                                 // 显式提及的用户ID
+	MentionAll    bool     `protobuf:"varint,32,opt,name=mention_all,json=mentionAll,proto3" json:"mention_all,omitempty"`          // @所有人（仅群主/群管理员）
+	MentionAdmins bool     `protobuf:"varint,33,opt,name=mention_admins,json=mentionAdmins,proto3" json:"mention_admins,omitempty"` // @管理员
+	// 已读回执
+	ReadCount   int32 `protobuf:"varint,34,opt,name=read_count,json=readCount,proto3" json:"read_count,omitempty"`       // 群消息已读人数（不含发送者）
+	MemberCount int32 `protobuf:"varint,35,opt,name=member_count,json=memberCount,proto3" json:"member_count,omitempty"` // 群消息应读人数（不含发送者）
+	// 临时信号
+	Active bool `protobuf:"varint,36,opt,name=active,proto3" json:"active,omitempty"` // 开始（true）或结束（false）
+	// 幂等发送
+	ClientMsgId string `protobuf:"bytes,37,opt,name=client_msg_id,json=clientMsgId,proto3" json:"client_msg_id,omitempty"` // 客户端生成的消息ID，重发时保持不变
+	// 自毁消息
+	Ttl       int32    `protobuf:"varint,38,opt,name=ttl,proto3" json:"ttl,omitempty"`                              // 消息存活时间（秒），0 表示不自动删除
+	ExpiresAt int64    `protobuf:"varint,39,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"` // 到期时间（Unix 秒），阅后计时的消息在首次被阅读前为 0
+	TargetIds []string `protobuf:"bytes,40,rep,name=target_ids,json=targetIds,proto3" json:"target_ids,omitempty"`  // 清除通知中需要删除的消息ID
+	// 转发
+	Forward *ForwardInfo `protobuf:"bytes,41,opt,name=forward,proto3" json:"forward,omitempty"` // 转发消息的来源（由服务器填充）
+	Record  *ChatRecord  `protobuf:"bytes,42,opt,name=record,proto3" json:"record,omitempty"`   // 合并转发的聊天记录（类型为 chat_record 时）
+	// 结构化消息体，与消息类型对应
+	//
+	// Types that are valid to be assigned to Body:
+	//
+	//	*Message_Location
+	//	*Message_Contact
+	//	*Message_Sticker
+	//	*Message_Link
+	//	*Message_Poll
+	//	*Message_GroupEvent
+	Body isMessage_Body `protobuf_oneof:"body"`
+	// 投票
+	OptionIds     []string `protobuf:"bytes,48,rep,name=option_ids,json=optionIds,proto3" json:"option_ids,omitempty"` // 投票指令选择的选项ID
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -393,6 +512,1150 @@ func (x *Message) GetMediaInfo() *MediaInfo {
 	return nil
 }
 
+func (x *Message) GetSeq() int64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *Message) GetBatch() *MessageBatch {
+	if x != nil {
+		return x.Batch
+	}
+	return nil
+}
+
+func (x *Message) GetTargetId() string {
+	if x != nil {
+		return x.TargetId
+	}
+	return ""
+}
+
+func (x *Message) GetRecalled() bool {
+	if x != nil {
+		return x.Recalled
+	}
+	return false
+}
+
+func (x *Message) GetEditedAt() int64 {
+	if x != nil {
+		return x.EditedAt
+	}
+	return 0
+}
+
+func (x *Message) GetReplyToId() string {
+	if x != nil {
+		return x.ReplyToId
+	}
+	return ""
+}
+
+func (x *Message) GetQuote() *QuotedMessage {
+	if x != nil {
+		return x.Quote
+	}
+	return nil
+}
+
+func (x *Message) GetThreadId() string {
+	if x != nil {
+		return x.ThreadId
+	}
+	return ""
+}
+
+func (x *Message) GetReplyCount() int32 {
+	if x != nil {
+		return x.ReplyCount
+	}
+	return 0
+}
+
+func (x *Message) GetEmoji() string {
+	if x != nil {
+		return x.Emoji
+	}
+	return ""
+}
+
+func (x *Message) GetRemove() bool {
+	if x != nil {
+		return x.Remove
+	}
+	return false
+}
+
+func (x *Message) GetReactions() []*ReactionSummary {
+	if x != nil {
+		return x.Reactions
+	}
+	return nil
+}
+
+func (x *Message) GetMentions() []string {
+	if x != nil {
+		return x.Mentions
+	}
+	return nil
+}
+
+func (x *Message) GetMentionAll() bool {
+	if x != nil {
+		return x.MentionAll
+	}
+	return false
+}
+
+func (x *Message) GetMentionAdmins() bool {
+	if x != nil {
+		return x.MentionAdmins
+	}
+	return false
+}
+
+func (x *Message) GetReadCount() int32 {
+	if x != nil {
+		return x.ReadCount
+	}
+	return 0
+}
+
+func (x *Message) GetMemberCount() int32 {
+	if x != nil {
+		return x.MemberCount
+	}
+	return 0
+}
+
+func (x *Message) GetActive() bool {
+	if x != nil {
+		return x.Active
+	}
+	return false
+}
+
+func (x *Message) GetClientMsgId() string {
+	if x != nil {
+		return x.ClientMsgId
+	}
+	return ""
+}
+
+func (x *Message) GetTtl() int32 {
+	if x != nil {
+		return x.Ttl
+	}
+	return 0
+}
+
+func (x *Message) GetExpiresAt() int64 {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return 0
+}
+
+func (x *Message) GetTargetIds() []string {
+	if x != nil {
+		return x.TargetIds
+	}
+	return nil
+}
+
+func (x *Message) GetForward() *ForwardInfo {
+	if x != nil {
+		return x.Forward
+	}
+	return nil
+}
+
+func (x *Message) GetRecord() *ChatRecord {
+	if x != nil {
+		return x.Record
+	}
+	return nil
+}
+
+func (x *Message) GetBody() isMessage_Body {
+	if x != nil {
+		return x.Body
+	}
+	return nil
+}
+
+func (x *Message) GetLocation() *Location {
+	if x != nil {
+		if x, ok := x.Body.(*Message_Location); ok {
+			return x.Location
+		}
+	}
+	return nil
+}
+
+func (x *Message) GetContact() *ContactCard {
+	if x != nil {
+		if x, ok := x.Body.(*Message_Contact); ok {
+			return x.Contact
+		}
+	}
+	return nil
+}
+
+func (x *Message) GetSticker() *Sticker {
+	if x != nil {
+		if x, ok := x.Body.(*Message_Sticker); ok {
+			return x.Sticker
+		}
+	}
+	return nil
+}
+
+func (x *Message) GetLink() *LinkPreview {
+	if x != nil {
+		if x, ok := x.Body.(*Message_Link); ok {
+			return x.Link
+		}
+	}
+	return nil
+}
+
+func (x *Message) GetPoll() *Poll {
+	if x != nil {
+		if x, ok := x.Body.(*Message_Poll); ok {
+			return x.Poll
+		}
+	}
+	return nil
+}
+
+func (x *Message) GetGroupEvent() *GroupEvent {
+	if x != nil {
+		if x, ok := x.Body.(*Message_GroupEvent); ok {
+			return x.GroupEvent
+		}
+	}
+	return nil
+}
+
+func (x *Message) GetOptionIds() []string {
+	if x != nil {
+		return x.OptionIds
+	}
+	return nil
+}
+
+type isMessage_Body interface {
+	isMessage_Body()
+}
+
+type Message_Location struct {
+	Location *Location `protobuf:"bytes,43,opt,name=location,proto3,oneof"` // 类型为 MESSAGE_TYPE_LOCATION 时
+}
+
+type Message_Contact struct {
+	Contact *ContactCard `protobuf:"bytes,44,opt,name=contact,proto3,oneof"` // 类型为 MESSAGE_TYPE_CONTACT 时
+}
+
+type Message_Sticker struct {
+	Sticker *Sticker `protobuf:"bytes,45,opt,name=sticker,proto3,oneof"` // 类型为 MESSAGE_TYPE_STICKER 时
+}
+
+type Message_Link struct {
+	Link *LinkPreview `protobuf:"bytes,46,opt,name=link,proto3,oneof"` // 类型为 MESSAGE_TYPE_LINK 时
+}
+
+type Message_Poll struct {
+	Poll *Poll `protobuf:"bytes,47,opt,name=poll,proto3,oneof"` // 类型为 MESSAGE_TYPE_POLL 时；计票和结束事件中为最新结果
+}
+
+type Message_GroupEvent struct {
+	GroupEvent *GroupEvent `protobuf:"bytes,49,opt,name=group_event,json=groupEvent,proto3,oneof"` // 类型为 MESSAGE_TYPE_GROUP_EVENT 时
+}
+
+func (*Message_Location) isMessage_Body() {}
+
+func (*Message_Contact) isMessage_Body() {}
+
+func (*Message_Sticker) isMessage_Body() {}
+
+func (*Message_Link) isMessage_Body() {}
+
+func (*Message_Poll) isMessage_Body() {}
+
+func (*Message_GroupEvent) isMessage_Body() {}
+
+// 位置
+type Location struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Latitude      float64                `protobuf:"fixed64,1,opt,name=latitude,proto3" json:"latitude,omitempty"`
+	Longitude     float64                `protobuf:"fixed64,2,opt,name=longitude,proto3" json:"longitude,omitempty"`
+	Name          string                 `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`       // 地点名称
+	Address       string                 `protobuf:"bytes,4,opt,name=address,proto3" json:"address,omitempty"` // 详细地址
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Location) Reset() {
+	*x = Location{}
+	mi := &file_proto_message_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Location) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Location) ProtoMessage() {}
+
+func (x *Location) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_message_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Location.ProtoReflect.Descriptor instead.
+func (*Location) Descriptor() ([]byte, []int) {
+	return file_proto_message_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Location) GetLatitude() float64 {
+	if x != nil {
+		return x.Latitude
+	}
+	return 0
+}
+
+func (x *Location) GetLongitude() float64 {
+	if x != nil {
+		return x.Longitude
+	}
+	return 0
+}
+
+func (x *Location) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Location) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+// 名片（昵称和头像由服务器填充）
+type ContactCard struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Nickname      string                 `protobuf:"bytes,2,opt,name=nickname,proto3" json:"nickname,omitempty"`
+	AvatarUrl     string                 `protobuf:"bytes,3,opt,name=avatar_url,json=avatarUrl,proto3" json:"avatar_url,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ContactCard) Reset() {
+	*x = ContactCard{}
+	mi := &file_proto_message_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ContactCard) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ContactCard) ProtoMessage() {}
+
+func (x *ContactCard) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_message_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ContactCard.ProtoReflect.Descriptor instead.
+func (*ContactCard) Descriptor() ([]byte, []int) {
+	return file_proto_message_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ContactCard) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *ContactCard) GetNickname() string {
+	if x != nil {
+		return x.Nickname
+	}
+	return ""
+}
+
+func (x *ContactCard) GetAvatarUrl() string {
+	if x != nil {
+		return x.AvatarUrl
+	}
+	return ""
+}
+
+// 表情包
+type Sticker struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	StickerId     string                 `protobuf:"bytes,1,opt,name=sticker_id,json=stickerId,proto3" json:"sticker_id,omitempty"`
+	PackId        string                 `protobuf:"bytes,2,opt,name=pack_id,json=packId,proto3" json:"pack_id,omitempty"`
+	Url           string                 `protobuf:"bytes,3,opt,name=url,proto3" json:"url,omitempty"`
+	Width         int32                  `protobuf:"varint,4,opt,name=width,proto3" json:"width,omitempty"`
+	Height        int32                  `protobuf:"varint,5,opt,name=height,proto3" json:"height,omitempty"`
+	Emoji         string                 `protobuf:"bytes,6,opt,name=emoji,proto3" json:"emoji,omitempty"` // 表情对应的文字/emoji
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Sticker) Reset() {
+	*x = Sticker{}
+	mi := &file_proto_message_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Sticker) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Sticker) ProtoMessage() {}
+
+func (x *Sticker) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_message_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Sticker.ProtoReflect.Descriptor instead.
+func (*Sticker) Descriptor() ([]byte, []int) {
+	return file_proto_message_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Sticker) GetStickerId() string {
+	if x != nil {
+		return x.StickerId
+	}
+	return ""
+}
+
+func (x *Sticker) GetPackId() string {
+	if x != nil {
+		return x.PackId
+	}
+	return ""
+}
+
+func (x *Sticker) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *Sticker) GetWidth() int32 {
+	if x != nil {
+		return x.Width
+	}
+	return 0
+}
+
+func (x *Sticker) GetHeight() int32 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *Sticker) GetEmoji() string {
+	if x != nil {
+		return x.Emoji
+	}
+	return ""
+}
+
+// 群投票（选项ID、计票结果和结束状态由服务器填充）
+type Poll struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Question      string                 `protobuf:"bytes,1,opt,name=question,proto3" json:"question,omitempty"`
+	Options       []*PollOption          `protobuf:"bytes,2,rep,name=options,proto3" json:"options,omitempty"`
+	Multiple      bool                   `protobuf:"varint,3,opt,name=multiple,proto3" json:"multiple,omitempty"`              // 允许多选
+	Anonymous     bool                   `protobuf:"varint,4,opt,name=anonymous,proto3" json:"anonymous,omitempty"`            // 匿名投票
+	CloseAt       int64                  `protobuf:"varint,5,opt,name=close_at,json=closeAt,proto3" json:"close_at,omitempty"` // 自动结束时间，0 表示手动结束
+	Closed        bool                   `protobuf:"varint,6,opt,name=closed,proto3" json:"closed,omitempty"`
+	ClosedAt      int64                  `protobuf:"varint,7,opt,name=closed_at,json=closedAt,proto3" json:"closed_at,omitempty"`
+	VoterCount    int32                  `protobuf:"varint,8,opt,name=voter_count,json=voterCount,proto3" json:"voter_count,omitempty"` // 参与投票的人数
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Poll) Reset() {
+	*x = Poll{}
+	mi := &file_proto_message_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Poll) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Poll) ProtoMessage() {}
+
+func (x *Poll) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_message_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Poll.ProtoReflect.Descriptor instead.
+func (*Poll) Descriptor() ([]byte, []int) {
+	return file_proto_message_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Poll) GetQuestion() string {
+	if x != nil {
+		return x.Question
+	}
+	return ""
+}
+
+func (x *Poll) GetOptions() []*PollOption {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+func (x *Poll) GetMultiple() bool {
+	if x != nil {
+		return x.Multiple
+	}
+	return false
+}
+
+func (x *Poll) GetAnonymous() bool {
+	if x != nil {
+		return x.Anonymous
+	}
+	return false
+}
+
+func (x *Poll) GetCloseAt() int64 {
+	if x != nil {
+		return x.CloseAt
+	}
+	return 0
+}
+
+func (x *Poll) GetClosed() bool {
+	if x != nil {
+		return x.Closed
+	}
+	return false
+}
+
+func (x *Poll) GetClosedAt() int64 {
+	if x != nil {
+		return x.ClosedAt
+	}
+	return 0
+}
+
+func (x *Poll) GetVoterCount() int32 {
+	if x != nil {
+		return x.VoterCount
+	}
+	return 0
+}
+
+// 群组变更事件
+type GroupEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Event         string                 `protobuf:"bytes,1,opt,name=event,proto3" json:"event,omitempty"`                             // created/member_joined/member_left/member_removed/renamed/description_changed/role_changed/owner_changed/dissolved
+	OperatorId    string                 `protobuf:"bytes,2,opt,name=operator_id,json=operatorId,proto3" json:"operator_id,omitempty"` // 操作者
+	MemberIds     []string               `protobuf:"bytes,3,rep,name=member_ids,json=memberIds,proto3" json:"member_ids,omitempty"`    // 受影响的成员
+	Name          string                 `protobuf:"bytes,4,opt,name=name,proto3" json:"name,omitempty"`                               // 群名称（创建和改名时）
+	Role          int32                  `protobuf:"varint,5,opt,name=role,proto3" json:"role,omitempty"`                              // 成员的新角色（角色变更时）
+	Description   string                 `protobuf:"bytes,6,opt,name=description,proto3" json:"description,omitempty"`                 // 群简介（修改简介时）
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GroupEvent) Reset() {
+	*x = GroupEvent{}
+	mi := &file_proto_message_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GroupEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GroupEvent) ProtoMessage() {}
+
+func (x *GroupEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_message_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GroupEvent.ProtoReflect.Descriptor instead.
+func (*GroupEvent) Descriptor() ([]byte, []int) {
+	return file_proto_message_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GroupEvent) GetEvent() string {
+	if x != nil {
+		return x.Event
+	}
+	return ""
+}
+
+func (x *GroupEvent) GetOperatorId() string {
+	if x != nil {
+		return x.OperatorId
+	}
+	return ""
+}
+
+func (x *GroupEvent) GetMemberIds() []string {
+	if x != nil {
+		return x.MemberIds
+	}
+	return nil
+}
+
+func (x *GroupEvent) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *GroupEvent) GetRole() int32 {
+	if x != nil {
+		return x.Role
+	}
+	return 0
+}
+
+func (x *GroupEvent) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+// 投票选项及其计票结果
+type PollOption struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Text          string                 `protobuf:"bytes,2,opt,name=text,proto3" json:"text,omitempty"`
+	Count         int32                  `protobuf:"varint,3,opt,name=count,proto3" json:"count,omitempty"`
+	VoterIds      []string               `protobuf:"bytes,4,rep,name=voter_ids,json=voterIds,proto3" json:"voter_ids,omitempty"` // 实名投票时选择该选项的用户
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PollOption) Reset() {
+	*x = PollOption{}
+	mi := &file_proto_message_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PollOption) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PollOption) ProtoMessage() {}
+
+func (x *PollOption) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_message_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PollOption.ProtoReflect.Descriptor instead.
+func (*PollOption) Descriptor() ([]byte, []int) {
+	return file_proto_message_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *PollOption) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *PollOption) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *PollOption) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *PollOption) GetVoterIds() []string {
+	if x != nil {
+		return x.VoterIds
+	}
+	return nil
+}
+
+// 链接卡片
+type LinkPreview struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Url           string                 `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
+	Title         string                 `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`
+	Description   string                 `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	ImageUrl      string                 `protobuf:"bytes,4,opt,name=image_url,json=imageUrl,proto3" json:"image_url,omitempty"`
+	SiteName      string                 `protobuf:"bytes,5,opt,name=site_name,json=siteName,proto3" json:"site_name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LinkPreview) Reset() {
+	*x = LinkPreview{}
+	mi := &file_proto_message_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LinkPreview) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LinkPreview) ProtoMessage() {}
+
+func (x *LinkPreview) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_message_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LinkPreview.ProtoReflect.Descriptor instead.
+func (*LinkPreview) Descriptor() ([]byte, []int) {
+	return file_proto_message_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *LinkPreview) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *LinkPreview) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *LinkPreview) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *LinkPreview) GetImageUrl() string {
+	if x != nil {
+		return x.ImageUrl
+	}
+	return ""
+}
+
+func (x *LinkPreview) GetSiteName() string {
+	if x != nil {
+		return x.SiteName
+	}
+	return ""
+}
+
+// 某个表情的回应汇总
+type ReactionSummary struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Emoji         string                 `protobuf:"bytes,1,opt,name=emoji,proto3" json:"emoji,omitempty"`
+	Count         int32                  `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	UserIds       []string               `protobuf:"bytes,3,rep,name=user_ids,json=userIds,proto3" json:"user_ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReactionSummary) Reset() {
+	*x = ReactionSummary{}
+	mi := &file_proto_message_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReactionSummary) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReactionSummary) ProtoMessage() {}
+
+func (x *ReactionSummary) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_message_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReactionSummary.ProtoReflect.Descriptor instead.
+func (*ReactionSummary) Descriptor() ([]byte, []int) {
+	return file_proto_message_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ReactionSummary) GetEmoji() string {
+	if x != nil {
+		return x.Emoji
+	}
+	return ""
+}
+
+func (x *ReactionSummary) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *ReactionSummary) GetUserIds() []string {
+	if x != nil {
+		return x.UserIds
+	}
+	return nil
+}
+
+// 被引用消息的快照
+type QuotedMessage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	SenderId      string                 `protobuf:"bytes,2,opt,name=sender_id,json=senderId,proto3" json:"sender_id,omitempty"`
+	Type          string                 `protobuf:"bytes,3,opt,name=type,proto3" json:"type,omitempty"`
+	Content       string                 `protobuf:"bytes,4,opt,name=content,proto3" json:"content,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QuotedMessage) Reset() {
+	*x = QuotedMessage{}
+	mi := &file_proto_message_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QuotedMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QuotedMessage) ProtoMessage() {}
+
+func (x *QuotedMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_message_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QuotedMessage.ProtoReflect.Descriptor instead.
+func (*QuotedMessage) Descriptor() ([]byte, []int) {
+	return file_proto_message_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *QuotedMessage) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *QuotedMessage) GetSenderId() string {
+	if x != nil {
+		return x.SenderId
+	}
+	return ""
+}
+
+func (x *QuotedMessage) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *QuotedMessage) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+// 转发消息的来源
+type ForwardInfo struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	MessageId      string                 `protobuf:"bytes,1,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`                // 原消息ID
+	SenderId       string                 `protobuf:"bytes,2,opt,name=sender_id,json=senderId,proto3" json:"sender_id,omitempty"`                   // 原发送者
+	ConversationId string                 `protobuf:"bytes,3,opt,name=conversation_id,json=conversationId,proto3" json:"conversation_id,omitempty"` // 原会话ID
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ForwardInfo) Reset() {
+	*x = ForwardInfo{}
+	mi := &file_proto_message_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ForwardInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ForwardInfo) ProtoMessage() {}
+
+func (x *ForwardInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_message_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ForwardInfo.ProtoReflect.Descriptor instead.
+func (*ForwardInfo) Descriptor() ([]byte, []int) {
+	return file_proto_message_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ForwardInfo) GetMessageId() string {
+	if x != nil {
+		return x.MessageId
+	}
+	return ""
+}
+
+func (x *ForwardInfo) GetSenderId() string {
+	if x != nil {
+		return x.SenderId
+	}
+	return ""
+}
+
+func (x *ForwardInfo) GetConversationId() string {
+	if x != nil {
+		return x.ConversationId
+	}
+	return ""
+}
+
+// 合并转发的聊天记录
+type ChatRecord struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Title         string                 `protobuf:"bytes,1,opt,name=title,proto3" json:"title,omitempty"`
+	Items         []*ChatRecordItem      `protobuf:"bytes,2,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChatRecord) Reset() {
+	*x = ChatRecord{}
+	mi := &file_proto_message_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChatRecord) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChatRecord) ProtoMessage() {}
+
+func (x *ChatRecord) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_message_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChatRecord.ProtoReflect.Descriptor instead.
+func (*ChatRecord) Descriptor() ([]byte, []int) {
+	return file_proto_message_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ChatRecord) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *ChatRecord) GetItems() []*ChatRecordItem {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+// 聊天记录中的一条消息
+type ChatRecordItem struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	SenderId      string                 `protobuf:"bytes,2,opt,name=sender_id,json=senderId,proto3" json:"sender_id,omitempty"`
+	Type          string                 `protobuf:"bytes,3,opt,name=type,proto3" json:"type,omitempty"`
+	Content       string                 `protobuf:"bytes,4,opt,name=content,proto3" json:"content,omitempty"`
+	Timestamp     int64                  `protobuf:"varint,5,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChatRecordItem) Reset() {
+	*x = ChatRecordItem{}
+	mi := &file_proto_message_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChatRecordItem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChatRecordItem) ProtoMessage() {}
+
+func (x *ChatRecordItem) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_message_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChatRecordItem.ProtoReflect.Descriptor instead.
+func (*ChatRecordItem) Descriptor() ([]byte, []int) {
+	return file_proto_message_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ChatRecordItem) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ChatRecordItem) GetSenderId() string {
+	if x != nil {
+		return x.SenderId
+	}
+	return ""
+}
+
+func (x *ChatRecordItem) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *ChatRecordItem) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+func (x *ChatRecordItem) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
 // 媒体文件信息
 type MediaInfo struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -410,7 +1673,7 @@ type MediaInfo struct {
 
 func (x *MediaInfo) Reset() {
 	*x = MediaInfo{}
-	mi := &file_proto_message_proto_msgTypes[2]
+	mi := &file_proto_message_proto_msgTypes[14]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -422,7 +1685,7 @@ func (x *MediaInfo) String() string {
 func (*MediaInfo) ProtoMessage() {}
 
 func (x *MediaInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_message_proto_msgTypes[2]
+	mi := &file_proto_message_proto_msgTypes[14]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -435,7 +1698,7 @@ func (x *MediaInfo) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use MediaInfo.ProtoReflect.Descriptor instead.
 func (*MediaInfo) Descriptor() ([]byte, []int) {
-	return file_proto_message_proto_rawDescGZIP(), []int{2}
+	return file_proto_message_proto_rawDescGZIP(), []int{14}
 }
 
 func (x *MediaInfo) GetFileName() string {
@@ -494,7 +1757,7 @@ func (x *MediaInfo) GetDuration() int32 {
 	return 0
 }
 
-// 批量消息（用于离线消息推送等）
+// 批量消息（用于离线消息推送、增量同步等）
 type MessageBatch struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Messages      []*Message             `protobuf:"bytes,1,rep,name=messages,proto3" json:"messages,omitempty"`
@@ -506,7 +1769,7 @@ type MessageBatch struct {
 
 func (x *MessageBatch) Reset() {
 	*x = MessageBatch{}
-	mi := &file_proto_message_proto_msgTypes[3]
+	mi := &file_proto_message_proto_msgTypes[15]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -518,7 +1781,7 @@ func (x *MessageBatch) String() string {
 func (*MessageBatch) ProtoMessage() {}
 
 func (x *MessageBatch) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_message_proto_msgTypes[3]
+	mi := &file_proto_message_proto_msgTypes[15]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -531,7 +1794,7 @@ func (x *MessageBatch) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use MessageBatch.ProtoReflect.Descriptor instead.
 func (*MessageBatch) Descriptor() ([]byte, []int) {
-	return file_proto_message_proto_rawDescGZIP(), []int{3}
+	return file_proto_message_proto_rawDescGZIP(), []int{15}
 }
 
 func (x *MessageBatch) GetMessages() []*Message {
@@ -568,7 +1831,7 @@ type UserStatus struct {
 
 func (x *UserStatus) Reset() {
 	*x = UserStatus{}
-	mi := &file_proto_message_proto_msgTypes[4]
+	mi := &file_proto_message_proto_msgTypes[16]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -580,7 +1843,7 @@ func (x *UserStatus) String() string {
 func (*UserStatus) ProtoMessage() {}
 
 func (x *UserStatus) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_message_proto_msgTypes[4]
+	mi := &file_proto_message_proto_msgTypes[16]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -593,7 +1856,7 @@ func (x *UserStatus) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use UserStatus.ProtoReflect.Descriptor instead.
 func (*UserStatus) Descriptor() ([]byte, []int) {
-	return file_proto_message_proto_rawDescGZIP(), []int{4}
+	return file_proto_message_proto_rawDescGZIP(), []int{16}
 }
 
 func (x *UserStatus) GetUserId() string {
@@ -640,7 +1903,7 @@ type ConversationInfo struct {
 
 func (x *ConversationInfo) Reset() {
 	*x = ConversationInfo{}
-	mi := &file_proto_message_proto_msgTypes[5]
+	mi := &file_proto_message_proto_msgTypes[17]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -652,7 +1915,7 @@ func (x *ConversationInfo) String() string {
 func (*ConversationInfo) ProtoMessage() {}
 
 func (x *ConversationInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_message_proto_msgTypes[5]
+	mi := &file_proto_message_proto_msgTypes[17]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -665,7 +1928,7 @@ func (x *ConversationInfo) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ConversationInfo.ProtoReflect.Descriptor instead.
 func (*ConversationInfo) Descriptor() ([]byte, []int) {
-	return file_proto_message_proto_rawDescGZIP(), []int{5}
+	return file_proto_message_proto_rawDescGZIP(), []int{17}
 }
 
 func (x *ConversationInfo) GetId() string {
@@ -733,7 +1996,7 @@ type GroupInfo struct {
 
 func (x *GroupInfo) Reset() {
 	*x = GroupInfo{}
-	mi := &file_proto_message_proto_msgTypes[6]
+	mi := &file_proto_message_proto_msgTypes[18]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -745,7 +2008,7 @@ func (x *GroupInfo) String() string {
 func (*GroupInfo) ProtoMessage() {}
 
 func (x *GroupInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_message_proto_msgTypes[6]
+	mi := &file_proto_message_proto_msgTypes[18]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -758,7 +2021,7 @@ func (x *GroupInfo) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GroupInfo.ProtoReflect.Descriptor instead.
 func (*GroupInfo) Descriptor() ([]byte, []int) {
-	return file_proto_message_proto_rawDescGZIP(), []int{6}
+	return file_proto_message_proto_rawDescGZIP(), []int{18}
 }
 
 func (x *GroupInfo) GetId() string {
@@ -823,7 +2086,7 @@ type AuthMessage struct {
 
 func (x *AuthMessage) Reset() {
 	*x = AuthMessage{}
-	mi := &file_proto_message_proto_msgTypes[7]
+	mi := &file_proto_message_proto_msgTypes[19]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -835,7 +2098,7 @@ func (x *AuthMessage) String() string {
 func (*AuthMessage) ProtoMessage() {}
 
 func (x *AuthMessage) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_message_proto_msgTypes[7]
+	mi := &file_proto_message_proto_msgTypes[19]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -848,7 +2111,7 @@ func (x *AuthMessage) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AuthMessage.ProtoReflect.Descriptor instead.
 func (*AuthMessage) Descriptor() ([]byte, []int) {
-	return file_proto_message_proto_rawDescGZIP(), []int{7}
+	return file_proto_message_proto_rawDescGZIP(), []int{19}
 }
 
 func (x *AuthMessage) GetToken() string {
@@ -892,7 +2155,7 @@ type AuthResponse struct {
 
 func (x *AuthResponse) Reset() {
 	*x = AuthResponse{}
-	mi := &file_proto_message_proto_msgTypes[8]
+	mi := &file_proto_message_proto_msgTypes[20]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -904,7 +2167,7 @@ func (x *AuthResponse) String() string {
 func (*AuthResponse) ProtoMessage() {}
 
 func (x *AuthResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_message_proto_msgTypes[8]
+	mi := &file_proto_message_proto_msgTypes[20]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -917,7 +2180,7 @@ func (x *AuthResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AuthResponse.ProtoReflect.Descriptor instead.
 func (*AuthResponse) Descriptor() ([]byte, []int) {
-	return file_proto_message_proto_rawDescGZIP(), []int{8}
+	return file_proto_message_proto_rawDescGZIP(), []int{20}
 }
 
 func (x *AuthResponse) GetSuccess() bool {
@@ -955,7 +2218,7 @@ const file_proto_message_proto_rawDesc = "" +
 	"\x13proto/message.proto\x12\bprotocol\"?\n" +
 	"\tErrorInfo\x12\x18\n" +
 	"\amessage\x18\x01 \x01(\tR\amessage\x12\x18\n" +
-	"\adetails\x18\x02 \x01(\tR\adetails\"\xc8\x05\n" +
+	"\adetails\x18\x02 \x01(\tR\adetails\"\xaa\x0e\n" +
 	"\aMessage\x12\x18\n" +
 	"\aversion\x18\x01 \x01(\tR\aversion\x12)\n" +
 	"\x04type\x18\x02 \x01(\x0e2\x15.protocol.MessageTypeR\x04type\x12\x1f\n" +
@@ -979,10 +2242,123 @@ const file_proto_message_proto_rawDesc = "" +
 	"\x05error\x18\x10 \x01(\v2\x13.protocol.ErrorInfoR\x05error\x12;\n" +
 	"\bmetadata\x18\x11 \x03(\v2\x1f.protocol.Message.MetadataEntryR\bmetadata\x122\n" +
 	"\n" +
-	"media_info\x18\x12 \x01(\v2\x13.protocol.MediaInfoR\tmediaInfo\x1a;\n" +
+	"media_info\x18\x12 \x01(\v2\x13.protocol.MediaInfoR\tmediaInfo\x12\x10\n" +
+	"\x03seq\x18\x13 \x01(\x03R\x03seq\x12,\n" +
+	"\x05batch\x18\x14 \x01(\v2\x16.protocol.MessageBatchR\x05batch\x12\x1b\n" +
+	"\ttarget_id\x18\x15 \x01(\tR\btargetId\x12\x1a\n" +
+	"\brecalled\x18\x16 \x01(\bR\brecalled\x12\x1b\n" +
+	"\tedited_at\x18\x17 \x01(\x03R\beditedAt\x12\x1e\n" +
+	"\vreply_to_id\x18\x18 \x01(\tR\treplyToId\x12-\n" +
+	"\x05quote\x18\x19 \x01(\v2\x17.protocol.QuotedMessageR\x05quote\x12\x1b\n" +
+	"\tthread_id\x18\x1a \x01(\tR\bthreadId\x12\x1f\n" +
+	"\vreply_count\x18\x1b \x01(\x05R\n" +
+	"replyCount\x12\x14\n" +
+	"\x05emoji\x18\x1c \x01(\tR\x05emoji\x12\x16\n" +
+	"\x06remove\x18\x1d \x01(\bR\x06remove\x127\n" +
+	"\treactions\x18\x1e \x03(\v2\x19.protocol.ReactionSummaryR\treactions\x12\x1a\n" +
+	"\bmentions\x18\x1f \x03(\tR\bmentions\x12\x1f\n" +
+	"\vmention_all\x18  \x01(\bR\n" +
+	"mentionAll\x12%\n" +
+	"\x0emention_admins\x18! \x01(\bR\rmentionAdmins\x12\x1d\n" +
+	"\n" +
+	"read_count\x18\" \x01(\x05R\treadCount\x12!\n" +
+	"\fmember_count\x18# \x01(\x05R\vmemberCount\x12\x16\n" +
+	"\x06active\x18$ \x01(\bR\x06active\x12\"\n" +
+	"\rclient_msg_id\x18% \x01(\tR\vclientMsgId\x12\x10\n" +
+	"\x03ttl\x18& \x01(\x05R\x03ttl\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18' \x01(\x03R\texpiresAt\x12\x1d\n" +
+	"\n" +
+	"target_ids\x18( \x03(\tR\ttargetIds\x12/\n" +
+	"\aforward\x18) \x01(\v2\x15.protocol.ForwardInfoR\aforward\x12,\n" +
+	"\x06record\x18* \x01(\v2\x14.protocol.ChatRecordR\x06record\x120\n" +
+	"\blocation\x18+ \x01(\v2\x12.protocol.LocationH\x00R\blocation\x121\n" +
+	"\acontact\x18, \x01(\v2\x15.protocol.ContactCardH\x00R\acontact\x12-\n" +
+	"\asticker\x18- \x01(\v2\x11.protocol.StickerH\x00R\asticker\x12+\n" +
+	"\x04link\x18. \x01(\v2\x15.protocol.LinkPreviewH\x00R\x04link\x12$\n" +
+	"\x04poll\x18/ \x01(\v2\x0e.protocol.PollH\x00R\x04poll\x127\n" +
+	"\vgroup_event\x181 \x01(\v2\x14.protocol.GroupEventH\x00R\n" +
+	"groupEvent\x12\x1d\n" +
+	"\n" +
+	"option_ids\x180 \x03(\tR\toptionIds\x1a;\n" +
 	"\rMetadataEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xec\x01\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01B\x06\n" +
+	"\x04body\"r\n" +
+	"\bLocation\x12\x1a\n" +
+	"\blatitude\x18\x01 \x01(\x01R\blatitude\x12\x1c\n" +
+	"\tlongitude\x18\x02 \x01(\x01R\tlongitude\x12\x12\n" +
+	"\x04name\x18\x03 \x01(\tR\x04name\x12\x18\n" +
+	"\aaddress\x18\x04 \x01(\tR\aaddress\"a\n" +
+	"\vContactCard\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x1a\n" +
+	"\bnickname\x18\x02 \x01(\tR\bnickname\x12\x1d\n" +
+	"\n" +
+	"avatar_url\x18\x03 \x01(\tR\tavatarUrl\"\x97\x01\n" +
+	"\aSticker\x12\x1d\n" +
+	"\n" +
+	"sticker_id\x18\x01 \x01(\tR\tstickerId\x12\x17\n" +
+	"\apack_id\x18\x02 \x01(\tR\x06packId\x12\x10\n" +
+	"\x03url\x18\x03 \x01(\tR\x03url\x12\x14\n" +
+	"\x05width\x18\x04 \x01(\x05R\x05width\x12\x16\n" +
+	"\x06height\x18\x05 \x01(\x05R\x06height\x12\x14\n" +
+	"\x05emoji\x18\x06 \x01(\tR\x05emoji\"\xfd\x01\n" +
+	"\x04Poll\x12\x1a\n" +
+	"\bquestion\x18\x01 \x01(\tR\bquestion\x12.\n" +
+	"\aoptions\x18\x02 \x03(\v2\x14.protocol.PollOptionR\aoptions\x12\x1a\n" +
+	"\bmultiple\x18\x03 \x01(\bR\bmultiple\x12\x1c\n" +
+	"\tanonymous\x18\x04 \x01(\bR\tanonymous\x12\x19\n" +
+	"\bclose_at\x18\x05 \x01(\x03R\acloseAt\x12\x16\n" +
+	"\x06closed\x18\x06 \x01(\bR\x06closed\x12\x1b\n" +
+	"\tclosed_at\x18\a \x01(\x03R\bclosedAt\x12\x1f\n" +
+	"\vvoter_count\x18\b \x01(\x05R\n" +
+	"voterCount\"\xac\x01\n" +
+	"\n" +
+	"GroupEvent\x12\x14\n" +
+	"\x05event\x18\x01 \x01(\tR\x05event\x12\x1f\n" +
+	"\voperator_id\x18\x02 \x01(\tR\n" +
+	"operatorId\x12\x1d\n" +
+	"\n" +
+	"member_ids\x18\x03 \x03(\tR\tmemberIds\x12\x12\n" +
+	"\x04name\x18\x04 \x01(\tR\x04name\x12\x12\n" +
+	"\x04role\x18\x05 \x01(\x05R\x04role\x12 \n" +
+	"\vdescription\x18\x06 \x01(\tR\vdescription\"c\n" +
+	"\n" +
+	"PollOption\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04text\x18\x02 \x01(\tR\x04text\x12\x14\n" +
+	"\x05count\x18\x03 \x01(\x05R\x05count\x12\x1b\n" +
+	"\tvoter_ids\x18\x04 \x03(\tR\bvoterIds\"\x91\x01\n" +
+	"\vLinkPreview\x12\x10\n" +
+	"\x03url\x18\x01 \x01(\tR\x03url\x12\x14\n" +
+	"\x05title\x18\x02 \x01(\tR\x05title\x12 \n" +
+	"\vdescription\x18\x03 \x01(\tR\vdescription\x12\x1b\n" +
+	"\timage_url\x18\x04 \x01(\tR\bimageUrl\x12\x1b\n" +
+	"\tsite_name\x18\x05 \x01(\tR\bsiteName\"X\n" +
+	"\x0fReactionSummary\x12\x14\n" +
+	"\x05emoji\x18\x01 \x01(\tR\x05emoji\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x05R\x05count\x12\x19\n" +
+	"\buser_ids\x18\x03 \x03(\tR\auserIds\"j\n" +
+	"\rQuotedMessage\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1b\n" +
+	"\tsender_id\x18\x02 \x01(\tR\bsenderId\x12\x12\n" +
+	"\x04type\x18\x03 \x01(\tR\x04type\x12\x18\n" +
+	"\acontent\x18\x04 \x01(\tR\acontent\"r\n" +
+	"\vForwardInfo\x12\x1d\n" +
+	"\n" +
+	"message_id\x18\x01 \x01(\tR\tmessageId\x12\x1b\n" +
+	"\tsender_id\x18\x02 \x01(\tR\bsenderId\x12'\n" +
+	"\x0fconversation_id\x18\x03 \x01(\tR\x0econversationId\"R\n" +
+	"\n" +
+	"ChatRecord\x12\x14\n" +
+	"\x05title\x18\x01 \x01(\tR\x05title\x12.\n" +
+	"\x05items\x18\x02 \x03(\v2\x18.protocol.ChatRecordItemR\x05items\"\x89\x01\n" +
+	"\x0eChatRecordItem\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1b\n" +
+	"\tsender_id\x18\x02 \x01(\tR\bsenderId\x12\x12\n" +
+	"\x04type\x18\x03 \x01(\tR\x04type\x12\x18\n" +
+	"\acontent\x18\x04 \x01(\tR\acontent\x12\x1c\n" +
+	"\ttimestamp\x18\x05 \x01(\x03R\ttimestamp\"\xec\x01\n" +
 	"\tMediaInfo\x12\x1b\n" +
 	"\tfile_name\x18\x01 \x01(\tR\bfileName\x12\x1b\n" +
 	"\tfile_type\x18\x02 \x01(\tR\bfileType\x12\x1b\n" +
@@ -1035,7 +2411,7 @@ const file_proto_message_proto_rawDesc = "" +
 	"\auser_id\x18\x02 \x01(\tR\x06userId\x12\x1d\n" +
 	"\n" +
 	"session_id\x18\x03 \x01(\tR\tsessionId\x12#\n" +
-	"\rerror_message\x18\x04 \x01(\tR\ferrorMessage*\xb1\x02\n" +
+	"\rerror_message\x18\x04 \x01(\tR\ferrorMessage*\x81\a\n" +
 	"\vMessageType\x12\x18\n" +
 	"\x14MESSAGE_TYPE_UNKNOWN\x10\x00\x12\x15\n" +
 	"\x11MESSAGE_TYPE_TEXT\x10\x01\x12\x16\n" +
@@ -1049,7 +2425,30 @@ const file_proto_message_proto_rawDesc = "" +
 	"\x14MESSAGE_TYPE_COMMAND\x10\t\x12\x19\n" +
 	"\x15MESSAGE_TYPE_RESPONSE\x10\n" +
 	"\x12\x16\n" +
-	"\x12MESSAGE_TYPE_ERROR\x10\v*\x96\x01\n" +
+	"\x12MESSAGE_TYPE_ERROR\x10\v\x12\x14\n" +
+	"\x10MESSAGE_TYPE_ACK\x10\f\x12\x15\n" +
+	"\x11MESSAGE_TYPE_SYNC\x10\r\x12\x17\n" +
+	"\x13MESSAGE_TYPE_RECALL\x10\x0e\x12\x15\n" +
+	"\x11MESSAGE_TYPE_EDIT\x10\x0f\x12\x19\n" +
+	"\x15MESSAGE_TYPE_REACTION\x10\x10\x12\x18\n" +
+	"\x14MESSAGE_TYPE_MENTION\x10\x11\x12\x15\n" +
+	"\x11MESSAGE_TYPE_READ\x10\x12\x12\x17\n" +
+	"\x13MESSAGE_TYPE_TYPING\x10\x13\x12\x1a\n" +
+	"\x16MESSAGE_TYPE_RECORDING\x10\x14\x12\x16\n" +
+	"\x12MESSAGE_TYPE_PURGE\x10\x15\x12\x1c\n" +
+	"\x18MESSAGE_TYPE_CHAT_RECORD\x10\x16\x12\x14\n" +
+	"\x10MESSAGE_TYPE_PIN\x10\x17\x12\x1d\n" +
+	"\x19MESSAGE_TYPE_ANNOUNCEMENT\x10\x18\x12\x19\n" +
+	"\x15MESSAGE_TYPE_LOCATION\x10\x19\x12\x18\n" +
+	"\x14MESSAGE_TYPE_CONTACT\x10\x1a\x12\x18\n" +
+	"\x14MESSAGE_TYPE_STICKER\x10\x1b\x12\x15\n" +
+	"\x11MESSAGE_TYPE_LINK\x10\x1c\x12\x15\n" +
+	"\x11MESSAGE_TYPE_POLL\x10\x1d\x12\x15\n" +
+	"\x11MESSAGE_TYPE_VOTE\x10\x1e\x12\x1b\n" +
+	"\x17MESSAGE_TYPE_POLL_CLOSE\x10\x1f\x12\x17\n" +
+	"\x13MESSAGE_TYPE_SYSTEM\x10 \x12\x1d\n" +
+	"\x19MESSAGE_TYPE_JOIN_REQUEST\x10!\x12\x1c\n" +
+	"\x18MESSAGE_TYPE_GROUP_EVENT\x10\"*\x96\x01\n" +
 	"\rMessageStatus\x12\x1a\n" +
 	"\x16MESSAGE_STATUS_UNKNOWN\x10\x00\x12\x17\n" +
 	"\x13MESSAGE_STATUS_SENT\x10\x01\x12\x1c\n" +
@@ -1070,33 +2469,58 @@ func file_proto_message_proto_rawDescGZIP() []byte {
 }
 
 var file_proto_message_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
-var file_proto_message_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_proto_message_proto_msgTypes = make([]protoimpl.MessageInfo, 22)
 var file_proto_message_proto_goTypes = []any{
 	(MessageType)(0),         // 0: protocol.MessageType
 	(MessageStatus)(0),       // 1: protocol.MessageStatus
 	(*ErrorInfo)(nil),        // 2: protocol.ErrorInfo
 	(*Message)(nil),          // 3: protocol.Message
-	(*MediaInfo)(nil),        // 4: protocol.MediaInfo
-	(*MessageBatch)(nil),     // 5: protocol.MessageBatch
-	(*UserStatus)(nil),       // 6: protocol.UserStatus
-	(*ConversationInfo)(nil), // 7: protocol.ConversationInfo
-	(*GroupInfo)(nil),        // 8: protocol.GroupInfo
-	(*AuthMessage)(nil),      // 9: protocol.AuthMessage
-	(*AuthResponse)(nil),     // 10: protocol.AuthResponse
-	nil,                      // 11: protocol.Message.MetadataEntry
+	(*Location)(nil),         // 4: protocol.Location
+	(*ContactCard)(nil),      // 5: protocol.ContactCard
+	(*Sticker)(nil),          // 6: protocol.Sticker
+	(*Poll)(nil),             // 7: protocol.Poll
+	(*GroupEvent)(nil),       // 8: protocol.GroupEvent
+	(*PollOption)(nil),       // 9: protocol.PollOption
+	(*LinkPreview)(nil),      // 10: protocol.LinkPreview
+	(*ReactionSummary)(nil),  // 11: protocol.ReactionSummary
+	(*QuotedMessage)(nil),    // 12: protocol.QuotedMessage
+	(*ForwardInfo)(nil),      // 13: protocol.ForwardInfo
+	(*ChatRecord)(nil),       // 14: protocol.ChatRecord
+	(*ChatRecordItem)(nil),   // 15: protocol.ChatRecordItem
+	(*MediaInfo)(nil),        // 16: protocol.MediaInfo
+	(*MessageBatch)(nil),     // 17: protocol.MessageBatch
+	(*UserStatus)(nil),       // 18: protocol.UserStatus
+	(*ConversationInfo)(nil), // 19: protocol.ConversationInfo
+	(*GroupInfo)(nil),        // 20: protocol.GroupInfo
+	(*AuthMessage)(nil),      // 21: protocol.AuthMessage
+	(*AuthResponse)(nil),     // 22: protocol.AuthResponse
+	nil,                      // 23: protocol.Message.MetadataEntry
 }
 var file_proto_message_proto_depIdxs = []int32{
 	0,  // 0: protocol.Message.type:type_name -> protocol.MessageType
 	1,  // 1: protocol.Message.status:type_name -> protocol.MessageStatus
 	2,  // 2: protocol.Message.error:type_name -> protocol.ErrorInfo
-	11, // 3: protocol.Message.metadata:type_name -> protocol.Message.MetadataEntry
-	4,  // 4: protocol.Message.media_info:type_name -> protocol.MediaInfo
-	3,  // 5: protocol.MessageBatch.messages:type_name -> protocol.Message
-	6,  // [6:6] is the sub-list for method output_type
-	6,  // [6:6] is the sub-list for method input_type
-	6,  // [6:6] is the sub-list for extension type_name
-	6,  // [6:6] is the sub-list for extension extendee
-	0,  // [0:6] is the sub-list for field type_name
+	23, // 3: protocol.Message.metadata:type_name -> protocol.Message.MetadataEntry
+	16, // 4: protocol.Message.media_info:type_name -> protocol.MediaInfo
+	17, // 5: protocol.Message.batch:type_name -> protocol.MessageBatch
+	12, // 6: protocol.Message.quote:type_name -> protocol.QuotedMessage
+	11, // 7: protocol.Message.reactions:type_name -> protocol.ReactionSummary
+	13, // 8: protocol.Message.forward:type_name -> protocol.ForwardInfo
+	14, // 9: protocol.Message.record:type_name -> protocol.ChatRecord
+	4,  // 10: protocol.Message.location:type_name -> protocol.Location
+	5,  // 11: protocol.Message.contact:type_name -> protocol.ContactCard
+	6,  // 12: protocol.Message.sticker:type_name -> protocol.Sticker
+	10, // 13: protocol.Message.link:type_name -> protocol.LinkPreview
+	7,  // 14: protocol.Message.poll:type_name -> protocol.Poll
+	8,  // 15: protocol.Message.group_event:type_name -> protocol.GroupEvent
+	9,  // 16: protocol.Poll.options:type_name -> protocol.PollOption
+	15, // 17: protocol.ChatRecord.items:type_name -> protocol.ChatRecordItem
+	3,  // 18: protocol.MessageBatch.messages:type_name -> protocol.Message
+	19, // [19:19] is the sub-list for method output_type
+	19, // [19:19] is the sub-list for method input_type
+	19, // [19:19] is the sub-list for extension type_name
+	19, // [19:19] is the sub-list for extension extendee
+	0,  // [0:19] is the sub-list for field type_name
 }
 
 func init() { file_proto_message_proto_init() }
@@ -1104,13 +2528,21 @@ func file_proto_message_proto_init() {
 	if File_proto_message_proto != nil {
 		return
 	}
+	file_proto_message_proto_msgTypes[1].OneofWrappers = []any{
+		(*Message_Location)(nil),
+		(*Message_Contact)(nil),
+		(*Message_Sticker)(nil),
+		(*Message_Link)(nil),
+		(*Message_Poll)(nil),
+		(*Message_GroupEvent)(nil),
+	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_message_proto_rawDesc), len(file_proto_message_proto_rawDesc)),
 			NumEnums:      2,
-			NumMessages:   10,
+			NumMessages:   22,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
@@ -31,7 +31,7 @@ func (w bodyLogWriter) Write(b []byte) (int, error) {
 }
 
 // SetupRouter 配置所有路由
-func SetupRouter(connMgr connection.ConnectionManager, messageService *chat.MessageService) *gin.Engine {
+func SetupRouter(connMgr connection.ConnectionManager, messageService *chat.MessageService, groupService *group.GroupService) *gin.Engine {
 	r := gin.Default()
 
 	// CORS 配置
@@ -140,13 +140,13 @@ func SetupRouter(connMgr connection.ConnectionManager, messageService *chat.Mess
 
 			// ----- 群组相关 -----
 			// 创建群组
-			auth.POST("/group/create", group.CreateGroup)
+			auth.POST("/group/create", group.CreateGroup(groupService))
 
 			// 邀请用户入群
-			auth.POST("/group/:groupId/invite", group.InviteUser)
+			auth.POST("/group/:groupId/invite", group.InviteUser(groupService))
 
 			// 退出群组
-			auth.POST("/group/:groupId/exit", group.ExitGroup)
+			auth.POST("/group/:groupId/exit", group.ExitGroup(groupService))
 
 			// 获取群成员列表
 			auth.GET("/group/:groupId/members", group.GetGroupMembers)
@@ -155,10 +155,47 @@ func SetupRouter(connMgr connection.ConnectionManager, messageService *chat.Mess
 			auth.GET("/groups", group.GetUserGroups)
 
 			// 更新群名称
-			auth.PUT("/group/:groupId/name", group.UpdateGroupName)
+			auth.PUT("/group/:groupId/name", group.UpdateGroupName(groupService))
 
 			// 解散群组
-			auth.DELETE("/group/:groupId", group.DeleteGroup)
+			auth.DELETE("/group/:groupId", group.DeleteGroup(groupService))
+
+			// 群组信息和群简介
+			auth.GET("/group/:groupId", group.GetGroupInfo)
+			auth.PUT("/group/:groupId/description", group.UpdateGroupDescription(groupService))
+
+			// 群公告
+			auth.GET("/group/:groupId/announcements", group.ListAnnouncements)
+			auth.POST("/group/:groupId/announcements", group.CreateAnnouncement(groupService))
+			auth.PUT("/group/:groupId/announcements/:announcementId", group.UpdateAnnouncement(groupService))
+			auth.DELETE("/group/:groupId/announcements/:announcementId", group.DeleteAnnouncement(groupService))
+
+			// 群管理员和群主转让（仅群主）
+			auth.PUT("/group/:groupId/admins/:userId", group.PromoteAdmin(groupService))
+			auth.DELETE("/group/:groupId/admins/:userId", group.DemoteAdmin(groupService))
+			auth.POST("/group/:groupId/transfer", group.TransferOwnership(groupService))
+
+			// 移出成员、成员禁言和全员禁言（群主/管理员）
+			auth.DELETE("/group/:groupId/members/:userId", group.RemoveMember(groupService))
+			auth.PUT("/group/:groupId/members/:userId/mute", group.MuteMember(groupService))
+			auth.DELETE("/group/:groupId/members/:userId/mute", group.UnmuteMember(groupService))
+			auth.PUT("/group/:groupId/mute", group.MuteAll(groupService))
+			auth.DELETE("/group/:groupId/mute", group.UnmuteAll(groupService))
+
+			// 入群方式、入群申请和邀请链接
+			auth.PUT("/group/:groupId/join-policy", group.SetJoinPolicy(groupService))
+			auth.POST("/group/:groupId/join", group.JoinGroup(groupService))
+			auth.GET("/group/:groupId/join-requests", group.ListJoinRequests)
+			auth.POST("/group/:groupId/join-requests/:requestId/approve", group.ApproveJoinRequest(groupService))
+			auth.POST("/group/:groupId/join-requests/:requestId/reject", group.RejectJoinRequest(groupService))
+			auth.GET("/group/:groupId/invites", group.ListInvites)
+			auth.POST("/group/:groupId/invites", group.CreateInvite)
+			auth.DELETE("/group/:groupId/invites/:inviteId", group.RevokeInvite)
+			auth.GET("/invites/:token", group.PreviewInvite)
+			auth.POST("/invites/:token/join", group.JoinByInvite(groupService))
+
+			// 群置顶消息
+			auth.GET("/group/:groupId/pins", chat.GetPinnedMessages)
 
 			// ----- 会话相关 -----
 
@@ -178,45 +215,48 @@ func SetupRouter(connMgr connection.ConnectionManager, messageService *chat.Mess
 			auth.GET("/conversation/:id", chat.GetConversation)
 			auth.GET("/conversations/:id", chat.GetConversation)
 			auth.GET("/conversations/:id/participants", chat.GetParticipants)
+			auth.PUT("/conversations/:id/mute", chat.MuteConversation)
+			auth.GET("/conversations/:id/retention", chat.GetConversationRetention)
+			auth.PUT("/conversations/:id/retention", chat.SetConversationRetention)
+			auth.GET("/conversations/:id/pins", chat.GetPinnedMessages)
+			auth.GET("/conversations/:id/messages", chat.GetMessages)
+			auth.POST("/conversations/:id/read", chat.MarkMessagesAsRead(messageService))
 
 			// ----- 消息相关 -----
-			auth.GET("/messages/:conversationId", chat.GetMessages)
-			auth.POST("/messages/:id/read", chat.MarkMessagesAsRead)
+			auth.POST("/messages/sync", chat.SyncMessages)
+			auth.POST("/messages/forward", chat.ForwardMessages(messageService))
+			auth.POST("/messages/:id/recall", chat.RecallMessage(messageService))
+			auth.PUT("/messages/:id", chat.EditMessage(messageService))
+			auth.GET("/messages/:id/revisions", chat.GetMessageRevisions)
+			auth.GET("/messages/:id/readers", chat.GetMessageReaders)
+			auth.POST("/messages/:id/reactions", chat.AddReaction(messageService))
+			auth.DELETE("/messages/:id/reactions", chat.RemoveReaction(messageService))
+			auth.POST("/messages/:id/pin", chat.PinMessage(messageService))
+			auth.DELETE("/messages/:id/pin", chat.UnpinMessage(messageService))
+			auth.GET("/search/messages", chat.SearchMessages)
+
+			// ----- 定时消息 -----
+			auth.POST("/scheduled-messages", chat.ScheduleMessage)
+			auth.GET("/scheduled-messages", chat.ListScheduledMessages)
+			auth.PUT("/scheduled-messages/:id", chat.UpdateScheduledMessage)
+			auth.DELETE("/scheduled-messages/:id", chat.CancelScheduledMessage)
+
+			// ----- 投票相关（投票ID即投票消息ID） -----
+			auth.GET("/polls/:id", chat.GetPoll)
+			auth.POST("/polls/:id/votes", chat.VotePoll(messageService))
+			auth.DELETE("/polls/:id/votes", chat.RetractVote(messageService))
+			auth.POST("/polls/:id/close", chat.ClosePoll(messageService))
+
+			// ----- 话题相关 -----
+			auth.GET("/threads/:id", chat.GetThread)
+			auth.GET("/threads/:id/messages", chat.GetThreadMessages)
+			auth.POST("/threads/:id/read", chat.MarkThreadRead)
 
 			// 获取与特定用户的消息
-			auth.GET("/messages/user/:user_id", func(c *gin.Context) {
-				userID, _ := c.Get("userID")
-				otherUserID := c.Param("user_id")
-				log.Printf("获取用户 %s 和 %s 之间的消息", userID, otherUserID)
-
-				msgService := chat.NewMessageService()
-				messages, err := msgService.GetPrivateMessages(c.Request.Context(), userID.(string), otherUserID, 50)
-				if err != nil {
-					log.Printf("获取消息失败: %v", err)
-					c.JSON(http.StatusInternalServerError, gin.H{"error": "获取消息失败"})
-					return
-				}
-
-				log.Printf("找到用户 %s 和 %s 之间的 %d 条消息", userID, otherUserID, len(messages))
-				c.JSON(http.StatusOK, messages)
-			})
+			auth.GET("/messages/user/:user_id", chat.GetUserMessages)
 
 			// 获取群组消息
-			auth.GET("/messages/group/:group_id", func(c *gin.Context) {
-				groupID := c.Param("group_id")
-				log.Printf("获取群组 %s 的消息", groupID)
-
-				msgService := chat.NewMessageService()
-				messages, err := msgService.GetGroupMessages(c.Request.Context(), groupID, 50)
-				if err != nil {
-					log.Printf("获取群组消息失败: %v", err)
-					c.JSON(http.StatusInternalServerError, gin.H{"error": "获取群组消息失败"})
-					return
-				}
-
-				log.Printf("找到群组 %s 的 %d 条消息", groupID, len(messages))
-				c.JSON(http.StatusOK, messages)
-			})
+			auth.GET("/messages/group/:group_id", chat.GetGroupMessages)
 
 			// 心跳检测
 			auth.GET("/heartbeat", user.Heartbeat)
@@ -0,0 +1,108 @@
+package search
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"cursorIM/internal/config"
+
+	"gorm.io/gorm"
+)
+
+// 搜索引擎类型
+const (
+	EngineMySQL  = "mysql"  // MySQL FULLTEXT（ngram 分词）
+	EngineMemory = "memory" // 进程内倒排索引，无需 MySQL，适合测试和单机部署
+)
+
+// Document 被索引的消息
+type Document struct {
+	ID             string `json:"id"`
+	ConversationID string `json:"conversation_id"`
+	SenderID       string `json:"sender_id"`
+	ContentType    string `json:"content_type"`
+	Content        string `json:"content"`
+	Timestamp      int64  `json:"timestamp"`
+	Seq            int64  `json:"seq"`
+	IsGroup        bool   `json:"is_group"`
+}
+
+// Query 搜索条件
+// ConversationIDs 为调用方有权访问的会话范围，为空时不返回任何结果
+type Query struct {
+	Text            string
+	ConversationIDs []string
+	SenderID        string
+	ContentType     string
+	From            int64    // 起始时间（Unix 秒，包含）
+	To              int64    // 结束时间（Unix 秒，包含）
+	ExcludeTypes    []string // 不参与搜索的消息类型（控制类消息等）
+	Limit           int
+	Offset          int
+}
+
+// Hit 单条搜索结果
+type Hit struct {
+	Document
+	Highlight string  `json:"highlight"` // 命中片段，关键词以 <em></em> 标记
+	Score     float64 `json:"score"`
+}
+
+// Result 搜索结果
+type Result struct {
+	Hits  []*Hit `json:"hits"`
+	Total int64  `json:"total"`
+}
+
+// Index 消息全文索引
+type Index interface {
+	// Name 索引实现名称
+	Name() string
+	// SelfMaintained 索引是否由存储自行维护（如 MySQL FULLTEXT），为 true 时调用方无需推送文档
+	SelfMaintained() bool
+	// Index 写入或更新文档
+	Index(doc *Document) error
+	// Delete 删除文档
+	Delete(id string) error
+	// Search 执行搜索
+	Search(ctx context.Context, query *Query) (*Result, error)
+}
+
+var (
+	defaultIndex Index
+	indexMutex   sync.RWMutex
+)
+
+// Init 按配置创建全局索引
+func Init(db *gorm.DB) Index {
+	var index Index
+	switch config.GlobalConfig.Search.Engine {
+	case EngineMemory:
+		index = NewMemoryIndex()
+	default:
+		mysqlIndex := NewMySQLIndex(db)
+		if err := mysqlIndex.EnsureIndex(); err != nil {
+			log.Printf("创建消息全文索引失败，搜索将退化为模糊匹配: %v", err)
+		}
+		index = mysqlIndex
+	}
+
+	SetIndex(index)
+	log.Printf("消息搜索引擎: %s", index.Name())
+	return index
+}
+
+// SetIndex 替换全局索引
+func SetIndex(index Index) {
+	indexMutex.Lock()
+	defer indexMutex.Unlock()
+	defaultIndex = index
+}
+
+// GetIndex 获取全局索引，未初始化时返回 nil
+func GetIndex() Index {
+	indexMutex.RLock()
+	defer indexMutex.RUnlock()
+	return defaultIndex
+}
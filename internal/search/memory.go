@@ -0,0 +1,205 @@
+package search
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// MemoryIndex 进程内倒排索引
+// 分词方式与 MySQL ngram 一致（按二元组切分），候选文档再按原文做子串校验，
+// 保证与 MySQL 实现的命中结果一致。索引不持久化，启动时需要由调用方重建
+type MemoryIndex struct {
+	mutex    sync.RWMutex
+	docs     map[string]*Document
+	postings map[string]map[string]struct{} // token -> 文档ID集合
+}
+
+// NewMemoryIndex 创建进程内索引
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{
+		docs:     make(map[string]*Document),
+		postings: make(map[string]map[string]struct{}),
+	}
+}
+
+// Name 实现 Index 接口
+func (m *MemoryIndex) Name() string {
+	return EngineMemory
+}
+
+// SelfMaintained 实现 Index 接口
+func (m *MemoryIndex) SelfMaintained() bool {
+	return false
+}
+
+// Index 实现 Index 接口，已存在的文档会被替换
+func (m *MemoryIndex) Index(doc *Document) error {
+	if doc == nil || doc.ID == "" {
+		return nil
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.removeLocked(doc.ID)
+	if doc.Content == "" {
+		return nil
+	}
+
+	stored := *doc
+	m.docs[doc.ID] = &stored
+	for _, token := range tokenize(doc.Content) {
+		ids, ok := m.postings[token]
+		if !ok {
+			ids = make(map[string]struct{})
+			m.postings[token] = ids
+		}
+		ids[doc.ID] = struct{}{}
+	}
+	return nil
+}
+
+// Delete 实现 Index 接口
+func (m *MemoryIndex) Delete(id string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.removeLocked(id)
+	return nil
+}
+
+// removeLocked 从倒排表中移除文档，调用方需持有写锁
+func (m *MemoryIndex) removeLocked(id string) {
+	doc, ok := m.docs[id]
+	if !ok {
+		return
+	}
+	for _, token := range tokenize(doc.Content) {
+		if ids, ok := m.postings[token]; ok {
+			delete(ids, id)
+			if len(ids) == 0 {
+				delete(m.postings, token)
+			}
+		}
+	}
+	delete(m.docs, id)
+}
+
+// Len 返回已索引的文档数
+func (m *MemoryIndex) Len() int {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return len(m.docs)
+}
+
+// Search 实现 Index 接口
+// 所有关键词都必须出现（AND 语义），得分为关键词出现次数之和，同分按时间倒序
+func (m *MemoryIndex) Search(ctx context.Context, query *Query) (*Result, error) {
+	result := &Result{Hits: make([]*Hit, 0)}
+	terms := splitTerms(query.Text)
+	if len(terms) == 0 || len(query.ConversationIDs) == 0 {
+		return result, nil
+	}
+
+	inScope := make(map[string]bool, len(query.ConversationIDs))
+	for _, id := range query.ConversationIDs {
+		inScope[id] = true
+	}
+	excluded := make(map[string]bool, len(query.ExcludeTypes))
+	for _, contentType := range query.ExcludeTypes {
+		excluded[contentType] = true
+	}
+
+	m.mutex.RLock()
+	candidates := m.candidatesLocked(terms)
+	var hits []*Hit
+	for id := range candidates {
+		doc := m.docs[id]
+		if !inScope[doc.ConversationID] || excluded[doc.ContentType] ||
+			(query.SenderID != "" && doc.SenderID != query.SenderID) ||
+			(query.ContentType != "" && doc.ContentType != query.ContentType) ||
+			(query.From > 0 && doc.Timestamp < query.From) ||
+			(query.To > 0 && doc.Timestamp > query.To) {
+			continue
+		}
+
+		lower := strings.ToLower(doc.Content)
+		score := 0
+		for _, term := range terms {
+			count := strings.Count(lower, term)
+			if count == 0 {
+				score = 0
+				break
+			}
+			score += count
+		}
+		if score == 0 {
+			continue
+		}
+
+		hits = append(hits, &Hit{
+			Document:  *doc,
+			Highlight: Highlight(doc.Content, terms),
+			Score:     float64(score),
+		})
+	}
+	m.mutex.RUnlock()
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Timestamp > hits[j].Timestamp
+	})
+
+	result.Total = int64(len(hits))
+	if query.Offset >= len(hits) {
+		return result, nil
+	}
+	hits = hits[query.Offset:]
+	if query.Limit > 0 && len(hits) > query.Limit {
+		hits = hits[:query.Limit]
+	}
+	result.Hits = hits
+	return result, nil
+}
+
+// candidatesLocked 返回包含所有关键词分词结果的文档ID，调用方需持有读锁
+func (m *MemoryIndex) candidatesLocked(terms []string) map[string]struct{} {
+	var candidates map[string]struct{}
+	for _, term := range terms {
+		// 短于 ngram 的关键词可能只是文档中某个词的一部分，不参与倒排筛选
+		if utf8.RuneCountInString(term) < ngramSize {
+			continue
+		}
+		for _, token := range tokenize(term) {
+			ids := m.postings[token]
+			if len(ids) == 0 {
+				return nil
+			}
+			if candidates == nil {
+				candidates = make(map[string]struct{}, len(ids))
+				for id := range ids {
+					candidates[id] = struct{}{}
+				}
+				continue
+			}
+			for id := range candidates {
+				if _, ok := ids[id]; !ok {
+					delete(candidates, id)
+				}
+			}
+		}
+	}
+
+	// 没有可用于筛选的关键词时退化为全量扫描，由子串校验过滤
+	if candidates == nil {
+		candidates = make(map[string]struct{}, len(m.docs))
+		for id := range m.docs {
+			candidates[id] = struct{}{}
+		}
+	}
+	return candidates
+}
@@ -0,0 +1,132 @@
+package search
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"空文本", "", nil},
+		{"单字", "好", []string{"好"}},
+		{"中文二元组", "你好吗", []string{"你好", "好吗"}},
+		{"英文转小写", "Go Lang", []string{"go", "la", "an", "ng"}},
+		{"标点分隔", "明天,见", []string{"明天", "见"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tokenize(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tokenize(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		terms   []string
+		want    string
+	}{
+		{"标记命中", "明天一起吃饭", []string{"吃饭"}, "明天一起<em>吃饭</em>"},
+		{"忽略大小写", "Hello World", []string{"world"}, "Hello <em>World</em>"},
+		{"多个关键词", "明天一起吃饭", []string{"明天", "吃饭"}, "<em>明天</em>一起<em>吃饭</em>"},
+		{"截取片段", "这是一段很长很长很长很长很长很长很长很长的开头然后是关键词后面还有很长很长很长很长很长很长很长很长的结尾",
+			[]string{"关键词"}, "…很长很长很长很长很长很长很长的开头然后是<em>关键词</em>后面还有很长很长很长很长很长很长很长很长…"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Highlight(tt.content, tt.terms); got != tt.want {
+				t.Errorf("Highlight() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryIndexSearch(t *testing.T) {
+	index := NewMemoryIndex()
+	docs := []*Document{
+		{ID: "m1", ConversationID: "c1", SenderID: "u1", ContentType: "text", Content: "明天一起吃饭", Timestamp: 100},
+		{ID: "m2", ConversationID: "c1", SenderID: "u2", ContentType: "text", Content: "吃饭吃饭", Timestamp: 200},
+		{ID: "m3", ConversationID: "c2", SenderID: "u1", ContentType: "text", Content: "今天吃饭了吗", Timestamp: 300},
+		{ID: "m4", ConversationID: "c1", SenderID: "u1", ContentType: "recall", Content: "吃饭", Timestamp: 400},
+		{ID: "m5", ConversationID: "c1", SenderID: "u1", ContentType: "text", Content: "Go 语言", Timestamp: 500},
+	}
+	for _, doc := range docs {
+		if err := index.Index(doc); err != nil {
+			t.Fatalf("Index(%s) 失败: %v", doc.ID, err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		query Query
+		want  []string
+	}{
+		{"按得分排序", Query{Text: "吃饭", ConversationIDs: []string{"c1", "c2"}}, []string{"m2", "m4", "m3", "m1"}},
+		{"限定会话范围", Query{Text: "吃饭", ConversationIDs: []string{"c2"}}, []string{"m3"}},
+		{"无会话范围不返回结果", Query{Text: "吃饭"}, nil},
+		{"排除控制类消息", Query{Text: "吃饭", ConversationIDs: []string{"c1"}, ExcludeTypes: []string{"recall"}}, []string{"m2", "m1"}},
+		{"多个关键词同时命中", Query{Text: "明天 吃饭", ConversationIDs: []string{"c1", "c2"}}, []string{"m1"}},
+		{"按发送者过滤", Query{Text: "吃饭", ConversationIDs: []string{"c1", "c2"}, SenderID: "u2"}, []string{"m2"}},
+		{"按时间过滤", Query{Text: "吃饭", ConversationIDs: []string{"c1", "c2"}, From: 150, To: 350}, []string{"m2", "m3"}},
+		{"短关键词子串匹配", Query{Text: "g", ConversationIDs: []string{"c1"}}, []string{"m5"}},
+		{"分页", Query{Text: "吃饭", ConversationIDs: []string{"c1", "c2"}, Offset: 1, Limit: 2}, []string{"m4", "m3"}},
+		{"没有命中", Query{Text: "睡觉", ConversationIDs: []string{"c1", "c2"}}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := tt.query
+			result, err := index.Search(context.Background(), &query)
+			if err != nil {
+				t.Fatalf("Search() 失败: %v", err)
+			}
+
+			var got []string
+			for _, hit := range result.Hits {
+				got = append(got, hit.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryIndexUpdateAndDelete(t *testing.T) {
+	index := NewMemoryIndex()
+	scope := []string{"c1"}
+	search := func(text string) int64 {
+		result, err := index.Search(context.Background(), &Query{Text: text, ConversationIDs: scope})
+		if err != nil {
+			t.Fatalf("Search(%q) 失败: %v", text, err)
+		}
+		return result.Total
+	}
+
+	index.Index(&Document{ID: "m1", ConversationID: "c1", Content: "原来的内容"})
+	index.Index(&Document{ID: "m1", ConversationID: "c1", Content: "编辑后的内容"})
+	if got := search("原来"); got != 0 {
+		t.Errorf("编辑后仍能搜到旧内容: total = %d", got)
+	}
+	if got := search("编辑"); got != 1 {
+		t.Errorf("编辑后搜不到新内容: total = %d", got)
+	}
+
+	index.Delete("m1")
+	if got := search("编辑"); got != 0 {
+		t.Errorf("删除后仍能搜到: total = %d", got)
+	}
+	if index.Len() != 0 || len(index.postings) != 0 {
+		t.Errorf("删除后索引未清空: docs=%d postings=%d", index.Len(), len(index.postings))
+	}
+}
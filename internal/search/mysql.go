@@ -0,0 +1,165 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"cursorIM/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// fulltextIndexName 消息内容的全文索引名
+const fulltextIndexName = "ft_messages_content"
+
+// MySQLIndex 基于 MySQL FULLTEXT 索引（ngram 分词，支持中文）的搜索实现
+// 索引由 MySQL 随 messages 表自动维护，Index/Delete 为空操作
+type MySQLIndex struct {
+	db *gorm.DB
+}
+
+// NewMySQLIndex 创建 MySQL 全文索引
+func NewMySQLIndex(db *gorm.DB) *MySQLIndex {
+	return &MySQLIndex{db: db}
+}
+
+// EnsureIndex 确保 messages.content 上存在 ngram 全文索引
+func (m *MySQLIndex) EnsureIndex() error {
+	var count int64
+	err := m.db.Raw(`
+		SELECT COUNT(*) FROM information_schema.statistics
+		WHERE table_schema = DATABASE() AND table_name = 'messages' AND index_name = ?
+	`, fulltextIndexName).Scan(&count).Error
+	if err != nil {
+		return fmt.Errorf("查询全文索引失败: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	log.Printf("创建消息全文索引 %s", fulltextIndexName)
+	return m.db.Exec(fmt.Sprintf(
+		"ALTER TABLE messages ADD FULLTEXT INDEX %s (content) WITH PARSER ngram", fulltextIndexName,
+	)).Error
+}
+
+// Name 实现 Index 接口
+func (m *MySQLIndex) Name() string {
+	return EngineMySQL
+}
+
+// SelfMaintained 实现 Index 接口
+func (m *MySQLIndex) SelfMaintained() bool {
+	return true
+}
+
+// Index 实现 Index 接口，全文索引由 MySQL 维护
+func (m *MySQLIndex) Index(doc *Document) error {
+	return nil
+}
+
+// Delete 实现 Index 接口，全文索引由 MySQL 维护
+func (m *MySQLIndex) Delete(id string) error {
+	return nil
+}
+
+// Search 实现 Index 接口
+// 不短于 ngram 长度的关键词使用 MATCH ... AGAINST 布尔模式的短语匹配，
+// 单个汉字等更短的关键词无法命中 ngram 索引，退化为 LIKE 匹配
+func (m *MySQLIndex) Search(ctx context.Context, query *Query) (*Result, error) {
+	result := &Result{Hits: make([]*Hit, 0)}
+	terms := splitTerms(query.Text)
+	if len(terms) == 0 || len(query.ConversationIDs) == 0 {
+		return result, nil
+	}
+
+	db := m.db.WithContext(ctx).Model(&model.Message{}).
+		Where("conversation_id IN ? AND recalled = ? AND content <> ''", query.ConversationIDs, false)
+	if len(query.ExcludeTypes) > 0 {
+		db = db.Where("content_type NOT IN ?", query.ExcludeTypes)
+	}
+	if query.SenderID != "" {
+		db = db.Where("sender_id = ?", query.SenderID)
+	}
+	if query.ContentType != "" {
+		db = db.Where("content_type = ?", query.ContentType)
+	}
+	if query.From > 0 {
+		db = db.Where("timestamp >= ?", query.From)
+	}
+	if query.To > 0 {
+		db = db.Where("timestamp <= ?", query.To)
+	}
+
+	var phrases []string
+	for _, term := range terms {
+		if utf8.RuneCountInString(term) < ngramSize {
+			db = db.Where("content LIKE ?", "%"+escapeLike(term)+"%")
+			continue
+		}
+		phrases = append(phrases, `+"`+strings.ReplaceAll(term, `"`, " ")+`"`)
+	}
+	against := strings.Join(phrases, " ")
+	if against != "" {
+		db = db.Where("MATCH(content) AGAINST(? IN BOOLEAN MODE)", against)
+	}
+
+	if err := db.Count(&result.Total).Error; err != nil {
+		return nil, fmt.Errorf("统计搜索结果失败: %w", err)
+	}
+	if result.Total == 0 {
+		return result, nil
+	}
+
+	var rows []struct {
+		model.Message
+		Score float64
+	}
+	if against != "" {
+		db = db.Select("messages.*, MATCH(content) AGAINST(? IN BOOLEAN MODE) AS score", against).
+			Order("score desc")
+	} else {
+		db = db.Select("messages.*, 0 AS score")
+	}
+	if err := db.Order("timestamp desc").
+		Offset(query.Offset).Limit(query.Limit).
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("搜索消息失败: %w", err)
+	}
+
+	for _, row := range rows {
+		result.Hits = append(result.Hits, &Hit{
+			Document:  documentFromModel(&row.Message),
+			Highlight: Highlight(row.Content, terms),
+			Score:     row.Score,
+		})
+	}
+	return result, nil
+}
+
+// DocumentFromModel 由数据库消息构造索引文档
+func DocumentFromModel(message *model.Message) *Document {
+	doc := documentFromModel(message)
+	return &doc
+}
+
+func documentFromModel(message *model.Message) Document {
+	return Document{
+		ID:             message.ID,
+		ConversationID: message.ConversationID,
+		SenderID:       message.SenderID,
+		ContentType:    message.ContentType,
+		Content:        message.Content,
+		Timestamp:      message.Timestamp,
+		Seq:            message.Seq,
+		IsGroup:        message.IsGroup,
+	}
+}
+
+// escapeLike 转义 LIKE 模式中的通配符
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,115 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ngramSize CJK 文本的分词粒度，与 MySQL ngram_token_size 默认值一致
+const ngramSize = 2
+
+// 高亮片段中命中关键词前后保留的字符数
+const snippetContext = 20
+
+// splitTerms 将查询文本按空白拆分为关键词（小写）
+func splitTerms(text string) []string {
+	var terms []string
+	for _, field := range strings.Fields(strings.ToLower(text)) {
+		if field != "" {
+			terms = append(terms, field)
+		}
+	}
+	return terms
+}
+
+// tokenize 分词：与 MySQL ngram 解析器一致，把连续的字母数字（含 CJK）按 ngram 切分，
+// 不足 ngram 长度的片段整体作为一个词
+func tokenize(text string) []string {
+	var tokens []string
+	var run []rune
+
+	flush := func() {
+		if len(run) == 0 {
+			return
+		}
+		if len(run) < ngramSize {
+			tokens = append(tokens, string(run))
+		} else {
+			for i := 0; i+ngramSize <= len(run); i++ {
+				tokens = append(tokens, string(run[i:i+ngramSize]))
+			}
+		}
+		run = run[:0]
+	}
+
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			run = append(run, r)
+		} else {
+			flush()
+		}
+	}
+	flush()
+	return tokens
+}
+
+// Highlight 截取内容中第一个命中关键词附近的片段，并用 <em></em> 标记所有命中的关键词
+func Highlight(content string, terms []string) string {
+	runes := []rune(content)
+	lower := []rune(strings.ToLower(content))
+
+	// 标记每个字符是否属于命中的关键词
+	marked := make([]bool, len(runes))
+	first := -1
+	for _, term := range terms {
+		termRunes := []rune(term)
+		if len(termRunes) == 0 {
+			continue
+		}
+		for i := 0; i+len(termRunes) <= len(lower); i++ {
+			if string(lower[i:i+len(termRunes)]) != term {
+				continue
+			}
+			for j := i; j < i+len(termRunes); j++ {
+				marked[j] = true
+			}
+			if first < 0 || i < first {
+				first = i
+			}
+		}
+	}
+
+	start, end := 0, len(runes)
+	if first >= 0 {
+		if first-snippetContext > 0 {
+			start = first - snippetContext
+		}
+		last := first
+		for last < len(runes) && marked[last] {
+			last++
+		}
+		if last+snippetContext < len(runes) {
+			end = last + snippetContext
+		}
+	} else if end > snippetContext*2 {
+		end = snippetContext * 2
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	for i := start; i < end; i++ {
+		if marked[i] && (i == start || !marked[i-1]) {
+			b.WriteString("<em>")
+		}
+		b.WriteRune(runes[i])
+		if marked[i] && (i == end-1 || !marked[i+1]) {
+			b.WriteString("</em>")
+		}
+	}
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
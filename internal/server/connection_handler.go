@@ -3,6 +3,7 @@ package server
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net"
@@ -12,11 +13,11 @@ import (
 
 	"cursorIM/internal/chat"
 	"cursorIM/internal/connection"
+	"cursorIM/internal/constants"
 	"cursorIM/internal/middleware"
 	"cursorIM/internal/protocol"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
 
@@ -44,7 +45,7 @@ func WebSocketHandler(connMgr connection.ConnectionManager, messageService *chat
 			}
 
 			// Handle authentication immediately
-			userID, err = authenticateTCPStyleWS(ws)
+			userID, _, err = authenticateTCPStyleWS(ws)
 			if err != nil {
 				log.Printf("TCP-style WebSocket authentication failed: %v", err)
 				ws.Close()
@@ -88,46 +89,64 @@ func WebSocketHandler(connMgr connection.ConnectionManager, messageService *chat
 	}
 }
 
+// authRequest 连接后认证请求（AUTH {token} [ack]）
+type authRequest struct {
+	Token string
+	Ack   bool // 客户端声明会确认每条推送，从第一条消息开始跟踪
+}
+
+// parseAuthLine 解析认证请求，token 之后的可选标志目前只有 ack
+func parseAuthLine(line string) (*authRequest, error) {
+	parts := strings.Fields(line)
+	if len(parts) < 2 || parts[0] != "AUTH" {
+		return nil, fmt.Errorf("invalid authentication format")
+	}
+
+	request := &authRequest{Token: parts[1]}
+	for _, flag := range parts[2:] {
+		if flag == "ack" {
+			request.Ack = true
+		}
+	}
+	return request, nil
+}
+
 // authenticateTCPStyleWS handles TCP-style WebSocket authentication
-func authenticateTCPStyleWS(ws *websocket.Conn) (string, error) {
+func authenticateTCPStyleWS(ws *websocket.Conn) (string, *authRequest, error) {
 	// Wait for authentication message
 	ws.SetReadDeadline(time.Now().Add(30 * time.Second))
 	_, authMsg, err := ws.ReadMessage()
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
-	// Parse authentication message (format: AUTH {token})
-	authStr := string(authMsg)
-	authStr = strings.TrimSpace(authStr)
-	parts := strings.SplitN(authStr, " ", 2)
-	if len(parts) != 2 || parts[0] != "AUTH" {
+	// Parse authentication message (format: AUTH {token} [ack])
+	request, err := parseAuthLine(string(authMsg))
+	if err != nil {
 		ws.WriteMessage(websocket.TextMessage, []byte("ERROR Invalid authentication format\n"))
-		return "", fmt.Errorf("invalid authentication format")
+		return "", nil, err
 	}
 
-	token := parts[1]
-
 	// Validate token
-	userID, err := middleware.ValidateToken(token)
+	userID, err := middleware.ValidateToken(request.Token)
 	if err != nil {
 		ws.WriteMessage(websocket.TextMessage, []byte("ERROR Authentication failed\n"))
-		return "", err
+		return "", nil, err
 	}
 
 	// Send authentication success message
 	if err := ws.WriteMessage(websocket.TextMessage, []byte("OK\n")); err != nil {
-		return "", err
+		return "", nil, err
 	}
 
 	// Clear read deadline
 	ws.SetReadDeadline(time.Time{})
 
-	return userID, nil
+	return userID, request, nil
 }
 
 // authenticateTCPConn handles TCP connection authentication
-func authenticateTCPConn(conn net.Conn) (string, error) {
+func authenticateTCPConn(conn net.Conn) (string, *authRequest, error) {
 	// Set read timeout
 	conn.SetReadDeadline(time.Now().Add(30 * time.Second))
 	defer conn.SetReadDeadline(time.Time{}) // Clear timeout
@@ -136,32 +155,29 @@ func authenticateTCPConn(conn net.Conn) (string, error) {
 	reader := bufio.NewReader(conn)
 	line, err := reader.ReadString('\n')
 	if err != nil {
-		return "", fmt.Errorf("failed to read authentication info: %w", err)
+		return "", nil, fmt.Errorf("failed to read authentication info: %w", err)
 	}
 
-	// Parse authentication info
-	line = strings.TrimSpace(line)
-	parts := strings.SplitN(line, " ", 2)
-	if len(parts) != 2 || parts[0] != "AUTH" {
+	// Parse authentication info (format: AUTH {token} [ack])
+	request, err := parseAuthLine(line)
+	if err != nil {
 		// Send authentication failure message
 		conn.Write([]byte("ERROR Invalid authentication format\n"))
-		return "", fmt.Errorf("invalid authentication format")
+		return "", nil, err
 	}
 
-	token := parts[1]
-
 	// Validate token
-	userID, err := middleware.ValidateToken(token)
+	userID, err := middleware.ValidateToken(request.Token)
 	if err != nil {
 		// Send authentication failure message
 		conn.Write([]byte("ERROR Authentication failed\n"))
-		return "", fmt.Errorf("invalid token: %w", err)
+		return "", nil, fmt.Errorf("invalid token: %w", err)
 	}
 
 	// Send authentication success message
 	conn.Write([]byte("OK\n"))
 
-	return userID, nil
+	return userID, request, nil
 }
 
 // handleAuthenticatedConnection handles authenticated connections (both TCP and WebSocket)
@@ -234,7 +250,7 @@ func handleMessage(connMgr connection.ConnectionManager, messageService *chat.Me
 	log.Printf("处理消息: %+v", message)
 
 	// 检查消息接收者
-	if message.RecipientID == "" && !message.IsGroup && message.Type != "ping" && message.Type != "pong" && message.Type != "status" {
+	if message.RecipientID == "" && !message.IsGroup && protocol.RequiresRecipient(message.Type) {
 		log.Printf("警告: 用户 %s 发送的消息没有接收者ID: %+v", userID, message)
 		// 返回错误但不中断处理
 		errorMsg := &protocol.Message{
@@ -271,35 +287,100 @@ func handleMessage(connMgr connection.ConnectionManager, messageService *chat.Me
 		// 处理状态更新消息
 		log.Printf("处理用户 %s 的状态更新: %s", userID, message.Content)
 		return messageService.BroadcastStatus(context.Background(), message)
+	} else if message.Type == constants.MessageTypeAck {
+		// 处理客户端的送达确认
+		return handleAck(connMgr, messageService, userID, message)
+	} else if message.Type == constants.MessageTypeSync {
+		// 处理客户端的增量同步请求
+		return handleSync(connMgr, messageService, userID, message)
+	} else if message.Type == constants.MessageTypeRecall {
+		// 处理撤回消息指令
+		return handleRecall(connMgr, messageService, userID, message)
+	} else if message.Type == constants.MessageTypeEdit {
+		// 处理编辑消息指令
+		return handleEdit(connMgr, messageService, userID, message)
+	} else if message.Type == constants.MessageTypeReaction {
+		// 处理表情回应指令
+		return handleReaction(connMgr, messageService, userID, message)
+	} else if message.Type == constants.MessageTypeRead {
+		// 处理已读上报
+		return handleRead(connMgr, messageService, userID, message)
+	} else if message.Type == constants.MessageTypeVote {
+		// 处理投票指令
+		return handleVote(connMgr, messageService, userID, message)
+	} else if message.Type == constants.MessageTypePollClose {
+		// 处理结束投票指令
+		return handlePollClose(connMgr, messageService, userID, message)
+	} else if protocol.IsEphemeral(message.Type) {
+		// 临时信号只转发给在线接收者
+		return handleSignal(connMgr, messageService, userID, message)
 	} else {
 		// 保存消息到数据库
 		log.Printf("保存用户 %s 发送的消息到数据库", userID)
 
-		// 确保消息有会话ID
-		if message.ConversationID == "" {
-			log.Printf("警告: 消息缺少会话ID，尝试生成临时会话ID")
-			// 为一对一消息生成临时会话ID
-			if message.RecipientID != "" {
-				// 生成一个更短的临时会话ID (使用UUID)
-				tempConvID := uuid.New().String()
-				message.ConversationID = tempConvID
-				log.Printf("为消息生成临时会话ID: %s (用户: %s -> %s)", tempConvID, userID, message.RecipientID)
-			} else {
-				log.Printf("无法为消息生成会话ID，缺少必要信息")
-				return fmt.Errorf("消息缺少会话ID和接收者ID")
-			}
+		// 确保消息带有规范的会话ID
+		if err := resolveConversationID(userID, message); err != nil {
+			return err
 		}
 
 		err := messageService.SaveMessage(context.Background(), message)
+		if errors.Is(err, chat.ErrDuplicateMessage) {
+			// 客户端重发，只回执首次保存的消息ID和序列号，不再投递
+			log.Printf("用户 %s 重发了消息 %s (client_msg_id=%s)", userID, message.ID, message.ClientMsgID)
+			return sendSaveAck(connMgr, userID, message)
+		}
 		if err != nil {
 			log.Printf("保存消息失败: %v", err)
+			if chat.ErrorCode(err) != "" {
+				if sendErr := sendError(connMgr, userID, message, err); sendErr != nil {
+					log.Printf("向用户 %s 返回错误失败: %v", userID, sendErr)
+				}
+			}
 			return err
 		}
 
 		// 发送消息
 		log.Printf("转发消息从用户 %s 到用户 %s", userID, message.RecipientID)
-		return connMgr.SendMessage(message)
+		if err := connMgr.SendMessage(message); err != nil {
+			return err
+		}
+
+		if message.ClientMsgID != "" {
+			return sendSaveAck(connMgr, userID, message)
+		}
+		return nil
+	}
+}
+
+// resolveConversationID 为消息设置规范的会话ID
+// 单聊总是使用双方唯一的会话（不存在时创建），群聊总是使用群组ID，携带其他会话ID的群消息被拒绝
+func resolveConversationID(userID string, message *protocol.Message) error {
+	if message.IsGroup {
+		if (message.ConversationID != "" && message.ConversationID != message.RecipientID) ||
+			(message.GroupID != "" && message.GroupID != message.RecipientID) {
+			log.Printf("用户 %s 发送的群消息会话ID %s 与群组 %s 不一致", userID, message.ConversationID, message.RecipientID)
+			return fmt.Errorf("群消息的会话ID必须是群组ID")
+		}
+		message.ConversationID = message.RecipientID
+		return nil
 	}
+
+	if message.RecipientID == "" {
+		log.Printf("无法为消息确定会话ID，缺少接收者ID")
+		return fmt.Errorf("消息缺少接收者ID")
+	}
+
+	conversationID, err := chat.NewChatService().ResolvePrivateConversation(context.Background(), userID, message.RecipientID)
+	if err != nil {
+		log.Printf("获取用户 %s 和 %s 的单聊会话失败: %v", userID, message.RecipientID, err)
+		return err
+	}
+
+	if message.ConversationID != "" && message.ConversationID != conversationID {
+		log.Printf("消息携带的会话ID %s 不是规范会话，改为 %s", message.ConversationID, conversationID)
+	}
+	message.ConversationID = conversationID
+	return nil
 }
 
 // sendUserStatusUpdate sends a user status update
@@ -397,7 +478,7 @@ func (s *TCPServer) handleConnection(conn net.Conn) {
 	defer conn.Close()
 
 	// First step: authentication
-	userID, err := authenticateTCPConn(conn)
+	userID, _, err := authenticateTCPConn(conn)
 	if err != nil {
 		log.Printf("TCP connection authentication failed: %v", err)
 		return
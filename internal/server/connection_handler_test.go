@@ -0,0 +1,31 @@
+package server
+
+import "testing"
+
+func TestParseAuthLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		want    *authRequest
+		wantErr bool
+	}{
+		{"只有 token", "AUTH abc\n", &authRequest{Token: "abc"}, false},
+		{"开启确认", "AUTH abc ack\n", &authRequest{Token: "abc", Ack: true}, false},
+		{"忽略未知标志", "AUTH abc gzip\n", &authRequest{Token: "abc"}, false},
+		{"缺少 token", "AUTH\n", nil, true},
+		{"错误的命令", "LOGIN abc\n", nil, true},
+		{"空行", "", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseAuthLine(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseAuthLine() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && *got != *tt.want {
+				t.Errorf("parseAuthLine() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,19 +2,21 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net"
 	"net/http"
+	"strconv"
 	"time"
 
 	"cursorIM/internal/chat"
 	"cursorIM/internal/connection"
+	"cursorIM/internal/constants"
 	"cursorIM/internal/middleware"
 	"cursorIM/internal/protocol"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 )
 
 // EnhancedWebSocketHandler 增强的 WebSocket 处理器，支持协议适配
@@ -32,7 +34,8 @@ func EnhancedWebSocketHandler(connMgr connection.ConnectionManager, messageServi
 			}
 
 			// 立即处理认证
-			userID, err = authenticateTCPStyleWS(ws)
+			var auth *authRequest
+			userID, auth, err = authenticateTCPStyleWS(ws)
 			if err != nil {
 				log.Printf("TCP-style WebSocket authentication failed: %v", err)
 				ws.Close()
@@ -43,6 +46,10 @@ func EnhancedWebSocketHandler(connMgr connection.ConnectionManager, messageServi
 
 			// 处理 TCP-style WebSocket 连接（使用 Protobuf）
 			conn := connection.NewEnhancedWebSocketConnection(ws, userID, connection.ConnectionTypeTCPWS)
+			// 认证请求带有 ack 标志时从第一条推送开始跟踪确认
+			if auth.Ack {
+				conn.EnableAcks()
+			}
 			handleEnhancedAuthenticatedConnection(conn, userID, connMgr, messageService)
 		} else {
 			// 标准 WebSocket 先认证
@@ -71,6 +78,10 @@ func EnhancedWebSocketHandler(connMgr connection.ConnectionManager, messageServi
 
 			// 处理标准 WebSocket 连接（使用 JSON）
 			conn := connection.NewEnhancedWebSocketConnection(ws, userID, connection.ConnectionTypeWebSocket)
+			// ack=1 表示客户端会确认每条推送，从第一条消息开始跟踪；否则在客户端第一次确认后开始
+			if c.Query("ack") == "1" {
+				conn.EnableAcks()
+			}
 			handleEnhancedAuthenticatedConnection(conn, userID, connMgr, messageService)
 		}
 	}
@@ -147,7 +158,7 @@ func handleEnhancedMessage(connMgr connection.ConnectionManager, messageService
 	log.Printf("处理增强消息: %+v", message)
 
 	// 检查消息接收者
-	if message.RecipientID == "" && !message.IsGroup && message.Type != "ping" && message.Type != "pong" && message.Type != "status" {
+	if message.RecipientID == "" && !message.IsGroup && protocol.RequiresRecipient(message.Type) {
 		log.Printf("警告: 用户 %s 发送的消息没有接收者ID: %+v", userID, message)
 		// 返回错误消息
 		errorMsg := &protocol.Message{
@@ -183,33 +194,309 @@ func handleEnhancedMessage(connMgr connection.ConnectionManager, messageService
 		log.Printf("处理用户 %s 的状态更新: %s", userID, message.Content)
 		return messageService.BroadcastStatus(context.Background(), message)
 
+	case constants.MessageTypeAck:
+		// 处理客户端的送达确认
+		return handleAck(connMgr, messageService, userID, message)
+
+	case constants.MessageTypeSync:
+		// 处理客户端的增量同步请求
+		return handleSync(connMgr, messageService, userID, message)
+
+	case constants.MessageTypeRecall:
+		// 处理撤回消息指令
+		return handleRecall(connMgr, messageService, userID, message)
+
+	case constants.MessageTypeEdit:
+		// 处理编辑消息指令
+		return handleEdit(connMgr, messageService, userID, message)
+
+	case constants.MessageTypeReaction:
+		// 处理表情回应指令
+		return handleReaction(connMgr, messageService, userID, message)
+
+	case constants.MessageTypeRead:
+		// 处理已读上报
+		return handleRead(connMgr, messageService, userID, message)
+
+	case constants.MessageTypeVote:
+		// 处理投票指令
+		return handleVote(connMgr, messageService, userID, message)
+
+	case constants.MessageTypePollClose:
+		// 处理结束投票指令
+		return handlePollClose(connMgr, messageService, userID, message)
+
+	case constants.MessageTypeTyping, constants.MessageTypeRecording:
+		// 临时信号只转发给在线接收者
+		return handleSignal(connMgr, messageService, userID, message)
+
 	default:
 		// 保存消息到数据库
 		log.Printf("保存用户 %s 发送的消息到数据库", userID)
 
-		// 确保消息有会话ID
-		if message.ConversationID == "" {
-			log.Printf("警告: 消息缺少会话ID，尝试生成临时会话ID")
-			if message.RecipientID != "" {
-				tempConvID := uuid.New().String()
-				message.ConversationID = tempConvID
-				log.Printf("为消息生成临时会话ID: %s (用户: %s -> %s)", tempConvID, userID, message.RecipientID)
-			} else {
-				log.Printf("无法为消息生成会话ID，缺少必要信息")
-				return fmt.Errorf("消息缺少会话ID和接收者ID")
-			}
+		// 确保消息带有规范的会话ID
+		if err := resolveConversationID(userID, message); err != nil {
+			return err
 		}
 
 		err := messageService.SaveMessage(context.Background(), message)
+		if errors.Is(err, chat.ErrDuplicateMessage) {
+			// 客户端重发，只回执首次保存的消息ID和序列号，不再投递
+			log.Printf("用户 %s 重发了消息 %s (client_msg_id=%s)", userID, message.ID, message.ClientMsgID)
+			return sendSaveAck(connMgr, userID, message)
+		}
 		if err != nil {
 			log.Printf("保存消息失败: %v", err)
+			if chat.ErrorCode(err) != "" {
+				if sendErr := sendError(connMgr, userID, message, err); sendErr != nil {
+					log.Printf("向用户 %s 返回错误失败: %v", userID, sendErr)
+				}
+			}
 			return err
 		}
 
 		// 发送消息
 		log.Printf("转发消息从用户 %s 到用户 %s", userID, message.RecipientID)
-		return connMgr.SendMessage(message)
+		if err := connMgr.SendMessage(message); err != nil {
+			return err
+		}
+
+		if message.ClientMsgID != "" {
+			return sendSaveAck(connMgr, userID, message)
+		}
+		return nil
+	}
+}
+
+// sendError 向发起请求的用户返回错误帧，带上请求ID、客户端消息ID、会话ID和目标消息ID，
+// 客户端据此定位失败的请求；业务错误（如被禁言）额外带上 error_code 和 403 状态码
+func sendError(connMgr connection.ConnectionManager, userID string, request *protocol.Message, cause error) error {
+	errorMsg := &protocol.Message{
+		Type:           constants.MessageTypeError,
+		RequestID:      request.RequestID,
+		ClientMsgID:    request.ClientMsgID,
+		SenderID:       "server",
+		RecipientID:    userID,
+		ConversationID: request.ConversationID,
+		TargetID:       request.TargetID,
+		Content:        cause.Error(),
+		Timestamp:      time.Now().Unix(),
+	}
+	if code := chat.ErrorCode(cause); code != "" {
+		errorMsg.StatusCode = constants.StatusForbidden
+		errorMsg.ErrorCode = code
+	}
+	return connMgr.SendMessage(errorMsg)
+}
+
+// handleAck 处理客户端的送达确认：更新消息状态并通知发送者
+func handleAck(connMgr connection.ConnectionManager, messageService *chat.MessageService, userID string, message *protocol.Message) error {
+	if message.ID == "" {
+		return fmt.Errorf("送达确认缺少消息ID")
+	}
+
+	original, changed, err := messageService.MarkMessageDelivered(context.Background(), message.ID, userID)
+	if err != nil {
+		log.Printf("处理用户 %s 对消息 %s 的确认失败: %v", userID, message.ID, err)
+		return err
+	}
+
+	if !changed {
+		return nil
+	}
+
+	log.Printf("消息 %s 已送达用户 %s，通知发送者 %s", message.ID, userID, original.SenderID)
+
+	// 通知发送者消息已送达
+	receipt := &protocol.Message{
+		Type:           constants.MessageTypeAck,
+		ID:             original.ID,
+		SenderID:       userID,
+		RecipientID:    original.SenderID,
+		ConversationID: original.ConversationID,
+		Status:         constants.MessageStatusDelivered,
+		Timestamp:      time.Now().Unix(),
+	}
+	return connMgr.SendMessage(receipt)
+}
+
+// sendSaveAck 向发送者回执消息已保存
+// 回执携带服务端消息ID、序列号和客户端消息ID，客户端据此确认发送成功并停止重发
+func sendSaveAck(connMgr connection.ConnectionManager, userID string, message *protocol.Message) error {
+	ack := &protocol.Message{
+		Type:           constants.MessageTypeAck,
+		ID:             message.ID,
+		ClientMsgID:    message.ClientMsgID,
+		RequestID:      message.RequestID,
+		SenderID:       "server",
+		RecipientID:    userID,
+		ConversationID: message.ConversationID,
+		Seq:            message.Seq,
+		Status:         constants.MessageStatusSent,
+		Timestamp:      time.Now().Unix(),
+	}
+	return connMgr.SendMessage(ack)
+}
+
+// handleSync 处理客户端的增量同步请求
+// 请求的 Metadata 为 会话ID -> 已收到的最大序列号；每个有新消息的会话返回一帧 sync，
+// 最后返回一帧 response 表示同步结束，所有返回帧都带上请求的 RequestID
+func handleSync(connMgr connection.ConnectionManager, messageService *chat.MessageService, userID string, message *protocol.Message) error {
+	cursors := make(map[string]int64, len(message.Metadata))
+	for conversationID, value := range message.Metadata {
+		seq, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			log.Printf("用户 %s 的同步请求中会话 %s 的序列号无效: %s", userID, conversationID, value)
+			continue
+		}
+		cursors[conversationID] = seq
+	}
+
+	results, err := messageService.SyncMessages(context.Background(), userID, cursors, 0)
+	if err != nil {
+		log.Printf("同步用户 %s 的消息失败: %v", userID, err)
+		return connMgr.SendMessage(&protocol.Message{
+			Type:        constants.MessageTypeResponse,
+			RequestID:   message.RequestID,
+			StatusCode:  constants.StatusInternalServerError,
+			SenderID:    "server",
+			RecipientID: userID,
+			Content:     "同步消息失败",
+			Timestamp:   time.Now().Unix(),
+		})
+	}
+
+	for _, result := range results {
+		syncMsg := &protocol.Message{
+			Type:           constants.MessageTypeSync,
+			RequestID:      message.RequestID,
+			SenderID:       "server",
+			RecipientID:    userID,
+			ConversationID: result.ConversationID,
+			Seq:            result.LastSeq,
+			Timestamp:      time.Now().Unix(),
+			Batch: &protocol.MessageBatch{
+				Messages:   result.Messages,
+				TotalCount: len(result.Messages),
+				HasMore:    result.HasMore,
+			},
+		}
+		if err := connMgr.SendMessage(syncMsg); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("用户 %s 同步了 %d 个会话的消息", userID, len(results))
+
+	return connMgr.SendMessage(&protocol.Message{
+		Type:        constants.MessageTypeResponse,
+		RequestID:   message.RequestID,
+		StatusCode:  constants.StatusOK,
+		SenderID:    "server",
+		RecipientID: userID,
+		Timestamp:   time.Now().Unix(),
+	})
+}
+
+// handleRecall 处理撤回消息指令，撤回通知由消息服务推送给所有参与者
+func handleRecall(connMgr connection.ConnectionManager, messageService *chat.MessageService, userID string, message *protocol.Message) error {
+	if message.TargetID == "" {
+		message.TargetID = message.ID
+	}
+	targetID := message.TargetID
+
+	if _, err := messageService.RecallMessage(context.Background(), targetID, userID); err != nil {
+		log.Printf("用户 %s 撤回消息 %s 失败: %v", userID, targetID, err)
+		return sendError(connMgr, userID, message, err)
+	}
+
+	return nil
+}
+
+// handleEdit 处理编辑消息指令，更新事件由消息服务推送给所有参与者
+func handleEdit(connMgr connection.ConnectionManager, messageService *chat.MessageService, userID string, message *protocol.Message) error {
+	if _, err := messageService.EditMessage(context.Background(), message.TargetID, userID, message.Content); err != nil {
+		log.Printf("用户 %s 编辑消息 %s 失败: %v", userID, message.TargetID, err)
+		return sendError(connMgr, userID, message, err)
+	}
+
+	return nil
+}
+
+// handleReaction 处理表情回应指令，变更事件由消息服务推送给所有参与者
+func handleReaction(connMgr connection.ConnectionManager, messageService *chat.MessageService, userID string, message *protocol.Message) error {
+	if _, err := messageService.ReactToMessage(context.Background(), message.TargetID, userID, message.Emoji, message.Remove); err != nil {
+		log.Printf("用户 %s 回应消息 %s 失败: %v", userID, message.TargetID, err)
+		return sendError(connMgr, userID, message, err)
+	}
+
+	return nil
+}
+
+// handleRead 处理客户端上报的已读位置，已读回执由消息服务推送给消息发送者
+// 请求的 conversation_id 为会话ID，seq 为已读到的序列号（为空表示最新消息）
+func handleRead(connMgr connection.ConnectionManager, messageService *chat.MessageService, userID string, message *protocol.Message) error {
+	if _, err := messageService.MarkConversationRead(context.Background(), message.ConversationID, userID, message.Seq); err != nil {
+		log.Printf("用户 %s 标记会话 %s 已读失败: %v", userID, message.ConversationID, err)
+		return sendError(connMgr, userID, message, err)
 	}
+
+	return nil
+}
+
+// handleVote 处理投票指令，target_id 为投票消息ID，option_ids 为空表示撤回投票
+// 计票结果由消息服务推送给所有群成员，投票者另外收到带有自己选择的回执
+func handleVote(connMgr connection.ConnectionManager, messageService *chat.MessageService, userID string, message *protocol.Message) error {
+	result, err := messageService.VotePoll(context.Background(), message.TargetID, userID, message.OptionIDs)
+	if err != nil {
+		log.Printf("用户 %s 参与投票 %s 失败: %v", userID, message.TargetID, err)
+		return sendError(connMgr, userID, message, err)
+	}
+
+	return connMgr.SendMessage(&protocol.Message{
+		Type:        constants.MessageTypeResponse,
+		RequestID:   message.RequestID,
+		StatusCode:  constants.StatusOK,
+		SenderID:    "server",
+		RecipientID: userID,
+		TargetID:    message.TargetID,
+		OptionIDs:   result.OptionIDs,
+		Poll:        result.Poll,
+		Timestamp:   time.Now().Unix(),
+	})
+}
+
+// handlePollClose 处理结束投票指令，最终结果由消息服务推送给所有群成员
+func handlePollClose(connMgr connection.ConnectionManager, messageService *chat.MessageService, userID string, message *protocol.Message) error {
+	if _, err := messageService.ClosePoll(context.Background(), message.TargetID, userID); err != nil {
+		log.Printf("用户 %s 结束投票 %s 失败: %v", userID, message.TargetID, err)
+		return sendError(connMgr, userID, message, err)
+	}
+
+	return nil
+}
+
+// handleSignal 转发临时信号（正在输入、正在录音等）
+// 信号不经过 SaveMessage，接收者不在线时由连接管理器直接丢弃；重复的信号按发送者限流
+func handleSignal(connMgr connection.ConnectionManager, messageService *chat.MessageService, userID string, message *protocol.Message) error {
+	if message.IsGroup {
+		if message.GroupID == "" {
+			message.GroupID = message.RecipientID
+		}
+		if !messageService.IsGroupMember(message.GroupID, userID) {
+			log.Printf("用户 %s 不是群组 %s 的成员，忽略临时信号", userID, message.GroupID)
+			return nil
+		}
+		message.ConversationID = message.GroupID
+	}
+
+	if !signalThrottle.Allow(message) {
+		return nil
+	}
+
+	// 信号不需要确认，也不需要唯一ID
+	message.ID = ""
+	return connMgr.SendMessage(message)
 }
 
 // EnhancedTCPServer 增强的 TCP 服务器，支持协议适配
@@ -285,7 +572,7 @@ func (s *EnhancedTCPServer) handleConnection(conn net.Conn) {
 	defer conn.Close()
 
 	// 首先进行认证
-	userID, err := authenticateTCPConn(conn)
+	userID, auth, err := authenticateTCPConn(conn)
 	if err != nil {
 		log.Printf("Enhanced TCP connection authentication failed: %v", err)
 		return
@@ -295,6 +582,9 @@ func (s *EnhancedTCPServer) handleConnection(conn net.Conn) {
 
 	// 创建增强 TCP 连接对象
 	tcpConn := connection.NewEnhancedTCPConnection(conn, userID, connection.ConnectionTypeTCP)
+	if auth.Ack {
+		tcpConn.EnableAcks()
+	}
 
 	// 处理连接
 	handleEnhancedAuthenticatedConnection(tcpConn, userID, s.connMgr, s.messageService)
@@ -0,0 +1,195 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"cursorIM/internal/chat"
+	"cursorIM/internal/connection"
+	"cursorIM/internal/constants"
+	"cursorIM/internal/model"
+	"cursorIM/internal/protocol"
+	"cursorIM/internal/redisclient"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// 定时消息调度参数
+const (
+	scheduleInterval        = time.Second      // 扫描到期消息的间隔
+	scheduleBatchSize       = 100              // 每次扫描处理的最大消息数
+	scheduleLockTTL         = 30 * time.Second // 单条消息发送锁的有效期
+	scheduleRequeueInterval = time.Minute      // 检查认领超时消息的间隔
+)
+
+// releaseLockScript 只释放自己持有的锁
+var releaseLockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// MessageScheduler 定时消息调度器
+// 每个节点都运行调度器。同一条定时消息先通过 Redis 锁在节点间互斥，
+// 再通过数据库状态 pending -> sending 认领，保证集群中只有一个节点发送；
+// 到期消息按客户端发送的同一路径（handleEnhancedMessage）保存和投递
+type MessageScheduler struct {
+	serverID       string
+	connMgr        connection.ConnectionManager
+	messageService *chat.MessageService
+}
+
+// NewMessageScheduler 创建定时消息调度器
+func NewMessageScheduler(serverID string, connMgr connection.ConnectionManager, messageService *chat.MessageService) *MessageScheduler {
+	return &MessageScheduler{
+		serverID:       serverID,
+		connMgr:        connMgr,
+		messageService: messageService,
+	}
+}
+
+// Run 运行调度器，直到 ctx 取消
+func (s *MessageScheduler) Run(ctx context.Context) {
+	log.Printf("定时消息调度器已启动 (服务器: %s)", s.serverID)
+
+	ticker := time.NewTicker(scheduleInterval)
+	defer ticker.Stop()
+	requeueTicker := time.NewTicker(scheduleRequeueInterval)
+	defer requeueTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("定时消息调度器已停止")
+			return
+		case <-requeueTicker.C:
+			if err := s.messageService.RequeueStaleScheduledMessages(ctx); err != nil {
+				log.Printf("%v", err)
+			}
+		case <-ticker.C:
+			s.dispatchDue(ctx)
+		}
+	}
+}
+
+// dispatchDue 发送所有到期的定时消息
+func (s *MessageScheduler) dispatchDue(ctx context.Context) {
+	due, err := s.messageService.DueScheduledMessages(ctx, time.Now(), scheduleBatchSize)
+	if err != nil {
+		log.Printf("%v", err)
+		return
+	}
+
+	for i := range due {
+		if ctx.Err() != nil {
+			return
+		}
+		s.dispatch(ctx, &due[i])
+	}
+}
+
+// dispatch 加锁、认领并发送一条定时消息
+func (s *MessageScheduler) dispatch(ctx context.Context, scheduled *model.ScheduledMessage) {
+	release, ok := s.acquireLock(ctx, scheduled.ID)
+	if !ok {
+		return
+	}
+	defer release()
+
+	claimed, err := s.messageService.ClaimScheduledMessage(ctx, scheduled.ID)
+	if err != nil {
+		log.Printf("%v", err)
+		return
+	}
+	if !claimed {
+		// 已被其他节点发送、或已被用户取消/修改
+		return
+	}
+
+	messageID, sendErr := s.send(ctx, scheduled)
+	if sendErr != nil {
+		log.Printf("发送定时消息 %s 失败: %v", scheduled.ID, sendErr)
+		s.notifyFailure(scheduled, sendErr)
+	} else {
+		log.Printf("定时消息 %s 已发送，消息ID: %s", scheduled.ID, messageID)
+	}
+
+	if err := s.messageService.CompleteScheduledMessage(ctx, scheduled.ID, messageID, sendErr); err != nil {
+		log.Printf("更新定时消息 %s 的状态失败: %v", scheduled.ID, err)
+	}
+}
+
+// send 按客户端发送消息的路径保存并投递定时消息，返回生成的消息ID
+// 认领超时后重新发送的消息如果已经保存过，不再走发送路径（会被去重），只补发给尚未收到的接收者
+func (s *MessageScheduler) send(ctx context.Context, scheduled *model.ScheduledMessage) (string, error) {
+	original, recipients, err := s.messageService.ScheduledResend(ctx, scheduled)
+	if err != nil {
+		return "", err
+	}
+	if original != nil {
+		s.resend(original, recipients)
+		return original.ID, nil
+	}
+
+	message, err := chat.ScheduledPayload(scheduled)
+	if err != nil {
+		return "", err
+	}
+
+	if err := handleEnhancedMessage(s.connMgr, s.messageService, scheduled.SenderID, message); err != nil {
+		return "", err
+	}
+	if message.ID == "" {
+		return "", errors.New("消息未能保存")
+	}
+	return message.ID, nil
+}
+
+// resend 将已保存的定时消息逐个补发给尚未收到的接收者，不在线的接收者由连接管理器存为离线消息
+func (s *MessageScheduler) resend(original *protocol.Message, recipients []string) {
+	log.Printf("定时消息 %s 已保存过，补发给 %d 个接收者", original.ID, len(recipients))
+	for _, recipientID := range recipients {
+		message := *original
+		message.RecipientID = recipientID
+		if err := s.connMgr.SendMessage(&message); err != nil {
+			log.Printf("向用户 %s 补发消息 %s 失败: %v", recipientID, original.ID, err)
+		}
+	}
+}
+
+// notifyFailure 通知发送者定时消息发送失败
+func (s *MessageScheduler) notifyFailure(scheduled *model.ScheduledMessage, sendErr error) {
+	request := &protocol.Message{TargetID: scheduled.ID}
+	if err := sendError(s.connMgr, scheduled.SenderID, request, fmt.Errorf("定时消息发送失败: %w", sendErr)); err != nil {
+		log.Printf("通知用户 %s 定时消息发送失败时出错: %v", scheduled.SenderID, err)
+	}
+}
+
+// acquireLock 获取单条定时消息的发送锁
+// Redis 不可用或出错时只依赖数据库认领，仍能保证只发送一次
+func (s *MessageScheduler) acquireLock(ctx context.Context, scheduledID string) (func(), bool) {
+	if !redisclient.IsRedisEnabled() {
+		return func() {}, true
+	}
+
+	client := redisclient.GetRedisClient()
+	key := fmt.Sprintf(constants.RedisKeyScheduledLock, scheduledID)
+	locked, err := client.SetNX(ctx, key, s.serverID, scheduleLockTTL).Result()
+	if err != nil {
+		log.Printf("获取定时消息 %s 的发送锁失败，退回数据库认领: %v", scheduledID, err)
+		return func() {}, true
+	}
+	if !locked {
+		return nil, false
+	}
+
+	return func() {
+		if err := releaseLockScript.Run(context.Background(), client, []string{key}, s.serverID).Err(); err != nil {
+			log.Printf("释放定时消息 %s 的发送锁失败: %v", scheduledID, err)
+		}
+	}, true
+}
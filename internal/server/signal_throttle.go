@@ -0,0 +1,91 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"cursorIM/internal/protocol"
+)
+
+// 临时信号限流参数
+const (
+	SignalThrottleInterval = 2 * time.Second // 同一发送者对同一目标发送同类信号的限流窗口
+	signalThrottleBurst    = 2               // 每个窗口内最多放行的信号数（一次开始加一次结束）
+	signalThrottleMaxKeys  = 10000           // 记录数上限，超过后先清理过期记录，仍超过时随机淘汰
+)
+
+// signalState 当前窗口内已放行的信号
+type signalState struct {
+	active      bool
+	windowStart time.Time
+	count       int
+}
+
+// SignalThrottle 按发送者对临时信号限流
+// 以（发送者、目标、信号类型）为键，每个窗口内最多放行 signalThrottleBurst 条：
+// 重复的相同状态直接丢弃，状态变化（例如输入开始后立即结束）在配额内放行，反复切换的信号同样受限
+type SignalThrottle struct {
+	interval time.Duration
+	last     map[string]signalState
+	mutex    sync.Mutex
+}
+
+// NewSignalThrottle 创建临时信号限流器
+func NewSignalThrottle(interval time.Duration) *SignalThrottle {
+	return &SignalThrottle{
+		interval: interval,
+		last:     make(map[string]signalState),
+	}
+}
+
+// Allow 判断信号是否可以转发
+func (t *SignalThrottle) Allow(message *protocol.Message) bool {
+	return t.allow(message, time.Now())
+}
+
+func (t *SignalThrottle) allow(message *protocol.Message, now time.Time) bool {
+	target := message.RecipientID
+	if message.IsGroup && message.GroupID != "" {
+		target = message.GroupID
+	}
+	key := message.SenderID + "|" + target + "|" + message.Type
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if state, ok := t.last[key]; ok && now.Sub(state.windowStart) < t.interval {
+		if state.active == message.Active || state.count >= signalThrottleBurst {
+			return false
+		}
+		state.active = message.Active
+		state.count++
+		t.last[key] = state
+		return true
+	}
+
+	if _, ok := t.last[key]; !ok && len(t.last) >= signalThrottleMaxKeys {
+		t.evict(now)
+	}
+
+	t.last[key] = signalState{active: message.Active, windowStart: now, count: 1}
+	return true
+}
+
+// evict 清理窗口已过期的记录，仍达到上限时随机淘汰，为新记录腾出位置
+func (t *SignalThrottle) evict(now time.Time) {
+	for k, state := range t.last {
+		if now.Sub(state.windowStart) >= t.interval {
+			delete(t.last, k)
+		}
+	}
+
+	for k := range t.last {
+		if len(t.last) < signalThrottleMaxKeys {
+			break
+		}
+		delete(t.last, k)
+	}
+}
+
+// signalThrottle TCP 与 WebSocket 连接共用的限流器
+var signalThrottle = NewSignalThrottle(SignalThrottleInterval)
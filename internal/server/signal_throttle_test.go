@@ -0,0 +1,96 @@
+package server
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"cursorIM/internal/constants"
+	"cursorIM/internal/protocol"
+)
+
+func typing(sender, recipient string, active bool) *protocol.Message {
+	return &protocol.Message{
+		Type:        constants.MessageTypeTyping,
+		SenderID:    sender,
+		RecipientID: recipient,
+		Active:      active,
+	}
+}
+
+func TestSignalThrottleAllow(t *testing.T) {
+	type step struct {
+		at      time.Duration // 相对第一条信号的时间
+		message *protocol.Message
+		want    bool
+	}
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{"重复的开始信号被丢弃", []step{
+			{0, typing("u1", "u2", true), true},
+			{time.Second, typing("u1", "u2", true), false},
+		}},
+		{"窗口过后重新放行", []step{
+			{0, typing("u1", "u2", true), true},
+			{SignalThrottleInterval, typing("u1", "u2", true), true},
+		}},
+		{"开始后立即结束放行", []step{
+			{0, typing("u1", "u2", true), true},
+			{100 * time.Millisecond, typing("u1", "u2", false), true},
+		}},
+		{"反复切换受限", []step{
+			{0, typing("u1", "u2", true), true},
+			{100 * time.Millisecond, typing("u1", "u2", false), true},
+			{200 * time.Millisecond, typing("u1", "u2", true), false},
+			{300 * time.Millisecond, typing("u1", "u2", false), false},
+		}},
+		{"不同目标互不影响", []step{
+			{0, typing("u1", "u2", true), true},
+			{0, typing("u1", "u3", true), true},
+			{0, typing("u4", "u2", true), true},
+		}},
+		{"不同信号类型互不影响", []step{
+			{0, typing("u1", "u2", true), true},
+			{0, &protocol.Message{Type: constants.MessageTypeRecording, SenderID: "u1", RecipientID: "u2", Active: true}, true},
+		}},
+		{"群聊按群组限流", []step{
+			{0, &protocol.Message{Type: constants.MessageTypeTyping, SenderID: "u1", RecipientID: "g1", GroupID: "g1", IsGroup: true, Active: true}, true},
+			{time.Second, &protocol.Message{Type: constants.MessageTypeTyping, SenderID: "u1", GroupID: "g1", IsGroup: true, Active: true}, false},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			throttle := NewSignalThrottle(SignalThrottleInterval)
+			start := time.Now()
+			for i, s := range tt.steps {
+				if got := throttle.allow(s.message, start.Add(s.at)); got != s.want {
+					t.Errorf("第 %d 条信号 allow() = %v, want %v", i+1, got, s.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSignalThrottleMaxKeys(t *testing.T) {
+	throttle := NewSignalThrottle(SignalThrottleInterval)
+	now := time.Now()
+
+	for i := 0; i < signalThrottleMaxKeys+100; i++ {
+		if !throttle.allow(typing(fmt.Sprintf("u%d", i), "target", true), now) {
+			t.Fatalf("第 %d 个发送者的首条信号被丢弃", i)
+		}
+	}
+	if got := len(throttle.last); got > signalThrottleMaxKeys {
+		t.Errorf("记录数 = %d, 超过上限 %d", got, signalThrottleMaxKeys)
+	}
+
+	// 过期记录优先清理
+	throttle.allow(typing("late", "target", true), now.Add(SignalThrottleInterval))
+	if got := len(throttle.last); got != 1 {
+		t.Errorf("清理过期记录后记录数 = %d, want 1", got)
+	}
+}
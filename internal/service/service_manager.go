@@ -34,6 +34,8 @@ func NewManager(ctx context.Context, connMgr connection.ConnectionManager) *Mana
 
 	// 设置聊天服务的连接管理器
 	manager.chatService.SetConnectionManager(connMgr)
+	manager.groupService.SetConnectionManager(connMgr)
+	manager.groupService.SetSystemMessenger(manager.chatService)
 
 	log.Println("服务管理器初始化完成")
 	return manager